@@ -1,60 +1,262 @@
 package main
 
 import (
+	streamauth "OrderSystemHighConcurrency/grpc-stream/internal/auth"
 	"OrderSystemHighConcurrency/grpc-stream/internal/config"
 	servicescontract "OrderSystemHighConcurrency/grpc-stream/internal/contracts"
+	"OrderSystemHighConcurrency/grpc-stream/internal/infrastructure/db"
+	"OrderSystemHighConcurrency/grpc-stream/internal/infrastructure/memory"
+	"OrderSystemHighConcurrency/grpc-stream/internal/infrastructure/transport"
 	"OrderSystemHighConcurrency/grpc-stream/internal/services"
+	"OrderSystemHighConcurrency/shared/auth"
+	sharedcontracts "OrderSystemHighConcurrency/shared/contracts"
 	sharedkafa "OrderSystemHighConcurrency/shared/kafka"
 	"OrderSystemHighConcurrency/shared/models"
+	sharedpb "OrderSystemHighConcurrency/shared/pb"
+	"OrderSystemHighConcurrency/shared/schema"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 
 	pb "OrderSystemHighConcurrency/grpc-stream/internal/pb"
 	"log"
 	"net"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 type server struct {
 	pb.UnimplementedOrderStreamServer
 	streamService servicescontract.StreamService
+	statusFeed    sharedcontracts.StatusFeed // nil when no status topic is configured
 }
 
+// StreamOrders publishes every order on the stream and answers each one with
+// its order ID and whether it was accepted, rejected or failed
 func (s *server) StreamOrders(stream pb.OrderStream_StreamOrdersServer) error {
 	for {
 		orderProto, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
 		if err != nil {
 			log.Printf("stream closed: %v", err)
 			return err
 		}
 
-		order := &models.Order{
-			OrderID:   orderProto.Id,
-			Amount:    orderProto.Amount,
-			UserID:    orderProto.CustomerId,
-			CreatedAt: orderProto.CreatedAt.AsTime(),
+		resp := &pb.StreamResponse{
+			Status:  "received",
+			OrderId: orderProto.GetOrderId(),
+			Code:    pb.AckCode_ACK_CODE_ACCEPTED,
+		}
+
+		order, err := orderProto.ToModel()
+		if err != nil {
+			err = fmt.Errorf("%w: %v", servicescontract.ErrInvalidOrder, err)
+		} else {
+			err = s.streamService.PublishOrder(stream.Context(), order)
 		}
 
-		_ = s.streamService.PublishOrder(context.Background(), order)
+		switch {
+		case errors.Is(err, servicescontract.ErrInvalidOrder), errors.Is(err, sharedcontracts.ErrForbidden):
+			resp.Status, resp.Code, resp.Error = "rejected", pb.AckCode_ACK_CODE_REJECTED, err.Error()
+		case err != nil:
+			resp.Status, resp.Code, resp.Error = "failed", pb.AckCode_ACK_CODE_FAILED, err.Error()
+		}
 
-		if err := stream.Send(&pb.StreamResponse{Status: "received"}); err != nil {
+		if err := stream.Send(resp); err != nil {
 			log.Printf("failed to send response: %v", err)
+			return err
+		}
+	}
+}
+
+// CreateOrder publishes a single order
+func (s *server) CreateOrder(ctx context.Context, orderProto *sharedpb.Order) (*pb.CreateOrderResponse, error) {
+	order, err := orderProto.ToModel()
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid order: %v", err)
+	}
+
+	if err := s.streamService.PublishOrder(ctx, order); err != nil {
+		return nil, toStatus(err, "failed to publish order")
+	}
+
+	return &pb.CreateOrderResponse{OrderId: order.OrderID, Status: string(order.Status)}, nil
+}
+
+// GetOrder returns the current state of an order
+func (s *server) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*sharedpb.Order, error) {
+	order, err := s.streamService.GetOrder(ctx, req.GetOrderId())
+	if err != nil {
+		return nil, toStatus(err, "failed to fetch order")
+	}
+	return sharedpb.FromModel(order), nil
+}
+
+// CancelOrder publishes a cancellation request. Whether it is honoured is
+// decided by order-processor, so the response only confirms the request.
+func (s *server) CancelOrder(ctx context.Context, req *pb.CancelOrderRequest) (*pb.CancelOrderResponse, error) {
+	order, err := s.streamService.CancelOrder(ctx, &models.Order{
+		OrderID:  req.GetOrderId(),
+		UserID:   req.GetUserId(),
+		Metadata: req.GetMetadata(),
+	})
+	if err != nil {
+		return nil, toStatus(err, "failed to request cancellation")
+	}
+
+	resp := &pb.CancelOrderResponse{OrderId: order.OrderID, Status: "cancellation requested"}
+	if order.Status == models.OrderStatusCancelled {
+		resp.Status = "order already cancelled"
+	}
+	return resp, nil
+}
+
+// WatchOrders streams status changes of the requested orders
+func (s *server) WatchOrders(req *pb.WatchOrdersRequest, stream pb.OrderStream_WatchOrdersServer) error {
+	if s.statusFeed == nil {
+		return status.Error(codes.Unimplemented, "order status events are disabled")
+	}
+
+	ctx := stream.Context()
+	filter := sharedcontracts.StatusFilter{OrderIDs: req.GetOrderIds(), UserID: req.GetUserId()}
+
+	// Authenticated callers watch their own orders only
+	if filter.UserID != "" || len(filter.OrderIDs) == 0 {
+		userID, err := auth.BindUser(ctx, filter.UserID)
+		if err != nil {
+			return status.Error(codes.PermissionDenied, "cannot watch orders of another user")
+		}
+		filter.UserID = userID
+	}
+
+	if len(filter.OrderIDs) == 0 && filter.UserID == "" {
+		return status.Error(codes.InvalidArgument, "order_ids or user_id is required")
+	}
+
+	if auth.PrincipalFrom(ctx) != nil {
+		for _, orderID := range filter.OrderIDs {
+			if _, err := s.streamService.GetOrder(ctx, orderID); err != nil {
+				return toStatus(err, "failed to fetch order "+orderID)
+			}
 		}
 	}
+
+	err := s.statusFeed.Watch(ctx, filter, req.GetCursor(), func(update sharedcontracts.StatusUpdate) error {
+		return stream.Send(toStatusUpdate(update))
+	})
+	switch {
+	case ctx.Err() != nil:
+		return nil
+	case errors.Is(err, sharedcontracts.ErrInvalidCursor):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, sharedcontracts.ErrWatcherTooSlow):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return err
+	}
+}
+
+// toStatusUpdate converts an update to its protobuf form
+func toStatusUpdate(update sharedcontracts.StatusUpdate) *pb.OrderStatusUpdate {
+	order := update.Order
+	msg := &pb.OrderStatusUpdate{
+		OrderId: order.OrderID,
+		UserId:  order.UserID,
+		Status:  string(order.Status),
+		Cursor:  update.Cursor,
+	}
+
+	if n := len(order.Transitions); n > 0 {
+		msg.Transition = sharedpb.FromTransition(order.Transitions[n-1])
+	}
+	return msg
+}
+
+// toStatus maps service errors to gRPC status codes
+func toStatus(err error, msg string) error {
+	switch {
+	case errors.Is(err, servicescontract.ErrInvalidOrder):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, models.ErrOrderNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, models.ErrCancelTooLate):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, sharedcontracts.ErrForbidden):
+		return status.Error(codes.PermissionDenied, "cannot act for another user")
+	default:
+		return status.Errorf(codes.Unavailable, "%s: %v", msg, err)
+	}
 }
 
 func main() {
 	cfg := config.LoadConfig()
 
-	producer, err := sharedkafa.NewKafkaProducer(cfg.KafkaBrokers, cfg.KafkaTopic)
+	schemaID, err := registerSchema(cfg)
+	if err != nil {
+		log.Fatalf("failed to register event schema: %v", err)
+	}
+
+	producer, err := sharedkafa.NewKafkaProducer(sharedkafa.ProducerConfig{
+		Brokers:       cfg.KafkaBrokers,
+		Topic:         cfg.KafkaTopic,
+		KeyStrategy:   sharedkafa.KeyStrategy(cfg.KafkaKeyStrategy),
+		Partitioner:   sharedkafa.Partitioner(cfg.KafkaPartitioner),
+		Source:        "grpc-stream",
+		SchemaVersion: cfg.KafkaSchemaVersion,
+		Encoding:      sharedkafa.Encoding(cfg.KafkaEncoding),
+		SchemaID:      schemaID,
+	})
 	if err != nil {
 		log.Fatalf("failed to init kafka producer: %v", err)
 	}
 
-	streamService := services.NewStreamService(producer)
+	projection := memory.NewOrderProjection(cfg.ProjectionTTL, cfg.ProjectionMaxOrders)
+
+	var reader servicescontract.OrderReader = projection
+	if cfg.OrderReadStore == "sql" {
+		database, err := db.NewDB(cfg)
+		if err != nil {
+			log.Fatalf("failed to connect DB: %v", err)
+		}
+		defer database.Close()
+
+		reader = db.NewOrderReader(database)
+	}
+
+	streamService := services.NewStreamService(producer, reader, projection)
 
-	grpcServer := grpc.NewServer()
-	pb.RegisterOrderStreamServer(grpcServer, &server{streamService: streamService})
+	srv := &server{streamService: streamService}
+	if cfg.StatusTopic != "" {
+		feed, err := sharedkafa.NewStatusFeed(cfg.KafkaBrokers, cfg.StatusTopic)
+		if err != nil {
+			log.Fatalf("failed to init status feed: %v", err)
+		}
+		defer feed.Close()
+
+		if err := feed.Start(context.Background()); err != nil {
+			log.Fatalf("failed to start status feed: %v", err)
+		}
+		srv.statusFeed = feed
+
+		// Keep accepted orders current until the projection lets them go
+		go followStatus(context.Background(), feed, projection)
+	}
+
+	interceptors := newInterceptors(cfg)
+	grpcServer := grpc.NewServer(
+		grpc.Creds(serverCredentials(cfg)),
+		grpc.UnaryInterceptor(interceptors.Unary()),
+		grpc.StreamInterceptor(interceptors.Stream()),
+	)
+	pb.RegisterOrderStreamServer(grpcServer, srv)
 
 	lis, err := net.Listen("tcp", ":"+cfg.GRPCPort)
 	if err != nil {
@@ -66,3 +268,91 @@ func main() {
 		log.Fatalf("failed to serve: %v", err)
 	}
 }
+
+// followStatus applies every status change to the projection, resuming
+// from the last change seen whenever the watch ends
+func followStatus(ctx context.Context, feed sharedcontracts.StatusFeed, projection servicescontract.OrderProjection) {
+	var cursor string
+	for ctx.Err() == nil {
+		err := feed.Watch(ctx, sharedcontracts.StatusFilter{All: true}, cursor, func(update sharedcontracts.StatusUpdate) error {
+			cursor = update.Cursor
+			return projection.Apply(ctx, update.Order)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("status feed watch for projection ended: %v", err)
+		time.Sleep(time.Second)
+	}
+}
+
+// serverCredentials returns TLS credentials, or plaintext when no
+// certificate is configured
+func serverCredentials(cfg *config.Config) credentials.TransportCredentials {
+	if cfg.TLSCertFile == "" {
+		log.Println("WARNING: TLS is disabled, gRPC traffic is plaintext")
+		return insecure.NewCredentials()
+	}
+
+	creds, err := transport.NewServerCredentials(
+		cfg.TLSCertFile,
+		cfg.TLSKeyFile,
+		cfg.TLSClientCAFile,
+		transport.ClientAuth(cfg.TLSClientAuth),
+	)
+	if err != nil {
+		log.Fatalf("invalid TLS config: %v", err)
+	}
+	return creds
+}
+
+// newInterceptors builds the auth interceptors; a method whose keys can't
+// be loaded stops startup rather than leaving the server open
+func newInterceptors(cfg *config.Config) *streamauth.Interceptors {
+	scopes := map[string]string{
+		pb.OrderStream_StreamOrders_FullMethodName: auth.ScopeOrdersWrite,
+		pb.OrderStream_CreateOrder_FullMethodName:  auth.ScopeOrdersWrite,
+		pb.OrderStream_CancelOrder_FullMethodName:  auth.ScopeOrdersWrite,
+		pb.OrderStream_GetOrder_FullMethodName:     auth.ScopeOrdersRead,
+		pb.OrderStream_WatchOrders_FullMethodName:  auth.ScopeOrdersRead,
+	}
+
+	var apiKeys, tokens sharedcontracts.CredentialVerifier
+	for _, method := range cfg.AuthMethods {
+		var err error
+		switch method {
+		case "none":
+			log.Println("WARNING: authentication is disabled, user_id is trusted from requests")
+			return streamauth.NewInterceptors(nil, nil, scopes)
+		case "api_key":
+			apiKeys, err = auth.NewAPIKeyVerifier(cfg.AuthAPIKeysFile)
+		case "jwt":
+			tokens, err = auth.NewJWTVerifier(cfg.AuthJWKSFile, cfg.AuthJWTIssuer, cfg.AuthJWTAudience)
+		default:
+			log.Fatalf("unknown auth method %q", method)
+		}
+		if err != nil {
+			log.Fatalf("failed to load %s credentials: %v (see the examples in config/)", method, err)
+		}
+	}
+
+	if apiKeys == nil && tokens == nil {
+		log.Fatal("no auth method configured; set AUTH_METHODS=none to disable authentication")
+	}
+	return streamauth.NewInterceptors(apiKeys, tokens, scopes)
+}
+
+// registerSchema registers the order event schema so incompatible changes
+// stop the server at startup. It returns 0 when no registry is configured.
+func registerSchema(cfg *config.Config) (int, error) {
+	registry, err := schema.Open(cfg.SchemaRegistry, cfg.SchemaRegistryPath, cfg.SchemaRegistryURL, cfg.SchemaCompatibility)
+	if err != nil || registry == nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return schema.RegisterOrderEvent(ctx, registry, cfg.KafkaTopic)
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"OrderSystemHighConcurrency/shared/auth"
+	sharedcontracts "OrderSystemHighConcurrency/shared/contracts"
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataAPIKey carries an API key; "authorization: ApiKey <key>" works too
+const MetadataAPIKey = "x-api-key"
+
+// Interceptors authenticate every RPC from its metadata and attach the
+// caller to the context, where auth.PrincipalFrom finds it
+type Interceptors struct {
+	apiKeys sharedcontracts.CredentialVerifier // nil when API keys are disabled
+	tokens  sharedcontracts.CredentialVerifier // nil when JWTs are disabled
+	scopes  map[string]string                  // required scope by full method name
+}
+
+// NewInterceptors creates the interceptors. RPCs missing from scopes only
+// need a valid caller. With neither verifier every RPC passes
+// unauthenticated.
+func NewInterceptors(
+	apiKeys sharedcontracts.CredentialVerifier,
+	tokens sharedcontracts.CredentialVerifier,
+	scopes map[string]string,
+) *Interceptors {
+	return &Interceptors{
+		apiKeys: apiKeys,
+		tokens:  tokens,
+		scopes:  scopes,
+	}
+}
+
+// Unary authenticates unary RPCs
+func (i *Interceptors) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := i.authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream authenticates streaming RPCs once, when the stream opens
+func (i *Interceptors) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := i.authorize(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authorize returns ctx carrying the caller, or a gRPC status error
+func (i *Interceptors) authorize(ctx context.Context, method string) (context.Context, error) {
+	if i.apiKeys == nil && i.tokens == nil {
+		return ctx, nil
+	}
+
+	principal, err := i.authenticate(ctx)
+	if err != nil {
+		if !errors.Is(err, sharedcontracts.ErrNoCredentials) {
+			log.Printf("authentication failed for %s from %s: %v", method, peerAddr(ctx), err)
+		}
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid credentials")
+	}
+
+	if scope, ok := i.scopes[method]; ok && !principal.HasScope(scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "missing scope %s", scope)
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+// authenticate reads the x-api-key or authorization metadata
+func (i *Interceptors) authenticate(ctx context.Context) (*sharedcontracts.Principal, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if keys := md.Get(MetadataAPIKey); len(keys) > 0 && i.apiKeys != nil {
+		return i.apiKeys.Verify(keys[0])
+	}
+
+	for _, value := range md.Get("authorization") {
+		scheme, credential, _ := strings.Cut(value, " ")
+		credential = strings.TrimSpace(credential)
+
+		switch {
+		case strings.EqualFold(scheme, "Bearer") && i.tokens != nil:
+			return i.tokens.Verify(credential)
+		case strings.EqualFold(scheme, "ApiKey") && i.apiKeys != nil:
+			return i.apiKeys.Verify(credential)
+		}
+	}
+	return nil, sharedcontracts.ErrNoCredentials
+}
+
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+	return "unknown"
+}
+
+// authenticatedStream replaces the stream's context with one carrying the
+// caller
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,207 @@
+package auth_test
+
+import (
+	streamauth "OrderSystemHighConcurrency/grpc-stream/internal/auth"
+	pb "OrderSystemHighConcurrency/grpc-stream/internal/pb"
+	"OrderSystemHighConcurrency/shared/auth"
+	sharedcontracts "OrderSystemHighConcurrency/shared/contracts"
+	sharedpb "OrderSystemHighConcurrency/shared/pb"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var jwtSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// whoamiServer answers every RPC with the subject of the caller
+type whoamiServer struct {
+	pb.UnimplementedOrderStreamServer
+}
+
+func subject(ctx context.Context) string {
+	if p := auth.PrincipalFrom(ctx); p != nil {
+		return p.Subject
+	}
+	return ""
+}
+
+func (whoamiServer) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*sharedpb.Order, error) {
+	return &sharedpb.Order{OrderId: req.GetOrderId(), UserId: subject(ctx)}, nil
+}
+
+func (whoamiServer) WatchOrders(req *pb.WatchOrdersRequest, stream pb.OrderStream_WatchOrdersServer) error {
+	return stream.Send(&pb.OrderStatusUpdate{UserId: subject(stream.Context())})
+}
+
+func writeJSON(t *testing.T, name string, v any) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// hs256 signs claims with jwtSecret
+func hs256(claims map[string]any) string {
+	enc := base64.RawURLEncoding
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "kid": "test"})
+	body, _ := json.Marshal(claims)
+
+	signed := enc.EncodeToString(header) + "." + enc.EncodeToString(body)
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte(signed))
+	return signed + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+// newVerifiers loads a reader and a writer API key and the JWT secret
+// through the real verifiers
+func newVerifiers(t *testing.T) (apiKeys, tokens sharedcontracts.CredentialVerifier) {
+	t.Helper()
+
+	apiKeys, err := auth.NewAPIKeyVerifier(writeJSON(t, "api-keys.json", []map[string]any{
+		{"id": "reader", "user_id": "merchant-42", "hash": auth.HashAPIKey("reader-key"), "scopes": []string{auth.ScopeOrdersRead}},
+		{"id": "writer", "user_id": "merchant-42", "hash": auth.HashAPIKey("writer-key"), "scopes": []string{auth.ScopeOrdersWrite}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err = auth.NewJWTVerifier(writeJSON(t, "jwks.json", map[string]any{"keys": []map[string]string{
+		{"kty": "oct", "kid": "test", "k": base64.RawURLEncoding.EncodeToString(jwtSecret)},
+	}}), "", "grpc-stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return apiKeys, tokens
+}
+
+// dial serves the interceptors on an in-memory listener and connects to it
+func dial(t *testing.T, interceptors *streamauth.Interceptors) pb.OrderStreamClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(interceptors.Unary()),
+		grpc.StreamInterceptor(interceptors.Stream()),
+	)
+	pb.RegisterOrderStreamServer(srv, whoamiServer{})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewOrderStreamClient(conn)
+}
+
+// call makes a unary and a streaming RPC and returns the subject each saw
+func call(ctx context.Context, client pb.OrderStreamClient) (unary string, unaryErr error, stream string, streamErr error) {
+	if order, err := client.GetOrder(ctx, &pb.GetOrderRequest{OrderId: "order-1"}); err != nil {
+		unaryErr = err
+	} else {
+		unary = order.GetUserId()
+	}
+
+	watch, err := client.WatchOrders(ctx, &pb.WatchOrdersRequest{UserId: "merchant-42"})
+	if err == nil {
+		var update *pb.OrderStatusUpdate
+		if update, err = watch.Recv(); err == nil {
+			stream = update.GetUserId()
+		}
+	}
+	return unary, unaryErr, stream, err
+}
+
+func TestInterceptors(t *testing.T) {
+	apiKeys, tokens := newVerifiers(t)
+	client := dial(t, streamauth.NewInterceptors(apiKeys, tokens, map[string]string{
+		pb.OrderStream_GetOrder_FullMethodName:    auth.ScopeOrdersRead,
+		pb.OrderStream_WatchOrders_FullMethodName: auth.ScopeOrdersRead,
+	}))
+
+	exp := time.Now().Add(time.Hour).Unix()
+	validJWT := hs256(map[string]any{"sub": "merchant-7", "aud": "grpc-stream", "exp": exp, "scope": auth.ScopeOrdersRead})
+
+	tests := []struct {
+		name     string
+		md       []string
+		wantCode codes.Code
+		wantSub  string
+	}{
+		{"API key", []string{streamauth.MetadataAPIKey, "reader-key"}, codes.OK, "merchant-42"},
+		{"API key in authorization", []string{"authorization", "ApiKey reader-key"}, codes.OK, "merchant-42"},
+		{"JWT", []string{"authorization", "Bearer " + validJWT}, codes.OK, "merchant-7"},
+
+		{"unknown API key", []string{streamauth.MetadataAPIKey, "stolen-key"}, codes.Unauthenticated, ""},
+		{"bad token", []string{"authorization", "Bearer " + validJWT[:len(validJWT)-4] + "AAAA"}, codes.Unauthenticated, ""},
+		{"token for another audience", []string{"authorization", "Bearer " + hs256(map[string]any{"sub": "merchant-7", "aud": "order-api", "exp": exp})}, codes.Unauthenticated, ""},
+		{"no credentials", nil, codes.Unauthenticated, ""},
+		{"missing scope", []string{streamauth.MetadataAPIKey, "writer-key"}, codes.PermissionDenied, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			ctx = metadata.AppendToOutgoingContext(ctx, tt.md...)
+
+			unary, unaryErr, stream, streamErr := call(ctx, client)
+
+			for kind, got := range map[string]struct {
+				sub string
+				err error
+			}{"unary": {unary, unaryErr}, "stream": {stream, streamErr}} {
+				if code := status.Code(got.err); code != tt.wantCode {
+					t.Errorf("%s: code = %s, want %s (%v)", kind, code, tt.wantCode, got.err)
+				}
+				if got.sub != tt.wantSub {
+					t.Errorf("%s: caller = %q, want %q", kind, got.sub, tt.wantSub)
+				}
+			}
+		})
+	}
+}
+
+func TestInterceptorsWithoutVerifiers(t *testing.T) {
+	client := dial(t, streamauth.NewInterceptors(nil, nil, map[string]string{
+		pb.OrderStream_GetOrder_FullMethodName: auth.ScopeOrdersRead,
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	unary, unaryErr, stream, streamErr := call(ctx, client)
+	if unaryErr != nil || streamErr != nil {
+		t.Fatalf("unauthenticated RPCs failed: %v, %v", unaryErr, streamErr)
+	}
+	if unary != "" || stream != "" {
+		t.Errorf("callers = %q, %q; want none", unary, stream)
+	}
+}
@@ -0,0 +1,21 @@
+package contracts
+
+import (
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+)
+
+// OrderReader looks up the current state of orders.
+type OrderReader interface {
+	// GetOrder returns models.ErrOrderNotFound when the order is unknown.
+	GetOrder(ctx context.Context, orderID string) (*models.Order, error)
+}
+
+// OrderProjection remembers the orders this server has accepted, so they
+// can be looked up before order-processor has persisted them.
+type OrderProjection interface {
+	OrderReader
+
+	// Apply records the latest known state of an order.
+	Apply(ctx context.Context, order *models.Order) error
+}
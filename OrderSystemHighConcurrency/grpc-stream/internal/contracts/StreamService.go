@@ -3,8 +3,25 @@ package contracts
 import (
 	"OrderSystemHighConcurrency/shared/models"
 	"context"
+	"errors"
 )
 
+// ErrInvalidOrder is wrapped by PublishOrder errors caused by the order
+// itself; the client has to fix the order rather than retry it.
+var ErrInvalidOrder = errors.New("invalid order")
+
 type StreamService interface {
+	// PublishOrder validates the order and publishes it to Kafka.
 	PublishOrder(ctx context.Context, order *models.Order) error
+
+	// GetOrder returns the current state of an order. Orders accepted by
+	// this server but not yet persisted are reported as QUEUED; unknown
+	// orders return models.ErrOrderNotFound.
+	GetOrder(ctx context.Context, orderID string) (*models.Order, error)
+
+	// CancelOrder publishes a cancellation request for the order and
+	// returns the order as known before cancelling. request needs the
+	// order ID; the user ID and metadata are only used when the order
+	// can't be looked up. Completed orders return models.ErrCancelTooLate.
+	CancelOrder(ctx context.Context, request *models.Order) (*models.Order, error)
 }
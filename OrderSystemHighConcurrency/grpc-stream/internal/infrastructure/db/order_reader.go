@@ -0,0 +1,71 @@
+package db
+
+import (
+	"OrderSystemHighConcurrency/grpc-stream/internal/contracts"
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// orderReader implements contracts.OrderReader on top of the orders table
+// written by order-processor
+type orderReader struct {
+	db *sql.DB
+}
+
+// NewOrderReader creates a SQL Server backed order reader
+func NewOrderReader(db *sql.DB) contracts.OrderReader {
+	return &orderReader{db: db}
+}
+
+// GetOrder loads a single order by ID
+func (r *orderReader) GetOrder(ctx context.Context, orderID string) (*models.Order, error) {
+	var (
+		o        models.Order
+		metadata sql.NullString
+		items    sql.NullString
+	)
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT order_id, user_id, amount, currency, status,
+			source, retry_count, created_at, updated_at,
+			metadata, items
+		FROM orders WHERE order_id = @p1`,
+		orderID,
+	).Scan(
+		&o.OrderID,
+		&o.UserID,
+		&o.Amount,
+		&o.Currency,
+		&o.Status,
+		&o.Source,
+		&o.RetryCount,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+		&metadata,
+		&items,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata.Valid {
+		if err := json.Unmarshal([]byte(metadata.String), &o.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	if items.Valid {
+		if err := json.Unmarshal([]byte(items.String), &o.Items); err != nil {
+			return nil, err
+		}
+	}
+
+	o.CreatedAt = o.CreatedAt.UTC()
+	o.UpdatedAt = o.UpdatedAt.UTC()
+	return &o, nil
+}
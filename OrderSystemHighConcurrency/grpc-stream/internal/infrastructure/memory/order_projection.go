@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"OrderSystemHighConcurrency/grpc-stream/internal/contracts"
+	"OrderSystemHighConcurrency/shared/models"
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// projected is an order and when it stops being served
+type projected struct {
+	order   models.Order
+	expires time.Time
+}
+
+// orderProjection implements contracts.OrderProjection in memory.
+// It knows about orders accepted by this grpc-stream replica and the status
+// changes it has seen since. Orders expire ttl after their last change and
+// the least recently changed ones are evicted beyond maxOrders.
+type orderProjection struct {
+	ttl       time.Duration
+	maxOrders int
+
+	mu     sync.RWMutex
+	orders map[string]*list.Element // of *projected
+	recent *list.List               // most recently changed first
+}
+
+// NewOrderProjection creates an empty in-memory projection. A ttl or
+// maxOrders of 0 disables that limit.
+func NewOrderProjection(ttl time.Duration, maxOrders int) contracts.OrderProjection {
+	return &orderProjection{
+		ttl:       ttl,
+		maxOrders: maxOrders,
+		orders:    make(map[string]*list.Element),
+		recent:    list.New(),
+	}
+}
+
+// Apply stores a copy of the order, replacing any previous state. A state
+// the stored one can't move to is stale, e.g. QUEUED after COMPLETED, and
+// is ignored.
+func (p *orderProjection) Apply(ctx context.Context, order *models.Order) error {
+	if order == nil {
+		return errors.New("order is nil")
+	}
+
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry := &projected{order: *order, expires: now.Add(p.ttl)}
+	if e, ok := p.orders[order.OrderID]; ok {
+		prev := e.Value.(*projected)
+		if !p.expired(prev, now) && prev.order.Status != order.Status && !models.CanTransition(prev.order.Status, order.Status) {
+			return nil
+		}
+		e.Value = entry
+		p.recent.MoveToFront(e)
+	} else {
+		p.orders[order.OrderID] = p.recent.PushFront(entry)
+	}
+
+	p.evict(now)
+	return nil
+}
+
+// GetOrder returns a copy of the stored order
+func (p *orderProjection) GetOrder(ctx context.Context, orderID string) (*models.Order, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	e, ok := p.orders[orderID]
+	if !ok || p.expired(e.Value.(*projected), time.Now()) {
+		return nil, models.ErrOrderNotFound
+	}
+
+	cp := e.Value.(*projected).order
+	return &cp, nil
+}
+
+// evict drops expired orders and those beyond maxOrders, oldest first
+func (p *orderProjection) evict(now time.Time) {
+	for e := p.recent.Back(); e != nil; e = p.recent.Back() {
+		entry := e.Value.(*projected)
+		if !p.expired(entry, now) && (p.maxOrders <= 0 || p.recent.Len() <= p.maxOrders) {
+			return
+		}
+		p.recent.Remove(e)
+		delete(p.orders, entry.order.OrderID)
+	}
+}
+
+func (p *orderProjection) expired(entry *projected, now time.Time) bool {
+	return p.ttl > 0 && !now.Before(entry.expires)
+}
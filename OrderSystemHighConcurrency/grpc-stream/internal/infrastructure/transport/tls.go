@@ -0,0 +1,61 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// ClientAuth decides whether clients must present a certificate
+type ClientAuth string
+
+const (
+	// ClientAuthRequire rejects clients without a certificate signed by the client CA
+	ClientAuthRequire ClientAuth = "require"
+
+	// ClientAuthOptional verifies a client certificate only when one is sent
+	ClientAuthOptional ClientAuth = "optional"
+)
+
+// NewServerCredentials loads the server certificate and key for TLS.
+// With a client CA file the server also verifies client certificates
+// (mutual TLS) according to clientAuth.
+func NewServerCredentials(certFile, keyFile, clientCAFile string, clientAuth ClientAuth) (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load server certificate: %w", err)
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAFile != "" {
+		pem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client CA: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("client CA file holds no PEM certificates")
+		}
+		cfg.ClientCAs = pool
+
+		switch clientAuth {
+		case ClientAuthRequire:
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		case ClientAuthOptional:
+			cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		default:
+			return nil, fmt.Errorf("unknown client auth mode %q", clientAuth)
+		}
+	}
+
+	return credentials.NewTLS(cfg), nil
+}
@@ -0,0 +1,234 @@
+package transport
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// authority is a test CA
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newAuthority(t *testing.T, name string) *authority {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &authority{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue signs a leaf certificate for a server or client and returns its
+// certificate and key PEM
+func (a *authority) issue(t *testing.T, name string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeTemp(t *testing.T, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// pki holds the files of a server and the certificates clients may present
+type pki struct {
+	serverCA     *authority
+	clientCA     *authority
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	client       tls.Certificate // signed by the client CA
+	stranger     tls.Certificate // signed by an unrelated CA
+}
+
+func newPKI(t *testing.T) *pki {
+	t.Helper()
+
+	p := &pki{serverCA: newAuthority(t, "server CA"), clientCA: newAuthority(t, "client CA")}
+
+	certPEM, keyPEM := p.serverCA.issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+	p.certFile = writeTemp(t, "server.crt", certPEM)
+	p.keyFile = writeTemp(t, "server.key", keyPEM)
+	p.clientCAFile = writeTemp(t, "client-ca.crt", p.clientCA.pem)
+
+	var err error
+	if p.client, err = tls.X509KeyPair(p.clientCA.issue(t, "merchant-42", x509.ExtKeyUsageClientAuth)); err != nil {
+		t.Fatal(err)
+	}
+	if p.stranger, err = tls.X509KeyPair(newAuthority(t, "other CA").issue(t, "mallory", x509.ExtKeyUsageClientAuth)); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// serve starts a gRPC server with creds on an in-memory listener
+func serve(t *testing.T, creds credentials.TransportCredentials) *bufconn.Listener {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.Creds(creds))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis
+}
+
+// check makes one RPC over the listener with the client's credentials
+func check(t *testing.T, lis *bufconn.Listener, creds credentials.TransportCredentials) error {
+	t.Helper()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(creds),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+// clientTLS trusts the server CA and presents cert, if any. The cert is
+// sent even when the server asks for another CA's, so untrusted
+// certificates reach the server's verification.
+func (p *pki) clientTLS(cert ...tls.Certificate) credentials.TransportCredentials {
+	roots := x509.NewCertPool()
+	roots.AddCert(p.serverCA.cert)
+
+	cfg := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+	if len(cert) > 0 {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &cert[0], nil
+		}
+	}
+	return credentials.NewTLS(cfg)
+}
+
+func TestServerCredentials(t *testing.T) {
+	p := newPKI(t)
+
+	tests := []struct {
+		name       string
+		clientCA   string
+		clientAuth ClientAuth
+		client     credentials.TransportCredentials
+		wantErr    bool
+	}{
+		{"TLS", "", ClientAuthRequire, p.clientTLS(), false},
+		{"TLS rejects plaintext", "", ClientAuthRequire, insecure.NewCredentials(), true},
+
+		{"mTLS require with client cert", p.clientCAFile, ClientAuthRequire, p.clientTLS(p.client), false},
+		{"mTLS require without client cert", p.clientCAFile, ClientAuthRequire, p.clientTLS(), true},
+		{"mTLS require with untrusted cert", p.clientCAFile, ClientAuthRequire, p.clientTLS(p.stranger), true},
+
+		{"mTLS optional with client cert", p.clientCAFile, ClientAuthOptional, p.clientTLS(p.client), false},
+		{"mTLS optional without client cert", p.clientCAFile, ClientAuthOptional, p.clientTLS(), false},
+		{"mTLS optional with untrusted cert", p.clientCAFile, ClientAuthOptional, p.clientTLS(p.stranger), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds, err := NewServerCredentials(p.certFile, p.keyFile, tt.clientCA, tt.clientAuth)
+			if err != nil {
+				t.Fatalf("NewServerCredentials: %v", err)
+			}
+
+			err = check(t, serve(t, creds), tt.client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RPC err = %v, want error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerCredentialsConfigErrors(t *testing.T) {
+	p := newPKI(t)
+
+	if _, err := NewServerCredentials(p.certFile, p.keyFile, p.clientCAFile, "sometimes"); err == nil {
+		t.Error("unknown client auth mode was accepted")
+	}
+	if _, err := NewServerCredentials(p.certFile, p.keyFile, writeTemp(t, "ca.crt", []byte("not a certificate")), ClientAuthRequire); err == nil {
+		t.Error("client CA without certificates was accepted")
+	}
+	if _, err := NewServerCredentials(p.certFile, p.keyFile, filepath.Join(t.TempDir(), "missing.crt"), ClientAuthRequire); err == nil {
+		t.Error("missing client CA file was accepted")
+	}
+	if _, err := NewServerCredentials(p.keyFile, p.certFile, "", ClientAuthRequire); err == nil {
+		t.Error("swapped certificate and key were accepted")
+	}
+}
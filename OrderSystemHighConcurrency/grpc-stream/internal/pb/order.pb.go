@@ -7,9 +7,9 @@
 package pb
 
 import (
+	pb "OrderSystemHighConcurrency/shared/pb"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
-	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -22,30 +22,83 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-type Order struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Amount        float64                `protobuf:"fixed64,2,opt,name=amount,proto3" json:"amount,omitempty"`
-	CustomerId    string                 `protobuf:"bytes,3,opt,name=customer_id,json=customerId,proto3" json:"customer_id,omitempty"`
-	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+type AckCode int32
+
+const (
+	AckCode_ACK_CODE_UNSPECIFIED AckCode = 0
+	AckCode_ACK_CODE_ACCEPTED    AckCode = 1 // published to Kafka
+	AckCode_ACK_CODE_REJECTED    AckCode = 2 // invalid, resending won't help
+	AckCode_ACK_CODE_FAILED      AckCode = 3 // could not be published, safe to resend
+)
+
+// Enum value maps for AckCode.
+var (
+	AckCode_name = map[int32]string{
+		0: "ACK_CODE_UNSPECIFIED",
+		1: "ACK_CODE_ACCEPTED",
+		2: "ACK_CODE_REJECTED",
+		3: "ACK_CODE_FAILED",
+	}
+	AckCode_value = map[string]int32{
+		"ACK_CODE_UNSPECIFIED": 0,
+		"ACK_CODE_ACCEPTED":    1,
+		"ACK_CODE_REJECTED":    2,
+		"ACK_CODE_FAILED":      3,
+	}
+)
+
+func (x AckCode) Enum() *AckCode {
+	p := new(AckCode)
+	*p = x
+	return p
+}
+
+func (x AckCode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AckCode) Descriptor() protoreflect.EnumDescriptor {
+	return file_grpc_stream_proto_order_proto_enumTypes[0].Descriptor()
+}
+
+func (AckCode) Type() protoreflect.EnumType {
+	return &file_grpc_stream_proto_order_proto_enumTypes[0]
+}
+
+func (x AckCode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AckCode.Descriptor instead.
+func (AckCode) EnumDescriptor() ([]byte, []int) {
+	return file_grpc_stream_proto_order_proto_rawDescGZIP(), []int{0}
+}
+
+type StreamResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Human readable outcome; "received" for accepted orders as before
+	Status        string  `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	OrderId       string  `protobuf:"bytes,2,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Code          AckCode `protobuf:"varint,3,opt,name=code,proto3,enum=grpcstream.AckCode" json:"code,omitempty"`
+	Error         string  `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"` // set unless accepted
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Order) Reset() {
-	*x = Order{}
+func (x *StreamResponse) Reset() {
+	*x = StreamResponse{}
 	mi := &file_grpc_stream_proto_order_proto_msgTypes[0]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Order) String() string {
+func (x *StreamResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Order) ProtoMessage() {}
+func (*StreamResponse) ProtoMessage() {}
 
-func (x *Order) ProtoReflect() protoreflect.Message {
+func (x *StreamResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_grpc_stream_proto_order_proto_msgTypes[0]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -57,60 +110,61 @@ func (x *Order) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Order.ProtoReflect.Descriptor instead.
-func (*Order) Descriptor() ([]byte, []int) {
+// Deprecated: Use StreamResponse.ProtoReflect.Descriptor instead.
+func (*StreamResponse) Descriptor() ([]byte, []int) {
 	return file_grpc_stream_proto_order_proto_rawDescGZIP(), []int{0}
 }
 
-func (x *Order) GetId() string {
+func (x *StreamResponse) GetStatus() string {
 	if x != nil {
-		return x.Id
+		return x.Status
 	}
 	return ""
 }
 
-func (x *Order) GetAmount() float64 {
+func (x *StreamResponse) GetOrderId() string {
 	if x != nil {
-		return x.Amount
+		return x.OrderId
 	}
-	return 0
+	return ""
 }
 
-func (x *Order) GetCustomerId() string {
+func (x *StreamResponse) GetCode() AckCode {
 	if x != nil {
-		return x.CustomerId
+		return x.Code
 	}
-	return ""
+	return AckCode_ACK_CODE_UNSPECIFIED
 }
 
-func (x *Order) GetCreatedAt() *timestamppb.Timestamp {
+func (x *StreamResponse) GetError() string {
 	if x != nil {
-		return x.CreatedAt
+		return x.Error
 	}
-	return nil
+	return ""
 }
 
-type StreamResponse struct {
+type CreateOrderResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StreamResponse) Reset() {
-	*x = StreamResponse{}
+func (x *CreateOrderResponse) Reset() {
+	*x = CreateOrderResponse{}
 	mi := &file_grpc_stream_proto_order_proto_msgTypes[1]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StreamResponse) String() string {
+func (x *CreateOrderResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StreamResponse) ProtoMessage() {}
+func (*CreateOrderResponse) ProtoMessage() {}
 
-func (x *StreamResponse) ProtoReflect() protoreflect.Message {
+func (x *CreateOrderResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_grpc_stream_proto_order_proto_msgTypes[1]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -122,35 +176,371 @@ func (x *StreamResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StreamResponse.ProtoReflect.Descriptor instead.
-func (*StreamResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use CreateOrderResponse.ProtoReflect.Descriptor instead.
+func (*CreateOrderResponse) Descriptor() ([]byte, []int) {
 	return file_grpc_stream_proto_order_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *StreamResponse) GetStatus() string {
+func (x *CreateOrderResponse) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *CreateOrderResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type GetOrderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetOrderRequest) Reset() {
+	*x = GetOrderRequest{}
+	mi := &file_grpc_stream_proto_order_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOrderRequest) ProtoMessage() {}
+
+func (x *GetOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_stream_proto_order_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOrderRequest.ProtoReflect.Descriptor instead.
+func (*GetOrderRequest) Descriptor() ([]byte, []int) {
+	return file_grpc_stream_proto_order_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetOrderRequest) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+// CancelOrderRequest names the order to cancel. user_id and metadata are
+// only needed for orders this server can't look up, to key the request
+// like the order's other events.
+type CancelOrderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Metadata      map[string]string      `protobuf:"bytes,3,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelOrderRequest) Reset() {
+	*x = CancelOrderRequest{}
+	mi := &file_grpc_stream_proto_order_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelOrderRequest) ProtoMessage() {}
+
+func (x *CancelOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_stream_proto_order_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelOrderRequest.ProtoReflect.Descriptor instead.
+func (*CancelOrderRequest) Descriptor() ([]byte, []int) {
+	return file_grpc_stream_proto_order_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CancelOrderRequest) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *CancelOrderRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *CancelOrderRequest) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type CancelOrderResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelOrderResponse) Reset() {
+	*x = CancelOrderResponse{}
+	mi := &file_grpc_stream_proto_order_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelOrderResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelOrderResponse) ProtoMessage() {}
+
+func (x *CancelOrderResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_stream_proto_order_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelOrderResponse.ProtoReflect.Descriptor instead.
+func (*CancelOrderResponse) Descriptor() ([]byte, []int) {
+	return file_grpc_stream_proto_order_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CancelOrderResponse) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *CancelOrderResponse) GetStatus() string {
 	if x != nil {
 		return x.Status
 	}
 	return ""
 }
 
+// WatchOrdersRequest selects orders by ID, by user, or both. Without a
+// cursor only changes from now on are sent.
+type WatchOrdersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderIds      []string               `protobuf:"bytes,1,rep,name=order_ids,json=orderIds,proto3" json:"order_ids,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Cursor        string                 `protobuf:"bytes,3,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchOrdersRequest) Reset() {
+	*x = WatchOrdersRequest{}
+	mi := &file_grpc_stream_proto_order_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchOrdersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchOrdersRequest) ProtoMessage() {}
+
+func (x *WatchOrdersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_stream_proto_order_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchOrdersRequest.ProtoReflect.Descriptor instead.
+func (*WatchOrdersRequest) Descriptor() ([]byte, []int) {
+	return file_grpc_stream_proto_order_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *WatchOrdersRequest) GetOrderIds() []string {
+	if x != nil {
+		return x.OrderIds
+	}
+	return nil
+}
+
+func (x *WatchOrdersRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *WatchOrdersRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type OrderStatusUpdate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Transition    *pb.StatusTransition   `protobuf:"bytes,4,opt,name=transition,proto3" json:"transition,omitempty"` // the change that led to status
+	Cursor        string                 `protobuf:"bytes,5,opt,name=cursor,proto3" json:"cursor,omitempty"`         // resume point after this update
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderStatusUpdate) Reset() {
+	*x = OrderStatusUpdate{}
+	mi := &file_grpc_stream_proto_order_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderStatusUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderStatusUpdate) ProtoMessage() {}
+
+func (x *OrderStatusUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_stream_proto_order_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderStatusUpdate.ProtoReflect.Descriptor instead.
+func (*OrderStatusUpdate) Descriptor() ([]byte, []int) {
+	return file_grpc_stream_proto_order_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *OrderStatusUpdate) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *OrderStatusUpdate) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *OrderStatusUpdate) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *OrderStatusUpdate) GetTransition() *pb.StatusTransition {
+	if x != nil {
+		return x.Transition
+	}
+	return nil
+}
+
+func (x *OrderStatusUpdate) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
 var File_grpc_stream_proto_order_proto protoreflect.FileDescriptor
 
 const file_grpc_stream_proto_order_proto_rawDesc = "" +
 	"\n" +
 	"\x1dgrpc-stream/proto/order.proto\x12\n" +
-	"grpcstream\x1a\x1fgoogle/protobuf/timestamp.proto\"\x8b\x01\n" +
-	"\x05Order\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
-	"\x06amount\x18\x02 \x01(\x01R\x06amount\x12\x1f\n" +
-	"\vcustomer_id\x18\x03 \x01(\tR\n" +
-	"customerId\x129\n" +
-	"\n" +
-	"created_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"(\n" +
+	"grpcstream\x1a\x18shared/proto/order.proto\"\x82\x01\n" +
 	"\x0eStreamResponse\x12\x16\n" +
-	"\x06status\x18\x01 \x01(\tR\x06status2P\n" +
-	"\vOrderStream\x12A\n" +
-	"\fStreamOrders\x12\x11.grpcstream.Order\x1a\x1a.grpcstream.StreamResponse(\x010\x01B4Z2OrderSystemHighConcurrency/grpc-stream/internal/pbb\x06proto3"
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x19\n" +
+	"\border_id\x18\x02 \x01(\tR\aorderId\x12'\n" +
+	"\x04code\x18\x03 \x01(\x0e2\x13.grpcstream.AckCodeR\x04code\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"H\n" +
+	"\x13CreateOrderResponse\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\",\n" +
+	"\x0fGetOrderRequest\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\"\xcf\x01\n" +
+	"\x12CancelOrderRequest\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12H\n" +
+	"\bmetadata\x18\x03 \x03(\v2,.grpcstream.CancelOrderRequest.MetadataEntryR\bmetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"H\n" +
+	"\x13CancelOrderResponse\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\"b\n" +
+	"\x12WatchOrdersRequest\x12\x1b\n" +
+	"\torder_ids\x18\x01 \x03(\tR\borderIds\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06cursor\x18\x03 \x01(\tR\x06cursor\"\xb4\x01\n" +
+	"\x11OrderStatusUpdate\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12;\n" +
+	"\n" +
+	"transition\x18\x04 \x01(\v2\x1b.orders.v1.StatusTransitionR\n" +
+	"transition\x12\x16\n" +
+	"\x06cursor\x18\x05 \x01(\tR\x06cursor*f\n" +
+	"\aAckCode\x12\x18\n" +
+	"\x14ACK_CODE_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11ACK_CODE_ACCEPTED\x10\x01\x12\x15\n" +
+	"\x11ACK_CODE_REJECTED\x10\x02\x12\x13\n" +
+	"\x0fACK_CODE_FAILED\x10\x032\xec\x02\n" +
+	"\vOrderStream\x12@\n" +
+	"\fStreamOrders\x12\x10.orders.v1.Order\x1a\x1a.grpcstream.StreamResponse(\x010\x01\x12@\n" +
+	"\vCreateOrder\x12\x10.orders.v1.Order\x1a\x1f.grpcstream.CreateOrderResponse\x129\n" +
+	"\bGetOrder\x12\x1b.grpcstream.GetOrderRequest\x1a\x10.orders.v1.Order\x12N\n" +
+	"\vCancelOrder\x12\x1e.grpcstream.CancelOrderRequest\x1a\x1f.grpcstream.CancelOrderResponse\x12N\n" +
+	"\vWatchOrders\x12\x1e.grpcstream.WatchOrdersRequest\x1a\x1d.grpcstream.OrderStatusUpdate0\x01B4Z2OrderSystemHighConcurrency/grpc-stream/internal/pbb\x06proto3"
 
 var (
 	file_grpc_stream_proto_order_proto_rawDescOnce sync.Once
@@ -164,21 +554,40 @@ func file_grpc_stream_proto_order_proto_rawDescGZIP() []byte {
 	return file_grpc_stream_proto_order_proto_rawDescData
 }
 
-var file_grpc_stream_proto_order_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_grpc_stream_proto_order_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_grpc_stream_proto_order_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_grpc_stream_proto_order_proto_goTypes = []any{
-	(*Order)(nil),                 // 0: grpcstream.Order
-	(*StreamResponse)(nil),        // 1: grpcstream.StreamResponse
-	(*timestamppb.Timestamp)(nil), // 2: google.protobuf.Timestamp
+	(AckCode)(0),                // 0: grpcstream.AckCode
+	(*StreamResponse)(nil),      // 1: grpcstream.StreamResponse
+	(*CreateOrderResponse)(nil), // 2: grpcstream.CreateOrderResponse
+	(*GetOrderRequest)(nil),     // 3: grpcstream.GetOrderRequest
+	(*CancelOrderRequest)(nil),  // 4: grpcstream.CancelOrderRequest
+	(*CancelOrderResponse)(nil), // 5: grpcstream.CancelOrderResponse
+	(*WatchOrdersRequest)(nil),  // 6: grpcstream.WatchOrdersRequest
+	(*OrderStatusUpdate)(nil),   // 7: grpcstream.OrderStatusUpdate
+	nil,                         // 8: grpcstream.CancelOrderRequest.MetadataEntry
+	(*pb.StatusTransition)(nil), // 9: orders.v1.StatusTransition
+	(*pb.Order)(nil),            // 10: orders.v1.Order
 }
 var file_grpc_stream_proto_order_proto_depIdxs = []int32{
-	2, // 0: grpcstream.Order.created_at:type_name -> google.protobuf.Timestamp
-	0, // 1: grpcstream.OrderStream.StreamOrders:input_type -> grpcstream.Order
-	1, // 2: grpcstream.OrderStream.StreamOrders:output_type -> grpcstream.StreamResponse
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	0,  // 0: grpcstream.StreamResponse.code:type_name -> grpcstream.AckCode
+	8,  // 1: grpcstream.CancelOrderRequest.metadata:type_name -> grpcstream.CancelOrderRequest.MetadataEntry
+	9,  // 2: grpcstream.OrderStatusUpdate.transition:type_name -> orders.v1.StatusTransition
+	10, // 3: grpcstream.OrderStream.StreamOrders:input_type -> orders.v1.Order
+	10, // 4: grpcstream.OrderStream.CreateOrder:input_type -> orders.v1.Order
+	3,  // 5: grpcstream.OrderStream.GetOrder:input_type -> grpcstream.GetOrderRequest
+	4,  // 6: grpcstream.OrderStream.CancelOrder:input_type -> grpcstream.CancelOrderRequest
+	6,  // 7: grpcstream.OrderStream.WatchOrders:input_type -> grpcstream.WatchOrdersRequest
+	1,  // 8: grpcstream.OrderStream.StreamOrders:output_type -> grpcstream.StreamResponse
+	2,  // 9: grpcstream.OrderStream.CreateOrder:output_type -> grpcstream.CreateOrderResponse
+	10, // 10: grpcstream.OrderStream.GetOrder:output_type -> orders.v1.Order
+	5,  // 11: grpcstream.OrderStream.CancelOrder:output_type -> grpcstream.CancelOrderResponse
+	7,  // 12: grpcstream.OrderStream.WatchOrders:output_type -> grpcstream.OrderStatusUpdate
+	8,  // [8:13] is the sub-list for method output_type
+	3,  // [3:8] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_grpc_stream_proto_order_proto_init() }
@@ -191,13 +600,14 @@ func file_grpc_stream_proto_order_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_grpc_stream_proto_order_proto_rawDesc), len(file_grpc_stream_proto_order_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   2,
+			NumEnums:      1,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_grpc_stream_proto_order_proto_goTypes,
 		DependencyIndexes: file_grpc_stream_proto_order_proto_depIdxs,
+		EnumInfos:         file_grpc_stream_proto_order_proto_enumTypes,
 		MessageInfos:      file_grpc_stream_proto_order_proto_msgTypes,
 	}.Build()
 	File_grpc_stream_proto_order_proto = out.File
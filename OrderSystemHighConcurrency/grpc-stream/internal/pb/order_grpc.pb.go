@@ -7,6 +7,7 @@
 package pb
 
 import (
+	pb "OrderSystemHighConcurrency/shared/pb"
 	context "context"
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
@@ -20,13 +21,32 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	OrderStream_StreamOrders_FullMethodName = "/grpcstream.OrderStream/StreamOrders"
+	OrderStream_CreateOrder_FullMethodName  = "/grpcstream.OrderStream/CreateOrder"
+	OrderStream_GetOrder_FullMethodName     = "/grpcstream.OrderStream/GetOrder"
+	OrderStream_CancelOrder_FullMethodName  = "/grpcstream.OrderStream/CancelOrder"
+	OrderStream_WatchOrders_FullMethodName  = "/grpcstream.OrderStream/WatchOrders"
 )
 
 // OrderStreamClient is the client API for OrderStream service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type OrderStreamClient interface {
-	StreamOrders(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Order, StreamResponse], error)
+	// StreamOrders acknowledges every order on the stream with its own
+	// StreamResponse, in the order they were received.
+	StreamOrders(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[pb.Order, StreamResponse], error)
+	// CreateOrder publishes a single order. Invalid orders fail with
+	// INVALID_ARGUMENT, publish failures with UNAVAILABLE.
+	CreateOrder(ctx context.Context, in *pb.Order, opts ...grpc.CallOption) (*CreateOrderResponse, error)
+	// GetOrder returns the current state of an order, or NOT_FOUND.
+	GetOrder(ctx context.Context, in *GetOrderRequest, opts ...grpc.CallOption) (*pb.Order, error)
+	// CancelOrder requests cancellation; order-processor cancels the order
+	// unless it has already completed. Orders known to be completed fail
+	// with FAILED_PRECONDITION.
+	CancelOrder(ctx context.Context, in *CancelOrderRequest, opts ...grpc.CallOption) (*CancelOrderResponse, error)
+	// WatchOrders streams the status changes of the selected orders as
+	// order-processor persists them. A stream ended with RESOURCE_EXHAUSTED
+	// fell behind; reconnect with the cursor of the last update received.
+	WatchOrders(ctx context.Context, in *WatchOrdersRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[OrderStatusUpdate], error)
 }
 
 type orderStreamClient struct {
@@ -37,24 +57,88 @@ func NewOrderStreamClient(cc grpc.ClientConnInterface) OrderStreamClient {
 	return &orderStreamClient{cc}
 }
 
-func (c *orderStreamClient) StreamOrders(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Order, StreamResponse], error) {
+func (c *orderStreamClient) StreamOrders(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[pb.Order, StreamResponse], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	stream, err := c.cc.NewStream(ctx, &OrderStream_ServiceDesc.Streams[0], OrderStream_StreamOrders_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
-	x := &grpc.GenericClientStream[Order, StreamResponse]{ClientStream: stream}
+	x := &grpc.GenericClientStream[pb.Order, StreamResponse]{ClientStream: stream}
 	return x, nil
 }
 
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
-type OrderStream_StreamOrdersClient = grpc.BidiStreamingClient[Order, StreamResponse]
+type OrderStream_StreamOrdersClient = grpc.BidiStreamingClient[pb.Order, StreamResponse]
+
+func (c *orderStreamClient) CreateOrder(ctx context.Context, in *pb.Order, opts ...grpc.CallOption) (*CreateOrderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateOrderResponse)
+	err := c.cc.Invoke(ctx, OrderStream_CreateOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderStreamClient) GetOrder(ctx context.Context, in *GetOrderRequest, opts ...grpc.CallOption) (*pb.Order, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(pb.Order)
+	err := c.cc.Invoke(ctx, OrderStream_GetOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderStreamClient) CancelOrder(ctx context.Context, in *CancelOrderRequest, opts ...grpc.CallOption) (*CancelOrderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelOrderResponse)
+	err := c.cc.Invoke(ctx, OrderStream_CancelOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderStreamClient) WatchOrders(ctx context.Context, in *WatchOrdersRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[OrderStatusUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &OrderStream_ServiceDesc.Streams[1], OrderStream_WatchOrders_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchOrdersRequest, OrderStatusUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OrderStream_WatchOrdersClient = grpc.ServerStreamingClient[OrderStatusUpdate]
 
 // OrderStreamServer is the server API for OrderStream service.
 // All implementations must embed UnimplementedOrderStreamServer
 // for forward compatibility.
 type OrderStreamServer interface {
-	StreamOrders(grpc.BidiStreamingServer[Order, StreamResponse]) error
+	// StreamOrders acknowledges every order on the stream with its own
+	// StreamResponse, in the order they were received.
+	StreamOrders(grpc.BidiStreamingServer[pb.Order, StreamResponse]) error
+	// CreateOrder publishes a single order. Invalid orders fail with
+	// INVALID_ARGUMENT, publish failures with UNAVAILABLE.
+	CreateOrder(context.Context, *pb.Order) (*CreateOrderResponse, error)
+	// GetOrder returns the current state of an order, or NOT_FOUND.
+	GetOrder(context.Context, *GetOrderRequest) (*pb.Order, error)
+	// CancelOrder requests cancellation; order-processor cancels the order
+	// unless it has already completed. Orders known to be completed fail
+	// with FAILED_PRECONDITION.
+	CancelOrder(context.Context, *CancelOrderRequest) (*CancelOrderResponse, error)
+	// WatchOrders streams the status changes of the selected orders as
+	// order-processor persists them. A stream ended with RESOURCE_EXHAUSTED
+	// fell behind; reconnect with the cursor of the last update received.
+	WatchOrders(*WatchOrdersRequest, grpc.ServerStreamingServer[OrderStatusUpdate]) error
 	mustEmbedUnimplementedOrderStreamServer()
 }
 
@@ -65,9 +149,21 @@ type OrderStreamServer interface {
 // pointer dereference when methods are called.
 type UnimplementedOrderStreamServer struct{}
 
-func (UnimplementedOrderStreamServer) StreamOrders(grpc.BidiStreamingServer[Order, StreamResponse]) error {
+func (UnimplementedOrderStreamServer) StreamOrders(grpc.BidiStreamingServer[pb.Order, StreamResponse]) error {
 	return status.Error(codes.Unimplemented, "method StreamOrders not implemented")
 }
+func (UnimplementedOrderStreamServer) CreateOrder(context.Context, *pb.Order) (*CreateOrderResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateOrder not implemented")
+}
+func (UnimplementedOrderStreamServer) GetOrder(context.Context, *GetOrderRequest) (*pb.Order, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetOrder not implemented")
+}
+func (UnimplementedOrderStreamServer) CancelOrder(context.Context, *CancelOrderRequest) (*CancelOrderResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CancelOrder not implemented")
+}
+func (UnimplementedOrderStreamServer) WatchOrders(*WatchOrdersRequest, grpc.ServerStreamingServer[OrderStatusUpdate]) error {
+	return status.Error(codes.Unimplemented, "method WatchOrders not implemented")
+}
 func (UnimplementedOrderStreamServer) mustEmbedUnimplementedOrderStreamServer() {}
 func (UnimplementedOrderStreamServer) testEmbeddedByValue()                     {}
 
@@ -90,11 +186,76 @@ func RegisterOrderStreamServer(s grpc.ServiceRegistrar, srv OrderStreamServer) {
 }
 
 func _OrderStream_StreamOrders_Handler(srv interface{}, stream grpc.ServerStream) error {
-	return srv.(OrderStreamServer).StreamOrders(&grpc.GenericServerStream[Order, StreamResponse]{ServerStream: stream})
+	return srv.(OrderStreamServer).StreamOrders(&grpc.GenericServerStream[pb.Order, StreamResponse]{ServerStream: stream})
 }
 
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
-type OrderStream_StreamOrdersServer = grpc.BidiStreamingServer[Order, StreamResponse]
+type OrderStream_StreamOrdersServer = grpc.BidiStreamingServer[pb.Order, StreamResponse]
+
+func _OrderStream_CreateOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(pb.Order)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderStreamServer).CreateOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderStream_CreateOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderStreamServer).CreateOrder(ctx, req.(*pb.Order))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderStream_GetOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderStreamServer).GetOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderStream_GetOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderStreamServer).GetOrder(ctx, req.(*GetOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderStream_CancelOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderStreamServer).CancelOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderStream_CancelOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderStreamServer).CancelOrder(ctx, req.(*CancelOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderStream_WatchOrders_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchOrdersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OrderStreamServer).WatchOrders(m, &grpc.GenericServerStream[WatchOrdersRequest, OrderStatusUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OrderStream_WatchOrdersServer = grpc.ServerStreamingServer[OrderStatusUpdate]
 
 // OrderStream_ServiceDesc is the grpc.ServiceDesc for OrderStream service.
 // It's only intended for direct use with grpc.RegisterService,
@@ -102,7 +263,20 @@ type OrderStream_StreamOrdersServer = grpc.BidiStreamingServer[Order, StreamResp
 var OrderStream_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "grpcstream.OrderStream",
 	HandlerType: (*OrderStreamServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateOrder",
+			Handler:    _OrderStream_CreateOrder_Handler,
+		},
+		{
+			MethodName: "GetOrder",
+			Handler:    _OrderStream_GetOrder_Handler,
+		},
+		{
+			MethodName: "CancelOrder",
+			Handler:    _OrderStream_CancelOrder_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "StreamOrders",
@@ -110,6 +284,11 @@ var OrderStream_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "WatchOrders",
+			Handler:       _OrderStream_WatchOrders_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "grpc-stream/proto/order.proto",
 }
@@ -2,23 +2,64 @@ package services
 
 import (
 	streamContracts "OrderSystemHighConcurrency/grpc-stream/internal/contracts"
+	"OrderSystemHighConcurrency/shared/auth"
 	sharedContracts "OrderSystemHighConcurrency/shared/contracts"
 	"OrderSystemHighConcurrency/shared/models"
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"time"
 )
 
 type streamService struct {
-	producer sharedContracts.Producer
+	producer   sharedContracts.Producer
+	reader     streamContracts.OrderReader
+	projection streamContracts.OrderProjection
 }
 
-func NewStreamService(producer sharedContracts.Producer) streamContracts.StreamService {
-	return &streamService{producer: producer}
+// NewStreamService creates a StreamService.
+// reader serves lookups of persisted orders; projection tracks orders this
+// server has accepted so they can be looked up before they are persisted.
+func NewStreamService(
+	producer sharedContracts.Producer,
+	reader streamContracts.OrderReader,
+	projection streamContracts.OrderProjection,
+) streamContracts.StreamService {
+	return &streamService{
+		producer:   producer,
+		reader:     reader,
+		projection: projection,
+	}
 }
 
 func (s *streamService) PublishOrder(ctx context.Context, order *models.Order) error {
 	if order == nil {
-		return nil
+		return fmt.Errorf("%w: order is empty", streamContracts.ErrInvalidOrder)
+	}
+
+	// An authenticated caller can only submit orders of its own
+	userID, err := auth.BindUser(ctx, order.UserID)
+	if err != nil {
+		return err
+	}
+	order.UserID = userID
+
+	if err := validate(order); err != nil {
+		return fmt.Errorf("%w: %v", streamContracts.ErrInvalidOrder, err)
+	}
+
+	// Whatever status the client sent, a streamed order starts its lifecycle here
+	now := time.Now().UTC()
+	order.Status = models.OrderStatusCreated
+	order.Transitions = nil
+	if order.CreatedAt.IsZero() {
+		order.CreatedAt = now
+	}
+	order.UpdatedAt = now
+	if err := order.TransitionTo(models.OrderStatusQueued, "accepted by grpc-stream"); err != nil {
+		return err
 	}
 
 	if err := s.producer.Publish(ctx, order); err != nil {
@@ -26,6 +67,98 @@ func (s *streamService) PublishOrder(ctx context.Context, order *models.Order) e
 		return err
 	}
 
+	if err := s.projection.Apply(ctx, order); err != nil {
+		log.Printf("[WARN] failed to project order %s: %v", order.OrderID, err)
+	}
+
 	log.Printf("[INFO] order %s published to Kafka via gRPC", order.OrderID)
 	return nil
 }
+
+func (s *streamService) GetOrder(ctx context.Context, orderID string) (*models.Order, error) {
+	if orderID == "" {
+		return nil, fmt.Errorf("%w: order_id is required", streamContracts.ErrInvalidOrder)
+	}
+
+	order, err := s.lookup(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Other users' orders are reported as unknown
+	if !auth.CanAccess(ctx, order.UserID) {
+		return nil, models.ErrOrderNotFound
+	}
+	return order, nil
+}
+
+// lookup finds the order in the read store or else the projection,
+// whoever it belongs to
+func (s *streamService) lookup(ctx context.Context, orderID string) (*models.Order, error) {
+	order, err := s.reader.GetOrder(ctx, orderID)
+	if errors.Is(err, models.ErrOrderNotFound) {
+		order, err = s.projection.GetOrder(ctx, orderID)
+	}
+	return order, err
+}
+
+func (s *streamService) CancelOrder(ctx context.Context, request *models.Order) (*models.Order, error) {
+	if request == nil || request.OrderID == "" {
+		return nil, fmt.Errorf("%w: order_id is required", streamContracts.ErrInvalidOrder)
+	}
+
+	userID, err := auth.BindUser(ctx, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	request.UserID = userID
+
+	// Prefer the known order so the request is keyed like its other events.
+	// Only an order unknown everywhere is cancelled as requested; one of
+	// another user is reported as unknown and left alone.
+	order, err := s.lookup(ctx, request.OrderID)
+	switch {
+	case errors.Is(err, models.ErrOrderNotFound):
+		if request.UserID == "" {
+			return nil, err
+		}
+		order = request
+	case err != nil:
+		return nil, err
+	case !auth.CanAccess(ctx, order.UserID):
+		return nil, models.ErrOrderNotFound
+	case request.UserID != "" && request.UserID != order.UserID:
+		return nil, models.ErrOrderNotFound
+	}
+
+	switch order.Status {
+	case models.OrderStatusCompleted:
+		return nil, models.ErrCancelTooLate
+	case models.OrderStatusCancelled:
+		return order, nil
+	}
+
+	if err := s.producer.PublishEvent(ctx, models.EventOrderCancelRequested, order); err != nil {
+		log.Printf("[ERROR] failed to publish cancellation of order %s: %v", order.OrderID, err)
+		return nil, err
+	}
+
+	log.Printf("[INFO] cancellation of order %s published to Kafka via gRPC", order.OrderID)
+	return order, nil
+}
+
+// validate runs the checks order-api applies to new orders
+func validate(order *models.Order) error {
+	if order.OrderID == "" {
+		return errors.New("order_id is required")
+	}
+	if order.UserID == "" {
+		return errors.New("user_id is required")
+	}
+
+	order.Currency = strings.ToUpper(order.CurrencyCode())
+	if err := order.ValidateAmount(); err != nil {
+		return err
+	}
+	return order.ValidateItems()
+}
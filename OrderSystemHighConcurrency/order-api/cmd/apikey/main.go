@@ -0,0 +1,63 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"OrderSystemHighConcurrency/shared/auth"
+)
+
+const usage = `apikey creates an API key for order-api and grpc-stream.
+
+Usage:
+  apikey -user merchant-42 [-id key-1] [-scopes orders:read,orders:write]
+
+The key is printed once on stderr; give it to the merchant. The entry on
+stdout holds only its hash and goes into the file named by
+AUTH_API_KEYS_FILE (default ./config/api-keys.json), a JSON array such as
+config/api-keys.example.json. Services read the file at startup.
+`
+
+func main() {
+	log.SetFlags(0)
+
+	flags := flag.NewFlagSet("apikey", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	userID := flags.String("user", "", "user the key acts for")
+	id := flags.String("id", "", "key ID shown in logs (default: random)")
+	scopes := flags.String("scopes", auth.ScopeOrdersRead+","+auth.ScopeOrdersWrite, "comma separated scopes")
+	flags.Parse(os.Args[1:])
+
+	if *userID == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatalf("failed to generate key: %v", err)
+	}
+	key := "ok_" + hex.EncodeToString(secret)
+	if *id == "" {
+		*id = "key-" + hex.EncodeToString(secret[:4])
+	}
+
+	entry, err := json.MarshalIndent(map[string]any{
+		"id":      *id,
+		"user_id": *userID,
+		"hash":    auth.HashAPIKey(key),
+		"scopes":  strings.Split(*scopes, ","),
+	}, "", "  ")
+	if err != nil {
+		log.Fatalf("failed to encode entry: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "API key (shown once): %s\n", key)
+	fmt.Println(string(entry))
+}
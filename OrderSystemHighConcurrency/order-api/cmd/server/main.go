@@ -1,14 +1,27 @@
 package main
 
 import (
+	apiauth "OrderSystemHighConcurrency/order-api/internal/auth"
 	"OrderSystemHighConcurrency/order-api/internal/config"
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
 	"OrderSystemHighConcurrency/order-api/internal/handlers"
+	"OrderSystemHighConcurrency/shared/auth"
+	sharedcontracts "OrderSystemHighConcurrency/shared/contracts"
 	"OrderSystemHighConcurrency/shared/kafka"
+	"OrderSystemHighConcurrency/shared/schema"
 
+	"OrderSystemHighConcurrency/order-api/internal/infrastructure/db"
+	"OrderSystemHighConcurrency/order-api/internal/infrastructure/idempotency"
+	apikafka "OrderSystemHighConcurrency/order-api/internal/infrastructure/kafka"
+	"OrderSystemHighConcurrency/order-api/internal/infrastructure/memory"
+	"OrderSystemHighConcurrency/order-api/internal/infrastructure/outbox"
 	"OrderSystemHighConcurrency/order-api/internal/infrastructure/ratelimit"
+	"OrderSystemHighConcurrency/order-api/internal/infrastructure/webhook"
 	"OrderSystemHighConcurrency/order-api/internal/services"
 
 	"context"
+	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -24,39 +37,144 @@ func main() {
 	// 1️⃣ Load configuration (env-based)
 	// ------------------------------------------------
 	cfg := config.LoadConfig()
-	kafkaBrokers := cfg.KafkaBrokers
-	kafkaTopic := cfg.KafkaTopic
 
+	// Register the event schema first: an incompatible schema must not be produced
+	schemaID := registerSchema(cfg)
+
+	producerConfig := kafka.ProducerConfig{
+		Brokers:       cfg.KafkaBrokers,
+		Topic:         cfg.KafkaTopic,
+		KeyStrategy:   kafka.KeyStrategy(cfg.KafkaKeyStrategy),
+		Partitioner:   kafka.Partitioner(cfg.KafkaPartitioner),
+		Source:        "order-api",
+		SchemaVersion: cfg.KafkaSchemaVersion,
+		Encoding:      kafka.Encoding(cfg.KafkaEncoding),
+		SchemaID:      schemaID,
+	}
+
+	// ------------------------------------------------
+	// 2️⃣ Initialize Kafka Producer (with durable outbox fallback)
 	// ------------------------------------------------
-	// 2️⃣ Initialize Kafka Producer
+	kafkaProducer := apikafka.NewLazyProducer(func() (contracts.Producer, error) {
+		return kafka.NewKafkaProducer(producerConfig)
+	}, cfg.KafkaRetryInterval)
+	defer kafkaProducer.Close() // close producer on shutdown
 
-	producer, err := kafka.NewKafkaProducer(kafkaBrokers, kafkaTopic)
+	orderOutbox, err := outbox.NewFileOutbox(cfg.OutboxDir)
 	if err != nil {
-		log.Println("Kafka not available, continuing for debug:", err)
+		log.Fatalf("failed to open outbox: %v", err)
+	}
+	defer orderOutbox.Close()
+
+	producer := outbox.NewProducer(kafkaProducer, orderOutbox)
+	relay := outbox.NewRelay(orderOutbox, kafkaProducer, cfg.OutboxRelayInterval)
+
+	// ------------------------------------------------
+	// 3️⃣ Database (only when a SQL-backed store is selected)
+	// ------------------------------------------------
+	var database *sql.DB
+	if cfg.OrderReadStore == "sql" || cfg.IdempotencyStore == "sql" || cfg.WebhookStore == "sql" {
+		database, err = db.NewDB(cfg)
+		if err != nil {
+			log.Fatalf("failed to connect DB: %v", err)
+		}
+		defer database.Close()
+	}
+
+	// ------------------------------------------------
+	// 4️⃣ Initialize Read Store
+	// ------------------------------------------------
+	projection := memory.NewOrderProjection(cfg.ProjectionTTL, cfg.ProjectionMaxOrders)
+
+	var reader contracts.OrderReader = projection
+	if cfg.OrderReadStore == "sql" {
+		reader = db.NewOrderReader(database)
+	} else if cfg.StatusTopic == "" {
+		log.Println("WARNING: STATUS_TOPIC is empty, the memory read store only shows orders as accepted")
 	}
-	defer producer.Close() // close producer on shutdown
+
 	// ------------------------------------------------
+	// 5️⃣ Initialize Idempotency Store
 	// ------------------------------------------------
-	// 3️⃣ Initialize Order Service
+	idempotencyStore := idempotency.NewMemoryStore()
+	if cfg.IdempotencyStore == "sql" {
+		idempotencyStore = idempotency.NewSQLStore(database)
+	}
+	idempotencyMiddleware := idempotency.NewMiddleware(idempotencyStore, cfg.IdempotencyTTL)
+
 	// ------------------------------------------------
-	orderService := services.NewOrderService(producer)
+	// 6️⃣ Initialize Order Service
+	// ------------------------------------------------
+	orderService := services.NewOrderService(producer, reader, projection)
+
+	// Webhooks are fed by the processor's status events
+	webhookStore := webhook.NewMemoryStore()
+	if cfg.WebhookStore == "sql" {
+		webhookStore = webhook.NewSQLStore(database)
+	}
+	webhookSender := webhook.NewHTTPSender(cfg.WebhookTimeout, cfg.WebhookBreakerThreshold, cfg.WebhookBreakerCooldown)
+	webhookService := services.NewWebhookService(webhookStore, webhookSender)
+	webhookDispatcher := services.NewWebhookDispatcher(
+		webhookStore,
+		webhookSender,
+		cfg.WebhookMaxAttempts,
+		cfg.WebhookBackoff,
+		cfg.WebhookMaxBackoff,
+		cfg.WebhookWorkers,
+	)
 
 	// ------------------------------------------------
-	// 4️⃣ Initialize HTTP Handler
+	// 7️⃣ Initialize HTTP Handler
 	// ------------------------------------------------
 	orderHandler := handlers.NewOrderHandler(orderService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+
+	// Status streams connect to Kafka on the first subscriber, like the producer
+	var statusFeed sharedcontracts.StatusFeed = disabledFeed{}
+	if cfg.StatusTopic != "" {
+		feed := apikafka.NewLazyStatusFeed(cfg.KafkaBrokers, cfg.StatusTopic, cfg.KafkaRetryInterval)
+		defer feed.Close()
+		statusFeed = feed
+
+		// Keep accepted orders current until the projection lets them go
+		if cfg.OrderReadStore != "sql" {
+			go followStatus(context.Background(), feed, projection)
+		}
+	}
+	streamHandler := handlers.NewStreamHandler(orderService, statusFeed, cfg.StreamHeartbeat, cfg.StreamMaxOrdersPerSocket)
 
 	// ------------------------------------------------
-	// 5️⃣ Rate Limiter Middleware
+	// 8️⃣ Rate Limiter & Auth Middleware
 	// ------------------------------------------------
 	rateLimiter := ratelimit.NewIPRateLimiter(100, time.Minute)
+	streamLimiter, err := ratelimit.NewConnLimiter(cfg.StreamMaxConnections, cfg.StreamMaxPerClient, cfg.StreamTrustedProxies)
+	if err != nil {
+		log.Fatalf("invalid stream limiter config: %v", err)
+	}
+	authn := apiauth.NewMiddleware(authenticators(cfg)...)
+
+	// protect rate-limits first, then authenticates and checks the scope
+	protect := func(scope string, h http.Handler) http.Handler {
+		return rateLimiter.Middleware(authn.Require(scope, h))
+	}
 
 	mux := http.NewServeMux()
-	mux.Handle("/orders", rateLimiter.Middleware(orderHandler))
+	mux.Handle("POST /orders", protect(auth.ScopeOrdersWrite, idempotencyMiddleware.Handler(orderHandler)))
+	mux.Handle("GET /orders", protect(auth.ScopeOrdersRead, http.HandlerFunc(orderHandler.ListOrders)))
+	mux.Handle("GET /orders/{id}", protect(auth.ScopeOrdersRead, http.HandlerFunc(orderHandler.GetOrder)))
+	mux.Handle("DELETE /orders/{id}", protect(auth.ScopeOrdersWrite, http.HandlerFunc(orderHandler.CancelOrder)))
+	mux.Handle("GET /orders/{id}/history", protect(auth.ScopeOrdersRead, http.HandlerFunc(orderHandler.GetTimeline)))
+	mux.Handle("GET /orders/{id}/events", protect(auth.ScopeOrdersRead, streamLimiter.Middleware(http.HandlerFunc(streamHandler.Events))))
+	mux.Handle("GET /ws/orders", protect(auth.ScopeOrdersRead, streamLimiter.Middleware(streamHandler.WebSocket())))
+	mux.Handle("POST /webhooks", protect(auth.ScopeWebhooks, http.HandlerFunc(webhookHandler.Register)))
+	mux.Handle("GET /webhooks", protect(auth.ScopeWebhooks, http.HandlerFunc(webhookHandler.List)))
+	mux.Handle("GET /webhooks/{id}", protect(auth.ScopeWebhooks, http.HandlerFunc(webhookHandler.Get)))
+	mux.Handle("DELETE /webhooks/{id}", protect(auth.ScopeWebhooks, http.HandlerFunc(webhookHandler.Delete)))
+	mux.Handle("GET /webhooks/{id}/deliveries", protect(auth.ScopeWebhooks, http.HandlerFunc(webhookHandler.Deliveries)))
 	mux.Handle("/metrics", promhttp.Handler())
 
 	// ------------------------------------------------
-	// 6️⃣ HTTP Server
+	// 9️⃣ HTTP Server
 	// ------------------------------------------------
 	server := &http.Server{
 		Addr:    ":8080",
@@ -64,7 +182,7 @@ func main() {
 	}
 
 	// ------------------------------------------------
-	// 7️⃣ Graceful Shutdown
+	// 🔟 Graceful Shutdown
 	// ------------------------------------------------
 	ctx, stop := signal.NotifyContext(
 		context.Background(),
@@ -73,6 +191,20 @@ func main() {
 	)
 	defer stop()
 
+	go relay.Run(ctx)
+
+	go webhookDispatcher.Run(ctx)
+	if cfg.StatusTopic != "" {
+		statusConsumer := apikafka.NewStatusConsumer(
+			cfg.KafkaBrokers,
+			cfg.WebhookGroup,
+			cfg.StatusTopic,
+			cfg.KafkaRetryInterval,
+			webhookDispatcher.HandleStatus,
+		)
+		go statusConsumer.Run(ctx)
+	}
+
 	go func() {
 		log.Printf("Order API running on %s", server.Addr)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -92,3 +224,91 @@ func main() {
 		log.Println("order-api stopped cleanly")
 	}
 }
+
+// followStatus applies every status change to the projection, resuming
+// from the last change seen whenever the watch ends
+func followStatus(ctx context.Context, feed sharedcontracts.StatusFeed, projection contracts.OrderProjection) {
+	var cursor string
+	for ctx.Err() == nil {
+		err := feed.Watch(ctx, sharedcontracts.StatusFilter{All: true}, cursor, func(update sharedcontracts.StatusUpdate) error {
+			cursor = update.Cursor
+			return projection.Apply(ctx, update.Order)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("status feed watch for projection ended: %v", err)
+		time.Sleep(time.Second)
+	}
+}
+
+// disabledFeed rejects every watch when STATUS_TOPIC is empty
+type disabledFeed struct{}
+
+func (disabledFeed) Watch(context.Context, sharedcontracts.StatusFilter, string, func(sharedcontracts.StatusUpdate) error) error {
+	return errors.New("status streams are disabled")
+}
+
+func (disabledFeed) Cursor() (string, error) {
+	return "", errors.New("status streams are disabled")
+}
+
+// authenticators builds the configured authenticators; a method whose keys
+// can't be loaded stops startup rather than leaving the API open
+func authenticators(cfg *config.Config) []contracts.Authenticator {
+	var list []contracts.Authenticator
+
+	for _, method := range cfg.AuthMethods {
+		switch method {
+		case "none":
+			log.Println("WARNING: authentication is disabled, user_id is trusted from requests")
+			return nil
+
+		case "api_key":
+			keys, err := auth.NewAPIKeyVerifier(cfg.AuthAPIKeysFile)
+			if err != nil {
+				log.Fatalf("failed to load API keys: %v (create the file with cmd/apikey, see config/api-keys.example.json)", err)
+			}
+			list = append(list, apiauth.NewAPIKeyAuthenticator(keys))
+
+		case "jwt":
+			tokens, err := auth.NewJWTVerifier(cfg.AuthJWKSFile, cfg.AuthJWTIssuer, cfg.AuthJWTAudience)
+			if err != nil {
+				log.Fatalf("failed to load JWKS: %v (see config/jwks.example.json)", err)
+			}
+			list = append(list, apiauth.NewBearerAuthenticator(tokens))
+
+		default:
+			log.Fatalf("unknown auth method %q", method)
+		}
+	}
+
+	if len(list) == 0 {
+		log.Fatal("no auth method configured; set AUTH_METHODS=none to disable authentication")
+	}
+	return list
+}
+
+// registerSchema registers the order event schema with the configured
+// registry and returns its ID, or 0 when no registry is configured
+func registerSchema(cfg *config.Config) int {
+	registry, err := schema.Open(cfg.SchemaRegistry, cfg.SchemaRegistryPath, cfg.SchemaRegistryURL, cfg.SchemaCompatibility)
+	if err != nil {
+		log.Fatalf("invalid schema registry config: %v", err)
+	}
+	if registry == nil {
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	id, err := schema.RegisterOrderEvent(ctx, registry, cfg.KafkaTopic)
+	if err != nil {
+		log.Fatalf("failed to register event schema: %v", err)
+	}
+
+	log.Printf("order event schema registered with id %d", id)
+	return id
+}
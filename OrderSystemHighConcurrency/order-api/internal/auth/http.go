@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	sharedcontracts "OrderSystemHighConcurrency/shared/contracts"
+	"net/http"
+	"strings"
+)
+
+// HeaderAPIKey carries an API key; "Authorization: ApiKey <key>" works too
+const HeaderAPIKey = "X-API-Key"
+
+// apiKeyAuthenticator implements contracts.Authenticator for API keys
+type apiKeyAuthenticator struct {
+	keys sharedcontracts.CredentialVerifier
+}
+
+// NewAPIKeyAuthenticator reads API keys from X-API-Key or an
+// "Authorization: ApiKey" header
+func NewAPIKeyAuthenticator(keys sharedcontracts.CredentialVerifier) contracts.Authenticator {
+	return &apiKeyAuthenticator{keys: keys}
+}
+
+func (a *apiKeyAuthenticator) Authenticate(r *http.Request) (*sharedcontracts.Principal, error) {
+	key := r.Header.Get(HeaderAPIKey)
+	if key == "" {
+		scheme, value, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !strings.EqualFold(scheme, "ApiKey") {
+			return nil, sharedcontracts.ErrNoCredentials
+		}
+		key = strings.TrimSpace(value)
+	}
+	return a.keys.Verify(key)
+}
+
+// bearerAuthenticator implements contracts.Authenticator for bearer tokens
+type bearerAuthenticator struct {
+	tokens sharedcontracts.CredentialVerifier
+}
+
+// NewBearerAuthenticator reads tokens from an "Authorization: Bearer"
+// header. Browsers can't set headers on EventSource and WebSocket
+// requests, so GET requests may pass the token as access_token instead.
+func NewBearerAuthenticator(tokens sharedcontracts.CredentialVerifier) contracts.Authenticator {
+	return &bearerAuthenticator{tokens: tokens}
+}
+
+func (a *bearerAuthenticator) Authenticate(r *http.Request) (*sharedcontracts.Principal, error) {
+	token := ""
+	if scheme, value, _ := strings.Cut(r.Header.Get("Authorization"), " "); strings.EqualFold(scheme, "Bearer") {
+		token = strings.TrimSpace(value)
+	} else if r.Method == http.MethodGet {
+		token = r.URL.Query().Get("access_token")
+	}
+	if token == "" {
+		return nil, sharedcontracts.ErrNoCredentials
+	}
+	return a.tokens.Verify(token)
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"OrderSystemHighConcurrency/shared/auth"
+	sharedcontracts "OrderSystemHighConcurrency/shared/contracts"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// Middleware authenticates requests and checks their scopes
+type Middleware struct {
+	authenticators []contracts.Authenticator
+}
+
+// NewMiddleware creates auth middleware. Credentials are checked by the
+// first authenticator that recognises them. Without authenticators every
+// request passes unauthenticated.
+func NewMiddleware(authenticators ...contracts.Authenticator) *Middleware {
+	return &Middleware{authenticators: authenticators}
+}
+
+// Require wraps next so it only runs for callers granted scope; the caller
+// is available to next through auth.PrincipalFrom
+func (m *Middleware) Require(scope string, next http.Handler) http.Handler {
+	if len(m.authenticators) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := m.authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer, ApiKey`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		if !principal.HasScope(scope) {
+			http.Error(w, "missing scope "+scope, http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func (m *Middleware) authenticate(r *http.Request) (*sharedcontracts.Principal, error) {
+	for _, a := range m.authenticators {
+		principal, err := a.Authenticate(r)
+		if errors.Is(err, sharedcontracts.ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			log.Printf("authentication failed from %s: %v", r.RemoteAddr, err)
+			return nil, sharedcontracts.ErrInvalidCredentials
+		}
+		return principal, nil
+	}
+	return nil, sharedcontracts.ErrNoCredentials
+}
@@ -0,0 +1,105 @@
+package auth_test
+
+import (
+	apiauth "OrderSystemHighConcurrency/order-api/internal/auth"
+	"OrderSystemHighConcurrency/shared/auth"
+	sharedcontracts "OrderSystemHighConcurrency/shared/contracts"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// stubVerifier accepts the credentials it holds principals for
+type stubVerifier map[string]*sharedcontracts.Principal
+
+func (v stubVerifier) Verify(credential string) (*sharedcontracts.Principal, error) {
+	if p, ok := v[credential]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("%w: unknown", sharedcontracts.ErrInvalidCredentials)
+}
+
+// whoami answers with the subject of the authenticated caller
+var whoami = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if p := auth.PrincipalFrom(r.Context()); p != nil {
+		io.WriteString(w, p.Subject)
+	}
+})
+
+func TestMiddlewareRequire(t *testing.T) {
+	keys := stubVerifier{
+		"reader-key": {Subject: "merchant-42", Method: "api_key", Scopes: []string{auth.ScopeOrdersRead}},
+		"writer-key": {Subject: "merchant-42", Method: "api_key", Scopes: []string{auth.ScopeOrdersWrite}},
+	}
+	tokens := stubVerifier{
+		"reader-token": {Subject: "merchant-7", Method: "jwt", Scopes: []string{auth.ScopeOrdersRead}},
+	}
+	handler := apiauth.NewMiddleware(
+		apiauth.NewAPIKeyAuthenticator(keys),
+		apiauth.NewBearerAuthenticator(tokens),
+	).Require(auth.ScopeOrdersRead, whoami)
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		header   map[string]string
+		wantCode int
+		wantBody string
+	}{
+		{"X-API-Key", http.MethodGet, "/orders", map[string]string{apiauth.HeaderAPIKey: "reader-key"}, http.StatusOK, "merchant-42"},
+		{"ApiKey scheme", http.MethodGet, "/orders", map[string]string{"Authorization": "ApiKey reader-key"}, http.StatusOK, "merchant-42"},
+		{"bearer token", http.MethodGet, "/orders", map[string]string{"Authorization": "Bearer reader-token"}, http.StatusOK, "merchant-7"},
+		{"access_token on GET", http.MethodGet, "/orders?access_token=reader-token", nil, http.StatusOK, "merchant-7"},
+
+		{"no credentials", http.MethodGet, "/orders", nil, http.StatusUnauthorized, ""},
+		{"unknown API key", http.MethodGet, "/orders", map[string]string{apiauth.HeaderAPIKey: "stolen-key"}, http.StatusUnauthorized, ""},
+		{"unknown token", http.MethodGet, "/orders", map[string]string{"Authorization": "Bearer forged-token"}, http.StatusUnauthorized, ""},
+		{"access_token on POST", http.MethodPost, "/orders?access_token=reader-token", nil, http.StatusUnauthorized, ""},
+		{"unsupported scheme", http.MethodGet, "/orders", map[string]string{"Authorization": "Basic cmVhZGVyOmtleQ=="}, http.StatusUnauthorized, ""},
+
+		{"missing scope", http.MethodGet, "/orders", map[string]string{apiauth.HeaderAPIKey: "writer-key"}, http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantCode, rec.Body)
+			}
+			switch tt.wantCode {
+			case http.StatusOK:
+				if got := rec.Body.String(); got != tt.wantBody {
+					t.Errorf("caller = %q, want %q", got, tt.wantBody)
+				}
+			case http.StatusUnauthorized:
+				if rec.Header().Get("WWW-Authenticate") == "" {
+					t.Error("401 without WWW-Authenticate")
+				}
+			case http.StatusForbidden:
+				if !strings.Contains(rec.Body.String(), auth.ScopeOrdersRead) {
+					t.Errorf("body = %q, want the missing scope", rec.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareWithoutAuthenticators(t *testing.T) {
+	handler := apiauth.NewMiddleware().Require(auth.ScopeOrdersWrite, whoami)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders", nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Errorf("status = %d, body = %q; want an anonymous 200", rec.Code, rec.Body)
+	}
+}
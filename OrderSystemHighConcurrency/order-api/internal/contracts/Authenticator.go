@@ -0,0 +1,14 @@
+package contracts
+
+import (
+	sharedcontracts "OrderSystemHighConcurrency/shared/contracts"
+	"net/http"
+)
+
+// Authenticator verifies the credentials of an HTTP request.
+type Authenticator interface {
+	// Authenticate returns the caller, sharedcontracts.ErrNoCredentials when
+	// the request has none this authenticator understands, or an error
+	// wrapping sharedcontracts.ErrInvalidCredentials.
+	Authenticate(r *http.Request) (*sharedcontracts.Principal, error)
+}
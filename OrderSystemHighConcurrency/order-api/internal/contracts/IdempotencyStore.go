@@ -0,0 +1,37 @@
+package contracts
+
+import (
+	"context"
+	"time"
+)
+
+// IdempotencyStore remembers the first response returned for an idempotency key
+// so retried requests can be answered without publishing the order again.
+// Implementations shared between replicas must make Reserve atomic.
+type IdempotencyStore interface {
+	// Reserve claims the key for a request with the given body hash.
+	// If the key is already known, the existing record is returned and
+	// reserved is false.
+	Reserve(ctx context.Context, key string, requestHash string, ttl time.Duration) (record *IdempotencyRecord, reserved bool, err error)
+
+	// Complete stores the response for a key previously reserved.
+	Complete(ctx context.Context, key string, resp *IdempotentResponse) error
+
+	// Release drops a reservation so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyRecord is the stored state of an idempotency key.
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	Response    *IdempotentResponse // nil while the first request is in flight
+	ExpiresAt   time.Time
+}
+
+// IdempotentResponse is the response replayed for duplicate requests.
+type IdempotentResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
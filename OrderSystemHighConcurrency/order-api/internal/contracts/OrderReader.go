@@ -0,0 +1,42 @@
+package contracts
+
+import (
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+)
+
+// OrderReader defines the read side used to look up orders.
+// Implementations may be backed by the processor's SQL store or a projection.
+type OrderReader interface {
+	// GetOrder returns the current state of an order.
+	// It returns models.ErrOrderNotFound when the order is unknown.
+	GetOrder(ctx context.Context, orderID string) (*models.Order, error)
+
+	// ListOrdersByUser returns a page of the user's orders, newest first.
+	// An empty cursor starts from the most recent order.
+	ListOrdersByUser(ctx context.Context, userID string, cursor string, limit int) (*OrderPage, error)
+
+	// GetHistory returns the status transitions of an order, oldest first.
+	// It returns models.ErrOrderNotFound when no history is known.
+	GetHistory(ctx context.Context, orderID string) ([]models.StatusTransition, error)
+}
+
+// OrderProjection is a read model that order-api keeps up to date itself.
+type OrderProjection interface {
+	OrderReader
+
+	// Apply records the latest known state of an order.
+	Apply(ctx context.Context, order *models.Order) error
+}
+
+// OrderTimeline is the status history of an order.
+type OrderTimeline struct {
+	OrderID     string                    `json:"order_id"`
+	Transitions []models.StatusTransition `json:"transitions"`
+}
+
+// OrderPage is one page of orders plus the cursor for the next page.
+type OrderPage struct {
+	Orders     []*models.Order `json:"orders"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
@@ -3,13 +3,33 @@ package contracts
 import (
 	"OrderSystemHighConcurrency/shared/models"
 	"context"
+	"errors"
 )
 
+// ErrInvalidOrder is wrapped by CreateOrder errors caused by the order
+// itself, which the client has to fix
+var ErrInvalidOrder = errors.New("invalid order")
+
 type OrderService interface {
 	// CreateOrder validates and creates a new order.
-	// It sends the order to a message queue for processing.
+	// It sends the order to a message queue for processing. Validation
+	// failures wrap ErrInvalidOrder.
 	CreateOrder(ctx context.Context, order *models.Order) error
 
-	// Optionally, you can add more future operations:
-	// GetOrderStatus(ctx context.Context, orderID string) (models.OrderStatus, error)
+	// GetOrder returns the current state of an order, including its status.
+	// Orders that were accepted but not yet persisted are reported as QUEUED;
+	// unknown orders return models.ErrOrderNotFound.
+	GetOrder(ctx context.Context, orderID string) (*models.Order, error)
+
+	// CancelOrder requests cancellation of an order. The request is
+	// asynchronous: order-processor cancels the order unless it completes
+	// first. Completed orders return models.ErrCancelTooLate.
+	CancelOrder(ctx context.Context, orderID string) (*models.Order, error)
+
+	// ListOrders returns a page of the user's orders, newest first.
+	ListOrders(ctx context.Context, userID string, cursor string, limit int) (*OrderPage, error)
+
+	// GetTimeline returns every status change of an order with its reason,
+	// including retries and routing to the DLQ.
+	GetTimeline(ctx context.Context, orderID string) (*OrderTimeline, error)
 }
@@ -0,0 +1,49 @@
+package contracts
+
+import (
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+	"time"
+)
+
+// Outbox durably stores orders that could not be published to the message
+// queue yet. Entries are relayed strictly in the order they were appended.
+type Outbox interface {
+	// Append persists an event about the order at the tail of the outbox.
+	Append(ctx context.Context, eventType models.EventType, order *models.Order) error
+
+	// Pending returns up to limit unrelayed entries, oldest first.
+	Pending(ctx context.Context, limit int) ([]*OutboxEntry, error)
+
+	// Ack marks every entry up to and including seq as relayed.
+	Ack(ctx context.Context, seq uint64) error
+
+	// Stats reports the current backlog.
+	Stats() OutboxStats
+
+	// Close releases the underlying storage.
+	Close() error
+}
+
+// OutboxEntry is a single order event waiting in the outbox.
+type OutboxEntry struct {
+	Seq        uint64           `json:"seq"`
+	Type       models.EventType `json:"type,omitempty"`
+	Order      *models.Order    `json:"order"`
+	EnqueuedAt time.Time        `json:"enqueued_at"`
+}
+
+// EventType returns the entry's event type. Entries written before the
+// outbox stored types are ORDER_CREATED.
+func (e *OutboxEntry) EventType() models.EventType {
+	if e.Type == "" {
+		return models.EventOrderCreated
+	}
+	return e.Type
+}
+
+// OutboxStats describes the outbox backlog.
+type OutboxStats struct {
+	Depth    int
+	OldestAt time.Time // zero when the outbox is empty
+}
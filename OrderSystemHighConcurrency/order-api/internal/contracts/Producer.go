@@ -9,5 +9,9 @@ import (
 type Producer interface {
 	// Publish sends the order to the message queue (Kafka, RabbitMQ, etc.)
 	Publish(ctx context.Context, order *models.Order) error
+
+	// PublishEvent sends an event of the given type about the order
+	PublishEvent(ctx context.Context, eventType models.EventType, order *models.Order) error
+
 	Close() error
 }
@@ -0,0 +1,135 @@
+package contracts
+
+import (
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrWebhookNotFound is returned for unknown webhook IDs
+	ErrWebhookNotFound = errors.New("webhook not found")
+
+	// ErrInvalidWebhook is returned when a registration is rejected
+	ErrInvalidWebhook = errors.New("invalid webhook")
+
+	// ErrCircuitOpen is returned by a WebhookSender while an endpoint's
+	// circuit breaker is open; the delivery was not attempted
+	ErrCircuitOpen = errors.New("webhook endpoint circuit open")
+)
+
+// WebhookEvent returns the event name a status change is delivered as,
+// e.g. "order.completed"
+func WebhookEvent(status models.OrderStatus) string {
+	return "order." + strings.ToLower(string(status))
+}
+
+// Webhook is an endpoint a merchant registered for the status changes of
+// their orders.
+type Webhook struct {
+	ID     string   `json:"id"`
+	UserID string   `json:"user_id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"` // e.g. "order.completed"; empty means every event
+
+	// Secret signs every delivery. It is only returned on registration.
+	Secret string `json:"secret,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Wants reports whether the webhook subscribed to the order's current status
+func (w *Webhook) Wants(order *models.Order) bool {
+	if order.UserID != w.UserID {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+
+	event := WebhookEvent(order.Status)
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliveryState is where a webhook delivery stands
+type DeliveryState string
+
+const (
+	DeliveryPending   DeliveryState = "PENDING"   // waiting for its next attempt
+	DeliveryDelivered DeliveryState = "DELIVERED" // the endpoint answered 2xx
+	DeliveryFailed    DeliveryState = "FAILED"    // attempts exhausted
+)
+
+// WebhookDelivery is one event sent to one webhook, with its attempts so far.
+type WebhookDelivery struct {
+	ID            string        `json:"id"`
+	WebhookID     string        `json:"webhook_id"`
+	Event         string        `json:"event"`
+	OrderID       string        `json:"order_id"`
+	State         DeliveryState `json:"state"`
+	Attempts      int           `json:"attempts"`
+	ResponseCode  int           `json:"response_code,omitempty"` // of the last attempt
+	LastError     string        `json:"last_error,omitempty"`
+	Payload       []byte        `json:"-"` // the signed body, identical on every attempt
+	CreatedAt     time.Time     `json:"created_at"`
+	NextAttemptAt time.Time     `json:"next_attempt_at"`
+	DeliveredAt   *time.Time    `json:"delivered_at,omitempty"`
+}
+
+// WebhookStore persists webhooks and their delivery log. Implementations
+// shared between replicas must make ClaimDue atomic.
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, hook *Webhook) error
+
+	// GetWebhook returns ErrWebhookNotFound for unknown IDs
+	GetWebhook(ctx context.Context, id string) (*Webhook, error)
+
+	ListWebhooks(ctx context.Context, userID string) ([]*Webhook, error)
+
+	// DeleteWebhook removes the webhook together with its delivery log
+	DeleteWebhook(ctx context.Context, id string) error
+
+	// AddDelivery stores a new delivery; a delivery with the same ID
+	// already stored is left as is
+	AddDelivery(ctx context.Context, delivery *WebhookDelivery) error
+
+	// UpdateDelivery stores the outcome of an attempt
+	UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+
+	// ClaimDue returns up to limit pending deliveries due at now and pushes
+	// their next attempt lease into the future, so no other replica picks
+	// them up while they are in flight
+	ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*WebhookDelivery, error)
+
+	// ListDeliveries returns the webhook's most recent deliveries, newest first
+	ListDeliveries(ctx context.Context, webhookID string, limit int) ([]*WebhookDelivery, error)
+}
+
+// WebhookSender performs a single signed delivery attempt and returns the
+// endpoint's HTTP status code.
+type WebhookSender interface {
+	Send(ctx context.Context, hook *Webhook, delivery *WebhookDelivery) (int, error)
+
+	// Forget drops what the sender keeps about a deleted webhook
+	Forget(webhookID string)
+}
+
+// WebhookService manages webhook registrations and their delivery log.
+type WebhookService interface {
+	// Register validates and stores a webhook, generating its ID and secret
+	Register(ctx context.Context, hook *Webhook) (*Webhook, error)
+
+	Get(ctx context.Context, id string) (*Webhook, error)
+	List(ctx context.Context, userID string) ([]*Webhook, error)
+	Delete(ctx context.Context, id string) error
+
+	// Deliveries returns the webhook's most recent deliveries, newest first
+	Deliveries(ctx context.Context, id string, limit int) ([]*WebhookDelivery, error)
+}
@@ -2,9 +2,24 @@ package handlers
 
 import (
 	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"OrderSystemHighConcurrency/order-api/internal/pagination"
+	"OrderSystemHighConcurrency/shared/auth"
+	sharedcontracts "OrderSystemHighConcurrency/shared/contracts"
+	sharedkafka "OrderSystemHighConcurrency/shared/kafka"
 	"OrderSystemHighConcurrency/shared/models"
+	"OrderSystemHighConcurrency/shared/pb"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
 )
 
 // OrderHandler handles HTTP requests for orders
@@ -27,22 +42,33 @@ func (h *OrderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var order models.Order
-
-	// Decode JSON request body
-	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+	// Decode JSON or protobuf request body
+	order, err := decodeOrder(r)
+	if err != nil {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	// Validate required fields (optional, basic example)
-	if order.OrderID == "" || order.Amount <= 0 {
+	if order.OrderID == "" || order.Amount.Sign() <= 0 {
 		http.Error(w, "invalid order data", http.StatusBadRequest)
 		return
 	}
 
+	// Forward the gateway's request ID as the Kafka trace ID
+	ctx := sharedkafka.WithTraceID(r.Context(), r.Header.Get("X-Request-ID"))
+
 	// Call the service to create the order
-	if err := h.orderService.CreateOrder(r.Context(), &order); err != nil {
+	err = h.orderService.CreateOrder(ctx, order)
+	if errors.Is(err, sharedcontracts.ErrForbidden) {
+		http.Error(w, "cannot create orders for another user", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, contracts.ErrInvalidOrder) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
 		http.Error(w, "failed to create order", http.StatusInternalServerError)
 		return
 	}
@@ -55,3 +81,127 @@ func (h *OrderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	_ = json.NewEncoder(w).Encode(resp)
 }
+
+// decodeOrder reads the request body as the canonical protobuf Order when
+// the client sends application/x-protobuf, and as JSON otherwise
+func decodeOrder(r *http.Request) (*models.Order, error) {
+	if r.Header.Get("Content-Type") != sharedkafka.ContentTypeProtobuf {
+		var order models.Order
+		if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+			return nil, err
+		}
+		return &order, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var p pb.Order
+	if err := proto.Unmarshal(body, &p); err != nil {
+		return nil, err
+	}
+	return p.ToModel()
+}
+
+// GetOrder handles GET /orders/{id}
+func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	order, err := h.orderService.GetOrder(r.Context(), r.PathValue("id"))
+	if errors.Is(err, models.ErrOrderNotFound) {
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to fetch order", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, order)
+}
+
+// CancelOrder handles DELETE /orders/{id}
+func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := sharedkafka.WithTraceID(r.Context(), r.Header.Get("X-Request-ID"))
+
+	order, err := h.orderService.CancelOrder(ctx, r.PathValue("id"))
+	switch {
+	case errors.Is(err, models.ErrOrderNotFound):
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	case errors.Is(err, models.ErrCancelTooLate):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "failed to cancel order", http.StatusInternalServerError)
+		return
+	}
+
+	status := "cancellation requested"
+	if order.Status == models.OrderStatusCancelled {
+		status = "order already cancelled"
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"status":  status,
+		"orderId": order.OrderID,
+	})
+}
+
+// GetTimeline handles GET /orders/{id}/history
+func (h *OrderHandler) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	timeline, err := h.orderService.GetTimeline(r.Context(), r.PathValue("id"))
+	if errors.Is(err, models.ErrOrderNotFound) {
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to fetch order history", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, timeline)
+}
+
+// ListOrders handles GET /orders?user_id=...&cursor=...&limit=...
+func (h *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// Authenticated callers list their own orders
+	userID := query.Get("user_id")
+	if userID == "" && auth.PrincipalFrom(r.Context()) == nil {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultPageSize
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxPageSize)
+	}
+
+	page, err := h.orderService.ListOrders(r.Context(), userID, query.Get("cursor"), limit)
+	if errors.Is(err, sharedcontracts.ErrForbidden) {
+		http.Error(w, "cannot list orders of another user", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		http.Error(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to list orders", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"OrderSystemHighConcurrency/shared/auth"
+	sharedcontracts "OrderSystemHighConcurrency/shared/contracts"
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// sseRetry tells EventSource clients how long to wait before reconnecting
+const sseRetry = 3 * time.Second
+
+// wsWriteTimeout bounds a single WebSocket write to a stalled client
+const wsWriteTimeout = 10 * time.Second
+
+var errStreamClosed = errors.New("stream closed")
+
+// StreamHandler pushes order status changes over Server-Sent Events and
+// WebSocket. Clients resume after a disconnect with the ID of the last
+// event they received.
+type StreamHandler struct {
+	orderService contracts.OrderService
+	feed         sharedcontracts.StatusFeed
+	heartbeat    time.Duration
+	maxOrders    int // orders one WebSocket may watch
+}
+
+// NewStreamHandler creates a new StreamHandler
+func NewStreamHandler(
+	service contracts.OrderService,
+	feed sharedcontracts.StatusFeed,
+	heartbeat time.Duration,
+	maxOrders int,
+) *StreamHandler {
+	return &StreamHandler{
+		orderService: service,
+		feed:         feed,
+		heartbeat:    heartbeat,
+		maxOrders:    maxOrders,
+	}
+}
+
+// Events handles GET /orders/{id}/events
+func (h *StreamHandler) Events(w http.ResponseWriter, r *http.Request) {
+	orderID := r.PathValue("id")
+
+	// EventSource sends Last-Event-ID on reconnect; ?cursor serves clients
+	// that reconnect by hand
+	cursor := r.Header.Get("Last-Event-ID")
+	if cursor == "" {
+		cursor = r.URL.Query().Get("cursor")
+	}
+
+	// A fresh subscriber gets the current state, then the changes from
+	// just before it was read, so none falls between the two
+	snapshot := cursor == ""
+	if snapshot {
+		var err error
+		if cursor, err = h.feed.Cursor(); err != nil {
+			http.Error(w, "status feed unavailable", http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	order, err := h.orderService.GetOrder(r.Context(), orderID)
+	if errors.Is(err, models.ErrOrderNotFound) {
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to fetch order", http.StatusInternalServerError)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	stream := &sseStream{w: w, flusher: flusher}
+	defer stream.close()
+
+	// A resuming subscriber gets exactly the changes it missed
+	if snapshot {
+		if err := stream.send("", "snapshot", order); err != nil {
+			return
+		}
+	}
+
+	go h.beat(ctx, func() error { return stream.comment("heartbeat") }, cancel)
+
+	filter := sharedcontracts.StatusFilter{OrderIDs: []string{orderID}}
+	err = h.feed.Watch(ctx, filter, cursor, func(u sharedcontracts.StatusUpdate) error {
+		return stream.send(u.Cursor, "status", u.Order)
+	})
+
+	switch {
+	case err == nil, ctx.Err() != nil:
+	case stream.started():
+		// The client reconnects on its own with its last event ID
+		stream.send("", "error", map[string]string{"error": err.Error()})
+		stream.close()
+	case stream.close():
+		// A heartbeat opened the stream while the watch was failing
+	case errors.Is(err, sharedcontracts.ErrInvalidCursor):
+		http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+	default:
+		http.Error(w, "status feed unavailable", http.StatusServiceUnavailable)
+	}
+}
+
+// WebSocket handles GET /ws/orders?order_id=...&user_id=...&last_event_id=...
+func (h *StreamHandler) WebSocket() http.Handler {
+	server := websocket.Server{Handler: h.serveSocket}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		orderIDs := query["order_id"]
+
+		// Authenticated callers watch their own orders only
+		if query.Get("user_id") != "" || len(orderIDs) == 0 {
+			userID, err := auth.BindUser(r.Context(), query.Get("user_id"))
+			if err != nil {
+				http.Error(w, "cannot watch orders of another user", http.StatusForbidden)
+				return
+			}
+			query.Set("user_id", userID)
+			r.URL.RawQuery = query.Encode()
+		}
+
+		if len(orderIDs) == 0 && query.Get("user_id") == "" {
+			http.Error(w, "order_id or user_id is required", http.StatusBadRequest)
+			return
+		}
+		if h.maxOrders > 0 && len(orderIDs) > h.maxOrders {
+			http.Error(w, fmt.Sprintf("at most %d order_id values per connection", h.maxOrders), http.StatusBadRequest)
+			return
+		}
+
+		if auth.PrincipalFrom(r.Context()) != nil {
+			for _, orderID := range orderIDs {
+				_, err := h.orderService.GetOrder(r.Context(), orderID)
+				if errors.Is(err, models.ErrOrderNotFound) {
+					http.Error(w, "order not found: "+orderID, http.StatusNotFound)
+					return
+				}
+				if err != nil {
+					http.Error(w, "failed to fetch order", http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+
+		server.ServeHTTP(w, r)
+	})
+}
+
+// wsMessage is a frame sent to WebSocket clients
+type wsMessage struct {
+	Type  string        `json:"type"` // snapshot, status, heartbeat or error
+	ID    string        `json:"id,omitempty"`
+	Order *models.Order `json:"order,omitempty"`
+	Error string        `json:"error,omitempty"`
+}
+
+// serveSocket streams status changes until the client goes away
+func (h *StreamHandler) serveSocket(ws *websocket.Conn) {
+	defer ws.Close()
+
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	defer cancel()
+
+	query := ws.Request().URL.Query()
+	filter := sharedcontracts.StatusFilter{
+		OrderIDs: query["order_id"],
+		UserID:   query.Get("user_id"),
+	}
+	cursor := query.Get("last_event_id")
+
+	var mu sync.Mutex
+	send := func(msg wsMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		ws.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		return websocket.JSON.Send(ws, msg)
+	}
+
+	// Watch from before the snapshots so no change falls between them
+	snapshot := cursor == ""
+	if snapshot {
+		var err error
+		if cursor, err = h.feed.Cursor(); err != nil {
+			send(wsMessage{Type: "error", Error: err.Error()})
+			return
+		}
+	}
+
+	// Clients send nothing; reading only tells us when they disconnect
+	go func() {
+		defer cancel()
+
+		var discard string
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+
+	if snapshot {
+		for _, orderID := range filter.OrderIDs {
+			order, err := h.orderService.GetOrder(ctx, orderID)
+			if err != nil {
+				continue
+			}
+			if err := send(wsMessage{Type: "snapshot", Order: order}); err != nil {
+				return
+			}
+		}
+	}
+
+	go h.beat(ctx, func() error { return send(wsMessage{Type: "heartbeat"}) }, cancel)
+
+	err := h.feed.Watch(ctx, filter, cursor, func(u sharedcontracts.StatusUpdate) error {
+		return send(wsMessage{Type: "status", ID: u.Cursor, Order: u.Order})
+	})
+	if err != nil && ctx.Err() == nil {
+		send(wsMessage{Type: "error", Error: err.Error()})
+	}
+}
+
+// beat calls fn every heartbeat interval until ctx is done, and cancels the
+// stream once a heartbeat can no longer be written
+func (h *StreamHandler) beat(ctx context.Context, fn func() error, cancel context.CancelFunc) {
+	if h.heartbeat <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := fn(); err != nil {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// sseStream writes Server-Sent Events. The response headers go out with the
+// first event, so errors before it can still be reported as HTTP statuses.
+type sseStream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+	open    bool
+	closed  bool // the handler has returned; nothing may be written
+}
+
+// send writes one event; id is omitted when empty so the client keeps its
+// last resume point
+func (s *sseStream) send(id, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errStreamClosed
+	}
+	s.start()
+	if id != "" {
+		fmt.Fprintf(s.w, "id: %s\n", id)
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// comment writes an SSE comment, which keeps proxies from closing an idle
+// stream
+func (s *sseStream) comment(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errStreamClosed
+	}
+	s.start()
+	if _, err := fmt.Fprintf(s.w, ": %s\n\n", text); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func (s *sseStream) started() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.open
+}
+
+// close stops further writes and reports whether the stream was opened
+func (s *sseStream) close() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	return s.open
+}
+
+// start writes the response headers once; callers hold mu
+func (s *sseStream) start() {
+	if s.open {
+		return
+	}
+	s.open = true
+
+	header := s.w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // nginx must not buffer the stream
+	s.w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(s.w, "retry: %d\n\n", sseRetry.Milliseconds())
+	s.flusher.Flush()
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"OrderSystemHighConcurrency/shared/auth"
+	sharedcontracts "OrderSystemHighConcurrency/shared/contracts"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// WebhookHandler handles HTTP requests for webhook registrations
+type WebhookHandler struct {
+	webhookService contracts.WebhookService
+}
+
+// NewWebhookHandler creates a new WebhookHandler
+func NewWebhookHandler(service contracts.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: service,
+	}
+}
+
+// registerRequest is the body of POST /webhooks
+type registerRequest struct {
+	UserID string   `json:"user_id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// Register handles POST /webhooks. The response is the only one that
+// contains the signing secret.
+func (h *WebhookHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var req registerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	hook, err := h.webhookService.Register(r.Context(), &contracts.Webhook{
+		UserID: req.UserID,
+		URL:    req.URL,
+		Events: req.Events,
+	})
+	if errors.Is(err, contracts.ErrInvalidWebhook) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, sharedcontracts.ErrForbidden) {
+		http.Error(w, "cannot register webhooks for another user", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to register webhook", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, hook)
+}
+
+// List handles GET /webhooks?user_id=...
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	// Authenticated callers list their own webhooks
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" && auth.PrincipalFrom(r.Context()) == nil {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	hooks, err := h.webhookService.List(r.Context(), userID)
+	if errors.Is(err, sharedcontracts.ErrForbidden) {
+		http.Error(w, "cannot list webhooks of another user", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to list webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"webhooks": hooks})
+}
+
+// Get handles GET /webhooks/{id}
+func (h *WebhookHandler) Get(w http.ResponseWriter, r *http.Request) {
+	hook, err := h.webhookService.Get(r.Context(), r.PathValue("id"))
+	if errors.Is(err, contracts.ErrWebhookNotFound) {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to fetch webhook", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, hook)
+}
+
+// Delete handles DELETE /webhooks/{id}
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	err := h.webhookService.Delete(r.Context(), r.PathValue("id"))
+	if errors.Is(err, contracts.ErrWebhookNotFound) {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to delete webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deliveries handles GET /webhooks/{id}/deliveries?limit=...
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	limit := defaultPageSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxPageSize)
+	}
+
+	deliveries, err := h.webhookService.Deliveries(r.Context(), r.PathValue("id"), limit)
+	if errors.Is(err, contracts.ErrWebhookNotFound) {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to fetch deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"deliveries": deliveries})
+}
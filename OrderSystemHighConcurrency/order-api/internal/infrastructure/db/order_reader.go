@@ -0,0 +1,171 @@
+package db
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"OrderSystemHighConcurrency/order-api/internal/pagination"
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+const orderColumns = `
+	order_id, user_id, amount, currency, status,
+	source, retry_count, created_at, updated_at,
+	metadata, items
+`
+
+// orderReader implements contracts.OrderReader on top of the
+// orders table written by order-processor
+type orderReader struct {
+	db *sql.DB
+}
+
+// NewOrderReader creates a SQL Server backed order reader
+func NewOrderReader(db *sql.DB) contracts.OrderReader {
+	return &orderReader{db: db}
+}
+
+// GetOrder loads a single order by ID
+func (r *orderReader) GetOrder(ctx context.Context, orderID string) (*models.Order, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+orderColumns+` FROM orders WHERE order_id = @p1`,
+		orderID,
+	)
+
+	order, err := scanOrder(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrOrderNotFound
+	}
+	return order, err
+}
+
+// ListOrdersByUser pages through a user's orders using keyset pagination
+func (r *orderReader) ListOrdersByUser(
+	ctx context.Context,
+	userID string,
+	cursor string,
+	limit int,
+) (*contracts.OrderPage, error) {
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra row to know whether another page exists
+	query := `SELECT TOP (@p1) ` + orderColumns + ` FROM orders WHERE user_id = @p2`
+	args := []interface{}{limit + 1, userID}
+
+	if after != nil {
+		query += ` AND (created_at < @p3 OR (created_at = @p3 AND order_id < @p4))`
+		args = append(args, after.CreatedAt, after.OrderID)
+	}
+	query += ` ORDER BY created_at DESC, order_id DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &contracts.OrderPage{Orders: make([]*models.Order, 0, limit)}
+
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(page.Orders) == limit {
+			last := page.Orders[len(page.Orders)-1]
+			page.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, OrderID: last.OrderID}.Encode()
+			break
+		}
+		page.Orders = append(page.Orders, order)
+	}
+
+	return page, rows.Err()
+}
+
+// GetHistory reads the order_status_history rows written by order-processor
+func (r *orderReader) GetHistory(ctx context.Context, orderID string) ([]models.StatusTransition, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT from_status, to_status, reason, occurred_at
+		FROM order_status_history
+		WHERE order_id = @p1
+		ORDER BY occurred_at, to_status`,
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transitions []models.StatusTransition
+	for rows.Next() {
+		var (
+			t      models.StatusTransition
+			reason sql.NullString
+		)
+		if err := rows.Scan(&t.From, &t.To, &reason, &t.At); err != nil {
+			return nil, err
+		}
+		t.Reason = reason.String
+		t.At = t.At.UTC()
+		transitions = append(transitions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(transitions) == 0 {
+		return nil, models.ErrOrderNotFound
+	}
+	return transitions, nil
+}
+
+// scanner is satisfied by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanOrder(s scanner) (*models.Order, error) {
+	var (
+		o        models.Order
+		metadata sql.NullString
+		items    sql.NullString
+	)
+
+	err := s.Scan(
+		&o.OrderID,
+		&o.UserID,
+		&o.Amount,
+		&o.Currency,
+		&o.Status,
+		&o.Source,
+		&o.RetryCount,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+		&metadata,
+		&items,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata.Valid {
+		if err := json.Unmarshal([]byte(metadata.String), &o.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	if items.Valid {
+		if err := json.Unmarshal([]byte(items.String), &o.Items); err != nil {
+			return nil, err
+		}
+	}
+
+	o.CreatedAt = o.CreatedAt.UTC()
+	o.UpdatedAt = o.UpdatedAt.UTC()
+	return &o, nil
+}
@@ -0,0 +1,91 @@
+package idempotency
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// memoryStore implements contracts.IdempotencyStore for a single replica
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]*contracts.IdempotencyRecord
+}
+
+// NewMemoryStore creates an in-memory idempotency store
+func NewMemoryStore() contracts.IdempotencyStore {
+	s := &memoryStore{
+		records: make(map[string]*contracts.IdempotencyRecord),
+	}
+
+	// cleanup expired keys periodically
+	go s.cleanup()
+	return s
+}
+
+// Reserve claims the key unless a live record already exists
+func (s *memoryStore) Reserve(
+	ctx context.Context,
+	key string,
+	requestHash string,
+	ttl time.Duration,
+) (*contracts.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if rec, ok := s.records[key]; ok && now.Before(rec.ExpiresAt) {
+		cp := *rec
+		return &cp, false, nil
+	}
+
+	rec := &contracts.IdempotencyRecord{
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   now.Add(ttl),
+	}
+	s.records[key] = rec
+
+	cp := *rec
+	return &cp, true, nil
+}
+
+// Complete attaches the response to a reserved key
+func (s *memoryStore) Complete(ctx context.Context, key string, resp *contracts.IdempotentResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.records[key]
+	if !ok {
+		return errors.New("idempotency key not reserved")
+	}
+
+	rec.Response = resp
+	return nil
+}
+
+// Release forgets the key
+func (s *memoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+// cleanup removes expired keys every minute
+func (s *memoryStore) cleanup() {
+	for {
+		time.Sleep(time.Minute)
+		s.mu.Lock()
+		now := time.Now()
+		for key, rec := range s.records {
+			if now.After(rec.ExpiresAt) {
+				delete(s.records, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
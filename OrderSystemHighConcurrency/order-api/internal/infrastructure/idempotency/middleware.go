@@ -0,0 +1,167 @@
+package idempotency
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"OrderSystemHighConcurrency/shared/auth"
+	sharedkafka "OrderSystemHighConcurrency/shared/kafka"
+	"OrderSystemHighConcurrency/shared/pb"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// HeaderKey is the request header carrying the client's idempotency key
+	HeaderKey = "Idempotency-Key"
+
+	// HeaderReplayed marks responses served from the store
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxBodyBytes = 1 << 20
+)
+
+// Middleware replays the first response for repeated requests
+type Middleware struct {
+	store contracts.IdempotencyStore
+	ttl   time.Duration
+}
+
+// NewMiddleware creates idempotency middleware; responses are kept for ttl
+func NewMiddleware(store contracts.IdempotencyStore, ttl time.Duration) *Middleware {
+	return &Middleware{
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// Handler wraps http.Handler.
+// The key comes from the Idempotency-Key header and falls back to the
+// order_id in the request body. Duplicates with the same body get the
+// stored response; the same key with a different body is rejected with 409.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+		if err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := requestKey(r, body)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Keys are per caller, so nobody can replay another user's response
+		if p := auth.PrincipalFrom(r.Context()); p != nil {
+			key = p.Subject + ":" + key
+		}
+
+		sum := sha256.Sum256(body)
+		hash := hex.EncodeToString(sum[:])
+
+		rec, reserved, err := m.store.Reserve(r.Context(), key, hash, m.ttl)
+		if err != nil {
+			log.Printf("idempotency store error for key %s: %v", key, err)
+			http.Error(w, "idempotency store unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		if !reserved {
+			replay(w, rec, hash)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		// Server errors are not cached so the client can retry
+		if recorder.status >= http.StatusInternalServerError {
+			if err := m.store.Release(r.Context(), key); err != nil {
+				log.Printf("failed to release idempotency key %s: %v", key, err)
+			}
+			return
+		}
+
+		resp := &contracts.IdempotentResponse{
+			StatusCode:  recorder.status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		}
+		if err := m.store.Complete(r.Context(), key, resp); err != nil {
+			log.Printf("failed to store response for idempotency key %s: %v", key, err)
+		}
+	})
+}
+
+func replay(w http.ResponseWriter, rec *contracts.IdempotencyRecord, hash string) {
+	if rec.RequestHash != hash {
+		http.Error(w, "idempotency key reused with a different request body", http.StatusConflict)
+		return
+	}
+
+	if rec.Response == nil {
+		http.Error(w, "a request with this idempotency key is in progress", http.StatusConflict)
+		return
+	}
+
+	if rec.Response.ContentType != "" {
+		w.Header().Set("Content-Type", rec.Response.ContentType)
+	}
+	w.Header().Set(HeaderReplayed, "true")
+	w.WriteHeader(rec.Response.StatusCode)
+	_, _ = w.Write(rec.Response.Body)
+}
+
+// requestKey returns the Idempotency-Key header or the body's order_id
+func requestKey(r *http.Request, body []byte) string {
+	if key := r.Header.Get(HeaderKey); key != "" {
+		return key
+	}
+
+	if r.Header.Get("Content-Type") == sharedkafka.ContentTypeProtobuf {
+		var order pb.Order
+		if err := proto.Unmarshal(body, &order); err != nil {
+			return ""
+		}
+		return order.OrderId
+	}
+
+	var payload struct {
+		OrderID string `json:"order_id"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	return payload.OrderID
+}
+
+// responseRecorder captures the status and body while writing through
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
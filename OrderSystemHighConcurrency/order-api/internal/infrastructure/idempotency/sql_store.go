@@ -0,0 +1,162 @@
+package idempotency
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+)
+
+// sqlStore implements contracts.IdempotencyStore on SQL Server so that every
+// order-api replica behind the gateway sees the same keys.
+//
+// Expected schema:
+//
+//	CREATE TABLE idempotency_keys (
+//	    idempotency_key NVARCHAR(255)  NOT NULL PRIMARY KEY,
+//	    request_hash    CHAR(64)       NOT NULL,
+//	    status_code     INT            NULL,
+//	    content_type    NVARCHAR(255)  NULL,
+//	    body            VARBINARY(MAX) NULL,
+//	    expires_at      DATETIME2      NOT NULL
+//	);
+type sqlStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore creates a SQL Server backed idempotency store
+func NewSQLStore(db *sql.DB) contracts.IdempotencyStore {
+	s := &sqlStore{db: db}
+
+	// purge expired keys periodically
+	go s.cleanup()
+	return s
+}
+
+// Reserve inserts the key; the primary key makes the claim atomic across replicas
+func (s *sqlStore) Reserve(
+	ctx context.Context,
+	key string,
+	requestHash string,
+	ttl time.Duration,
+) (*contracts.IdempotencyRecord, bool, error) {
+	// Two attempts: the second one runs after an expired record was removed
+	for attempt := 0; attempt < 2; attempt++ {
+		now := time.Now().UTC()
+		rec := &contracts.IdempotencyRecord{
+			Key:         key,
+			RequestHash: requestHash,
+			ExpiresAt:   now.Add(ttl),
+		}
+
+		_, err := s.db.ExecContext(ctx,
+			`INSERT INTO idempotency_keys (idempotency_key, request_hash, expires_at) VALUES (@p1, @p2, @p3)`,
+			rec.Key, rec.RequestHash, rec.ExpiresAt,
+		)
+		if err == nil {
+			return rec, true, nil
+		}
+		if !isDuplicateKey(err) {
+			return nil, false, err
+		}
+
+		existing, err := s.get(ctx, key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue // released concurrently, try again
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		if now.Before(existing.ExpiresAt) {
+			return existing, false, nil
+		}
+
+		if _, err := s.db.ExecContext(ctx,
+			`DELETE FROM idempotency_keys WHERE idempotency_key = @p1 AND expires_at <= @p2`,
+			key, now,
+		); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return nil, false, errors.New("failed to reserve idempotency key")
+}
+
+// Complete stores the response for a reserved key
+func (s *sqlStore) Complete(ctx context.Context, key string, resp *contracts.IdempotentResponse) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE idempotency_keys SET status_code = @p2, content_type = @p3, body = @p4 WHERE idempotency_key = @p1`,
+		key, resp.StatusCode, resp.ContentType, resp.Body,
+	)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("idempotency key not reserved")
+	}
+	return nil
+}
+
+// Release deletes the key
+func (s *sqlStore) Release(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE idempotency_key = @p1`,
+		key,
+	)
+	return err
+}
+
+func (s *sqlStore) get(ctx context.Context, key string) (*contracts.IdempotencyRecord, error) {
+	var (
+		rec         contracts.IdempotencyRecord
+		statusCode  sql.NullInt64
+		contentType sql.NullString
+		body        []byte
+	)
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT idempotency_key, request_hash, status_code, content_type, body, expires_at
+		FROM idempotency_keys WHERE idempotency_key = @p1`,
+		key,
+	).Scan(&rec.Key, &rec.RequestHash, &statusCode, &contentType, &body, &rec.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode.Valid {
+		rec.Response = &contracts.IdempotentResponse{
+			StatusCode:  int(statusCode.Int64),
+			ContentType: contentType.String,
+			Body:        body,
+		}
+	}
+	return &rec, nil
+}
+
+// cleanup removes expired keys every minute
+func (s *sqlStore) cleanup() {
+	for {
+		time.Sleep(time.Minute)
+		if _, err := s.db.Exec(
+			`DELETE FROM idempotency_keys WHERE expires_at <= @p1`,
+			time.Now().UTC(),
+		); err != nil {
+			log.Printf("idempotency cleanup failed: %v", err)
+		}
+	}
+}
+
+// isDuplicateKey reports a primary key / unique index violation
+func isDuplicateKey(err error) bool {
+	var sqlErr mssql.Error
+	if errors.As(err, &sqlErr) {
+		return sqlErr.Number == 2627 || sqlErr.Number == 2601
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package kafka
+
+import (
+	sharedcontracts "OrderSystemHighConcurrency/shared/contracts"
+	sharedkafka "OrderSystemHighConcurrency/shared/kafka"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// lazyStatusFeed implements sharedcontracts.StatusFeed on top of a feed
+// that connects on the first watch, so order-api starts without Kafka
+type lazyStatusFeed struct {
+	brokers       []string
+	topic         string
+	retryInterval time.Duration
+
+	mu          sync.Mutex
+	feed        *sharedkafka.StatusFeed
+	lastDial    time.Time
+	lastDialErr error
+}
+
+// NewLazyStatusFeed creates a status feed that connects on demand and
+// redials at most once per retryInterval while Kafka is unreachable
+func NewLazyStatusFeed(brokers []string, topic string, retryInterval time.Duration) *lazyStatusFeed {
+	return &lazyStatusFeed{
+		brokers:       brokers,
+		topic:         topic,
+		retryInterval: retryInterval,
+	}
+}
+
+// Watch connects if needed and forwards the watch
+func (l *lazyStatusFeed) Watch(
+	ctx context.Context,
+	filter sharedcontracts.StatusFilter,
+	cursor string,
+	fn func(sharedcontracts.StatusUpdate) error,
+) error {
+	feed, err := l.get()
+	if err != nil {
+		return err
+	}
+	return feed.Watch(ctx, filter, cursor, fn)
+}
+
+// Cursor connects if needed and returns the feed's current position
+func (l *lazyStatusFeed) Cursor() (string, error) {
+	feed, err := l.get()
+	if err != nil {
+		return "", err
+	}
+	return feed.Cursor()
+}
+
+// Close disconnects the feed if it was ever connected
+func (l *lazyStatusFeed) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.feed != nil {
+		return l.feed.Close()
+	}
+	return nil
+}
+
+func (l *lazyStatusFeed) get() (*sharedkafka.StatusFeed, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.feed != nil {
+		return l.feed, nil
+	}
+
+	if !l.lastDial.IsZero() && time.Since(l.lastDial) < l.retryInterval {
+		return nil, fmt.Errorf("kafka unavailable: %w", l.lastDialErr)
+	}
+
+	l.lastDial = time.Now()
+	feed, err := sharedkafka.NewStatusFeed(l.brokers, l.topic)
+	if err == nil {
+		err = feed.Start(context.Background())
+		if err != nil {
+			feed.Close()
+		}
+	}
+	if err != nil {
+		l.lastDialErr = err
+		return nil, fmt.Errorf("kafka unavailable: %w", err)
+	}
+
+	l.feed = feed
+	return feed, nil
+}
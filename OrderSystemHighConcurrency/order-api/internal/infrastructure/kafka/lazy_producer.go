@@ -0,0 +1,82 @@
+package kafka
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// lazyProducer implements contracts.Producer on top of a producer that may
+// not be available yet. It dials on first use and redials at most once per
+// retryInterval while the broker is unreachable.
+type lazyProducer struct {
+	dial          func() (contracts.Producer, error)
+	retryInterval time.Duration
+
+	mu          sync.Mutex
+	producer    contracts.Producer
+	lastDial    time.Time
+	lastDialErr error
+}
+
+// NewLazyProducer creates a producer that connects on demand
+func NewLazyProducer(dial func() (contracts.Producer, error), retryInterval time.Duration) contracts.Producer {
+	return &lazyProducer{
+		dial:          dial,
+		retryInterval: retryInterval,
+	}
+}
+
+// Publish connects if needed and forwards the order
+func (l *lazyProducer) Publish(ctx context.Context, order *models.Order) error {
+	producer, err := l.get()
+	if err != nil {
+		return err
+	}
+	return producer.Publish(ctx, order)
+}
+
+// PublishEvent connects if needed and forwards the event
+func (l *lazyProducer) PublishEvent(ctx context.Context, eventType models.EventType, order *models.Order) error {
+	producer, err := l.get()
+	if err != nil {
+		return err
+	}
+	return producer.PublishEvent(ctx, eventType, order)
+}
+
+func (l *lazyProducer) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.producer != nil {
+		return l.producer.Close()
+	}
+	return nil
+}
+
+func (l *lazyProducer) get() (contracts.Producer, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.producer != nil {
+		return l.producer, nil
+	}
+
+	if !l.lastDial.IsZero() && time.Since(l.lastDial) < l.retryInterval {
+		return nil, fmt.Errorf("kafka unavailable: %w", l.lastDialErr)
+	}
+
+	l.lastDial = time.Now()
+	producer, err := l.dial()
+	if err != nil {
+		l.lastDialErr = err
+		return nil, fmt.Errorf("kafka unavailable: %w", err)
+	}
+
+	l.producer = producer
+	return producer, nil
+}
@@ -0,0 +1,116 @@
+package kafka
+
+import (
+	sharedkafka "OrderSystemHighConcurrency/shared/kafka"
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+	"log"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// StatusHandler accepts one order status change. An error makes the
+// consumer retry the same event.
+type StatusHandler func(ctx context.Context, order *models.Order) error
+
+// StatusConsumer hands every event of the status topic to a handler.
+// Replicas share the topic through a consumer group, and an event's offset
+// is only marked once the handler accepted it.
+type StatusConsumer struct {
+	brokers       []string
+	groupID       string
+	topic         string
+	retryInterval time.Duration
+	handle        StatusHandler
+}
+
+// NewStatusConsumer creates a consumer; nothing connects before Run
+func NewStatusConsumer(
+	brokers []string,
+	groupID string,
+	topic string,
+	retryInterval time.Duration,
+	handle StatusHandler,
+) *StatusConsumer {
+	return &StatusConsumer{
+		brokers:       brokers,
+		groupID:       groupID,
+		topic:         topic,
+		retryInterval: retryInterval,
+		handle:        handle,
+	}
+}
+
+// Run consumes until ctx is done, reconnecting while Kafka is unreachable
+func (c *StatusConsumer) Run(ctx context.Context) {
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_8_0_0
+	config.Consumer.Offsets.Initial = sarama.OffsetNewest
+
+	for ctx.Err() == nil {
+		group, err := sarama.NewConsumerGroup(c.brokers, c.groupID, config)
+		if err != nil {
+			log.Printf("status consumer: kafka unavailable: %v", err)
+			c.wait(ctx)
+			continue
+		}
+
+		for ctx.Err() == nil {
+			if err := group.Consume(ctx, []string{c.topic}, c); err != nil {
+				log.Printf("status consumer: consume error: %v", err)
+				break
+			}
+		}
+
+		group.Close()
+		c.wait(ctx)
+	}
+}
+
+func (c *StatusConsumer) wait(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(c.retryInterval):
+	}
+}
+
+func (c *StatusConsumer) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (c *StatusConsumer) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim handles a partition's events in order
+func (c *StatusConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	ctx := session.Context()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+
+			if order := sharedkafka.DecodeStatus(msg); order != nil {
+				for {
+					err := c.handle(ctx, order)
+					if err == nil {
+						break
+					}
+					log.Printf("status consumer: order %s not handled, retrying: %v", order.OrderID, err)
+
+					c.wait(ctx)
+					if ctx.Err() != nil {
+						return nil
+					}
+				}
+			}
+			session.MarkMessage(msg, "")
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package memory
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"OrderSystemHighConcurrency/order-api/internal/pagination"
+	"OrderSystemHighConcurrency/shared/models"
+	"container/list"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// projected is an order and when it stops being served
+type projected struct {
+	order   *models.Order
+	expires time.Time
+}
+
+// orderProjection implements contracts.OrderProjection in memory.
+// It knows about orders accepted by this order-api replica and the status
+// changes it has seen since. Orders expire ttl after their last change and
+// the least recently changed ones are evicted beyond maxOrders.
+type orderProjection struct {
+	ttl       time.Duration
+	maxOrders int
+
+	mu     sync.RWMutex
+	orders map[string]*list.Element   // of *projected
+	recent *list.List                 // most recently changed first
+	byUser map[string][]*models.Order // newest first
+}
+
+// NewOrderProjection creates an empty in-memory projection. A ttl or
+// maxOrders of 0 disables that limit.
+func NewOrderProjection(ttl time.Duration, maxOrders int) contracts.OrderProjection {
+	return &orderProjection{
+		ttl:       ttl,
+		maxOrders: maxOrders,
+		orders:    make(map[string]*list.Element),
+		recent:    list.New(),
+		byUser:    make(map[string][]*models.Order),
+	}
+}
+
+// Apply stores a copy of the order, replacing any previous state. A state
+// the stored one can't move to is stale, e.g. QUEUED after COMPLETED, and
+// is ignored.
+func (p *orderProjection) Apply(ctx context.Context, order *models.Order) error {
+	if order == nil {
+		return errors.New("order is nil")
+	}
+
+	o := *order
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry := &projected{order: &o, expires: now.Add(p.ttl)}
+	if e, ok := p.orders[o.OrderID]; ok {
+		prev := e.Value.(*projected)
+		if !p.expired(prev, now) && prev.order.Status != o.Status && !models.CanTransition(prev.order.Status, o.Status) {
+			return nil
+		}
+		p.removeFromUser(prev.order)
+		e.Value = entry
+		p.recent.MoveToFront(e)
+	} else {
+		p.orders[o.OrderID] = p.recent.PushFront(entry)
+	}
+
+	list := append(p.byUser[o.UserID], &o)
+	sort.Slice(list, func(i, j int) bool {
+		return sortsBefore(list[i], list[j])
+	})
+	p.byUser[o.UserID] = list
+
+	p.evict(now)
+	return nil
+}
+
+// GetOrder returns a copy of the stored order
+func (p *orderProjection) GetOrder(ctx context.Context, orderID string) (*models.Order, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	o, ok := p.lookup(orderID)
+	if !ok {
+		return nil, models.ErrOrderNotFound
+	}
+
+	cp := *o
+	return &cp, nil
+}
+
+// GetHistory returns the transitions the stored order carries
+func (p *orderProjection) GetHistory(ctx context.Context, orderID string) ([]models.StatusTransition, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	o, ok := p.lookup(orderID)
+	if !ok {
+		return nil, models.ErrOrderNotFound
+	}
+	return append([]models.StatusTransition(nil), o.Transitions...), nil
+}
+
+// ListOrdersByUser pages through the user's orders, newest first
+func (p *orderProjection) ListOrdersByUser(
+	ctx context.Context,
+	userID string,
+	cursor string,
+	limit int,
+) (*contracts.OrderPage, error) {
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	page := &contracts.OrderPage{Orders: make([]*models.Order, 0, limit)}
+	now := time.Now()
+
+	for _, o := range p.byUser[userID] {
+		if after != nil && !after.After(o.CreatedAt, o.OrderID) {
+			continue
+		}
+		if p.expired(p.orders[o.OrderID].Value.(*projected), now) {
+			continue
+		}
+
+		if len(page.Orders) == limit {
+			last := page.Orders[len(page.Orders)-1]
+			page.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt, OrderID: last.OrderID}.Encode()
+			break
+		}
+
+		cp := *o
+		page.Orders = append(page.Orders, &cp)
+	}
+
+	return page, nil
+}
+
+// lookup returns the stored order unless it has expired
+func (p *orderProjection) lookup(orderID string) (*models.Order, bool) {
+	e, ok := p.orders[orderID]
+	if !ok || p.expired(e.Value.(*projected), time.Now()) {
+		return nil, false
+	}
+	return e.Value.(*projected).order, true
+}
+
+// evict drops expired orders and those beyond maxOrders, oldest first
+func (p *orderProjection) evict(now time.Time) {
+	for e := p.recent.Back(); e != nil; e = p.recent.Back() {
+		entry := e.Value.(*projected)
+		if !p.expired(entry, now) && (p.maxOrders <= 0 || p.recent.Len() <= p.maxOrders) {
+			return
+		}
+		p.recent.Remove(e)
+		delete(p.orders, entry.order.OrderID)
+		p.removeFromUser(entry.order)
+	}
+}
+
+func (p *orderProjection) expired(entry *projected, now time.Time) bool {
+	return p.ttl > 0 && !now.Before(entry.expires)
+}
+
+func (p *orderProjection) removeFromUser(order *models.Order) {
+	list := p.byUser[order.UserID]
+	for i, o := range list {
+		if o == order {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) == 0 {
+		delete(p.byUser, order.UserID)
+		return
+	}
+	p.byUser[order.UserID] = list
+}
+
+// sortsBefore orders by (CreatedAt, OrderID) descending
+func sortsBefore(a, b *models.Order) bool {
+	if a.CreatedAt.Equal(b.CreatedAt) {
+		return a.OrderID > b.OrderID
+	}
+	return a.CreatedAt.After(b.CreatedAt)
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"OrderSystemHighConcurrency/shared/models"
+)
+
+func order(id, user string, status models.OrderStatus, created time.Time) *models.Order {
+	return &models.Order{OrderID: id, UserID: user, Status: status, CreatedAt: created}
+}
+
+func TestProjectionFollowsStatusChanges(t *testing.T) {
+	ctx := context.Background()
+	p := NewOrderProjection(time.Hour, 10)
+	now := time.Now()
+
+	p.Apply(ctx, order("o1", "u1", models.OrderStatusQueued, now))
+	p.Apply(ctx, order("o1", "u1", models.OrderStatusProcessing, now))
+	p.Apply(ctx, order("o1", "u1", models.OrderStatusCompleted, now))
+
+	// A late copy of an earlier state is stale
+	p.Apply(ctx, order("o1", "u1", models.OrderStatusQueued, now))
+
+	got, err := p.GetOrder(ctx, "o1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.OrderStatusCompleted {
+		t.Errorf("status = %s, want %s", got.Status, models.OrderStatusCompleted)
+	}
+
+	page, err := p.ListOrdersByUser(ctx, "u1", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Orders) != 1 || page.Orders[0].Status != models.OrderStatusCompleted {
+		t.Errorf("list = %+v, want o1 once as COMPLETED", page.Orders)
+	}
+}
+
+func TestProjectionEvictsLeastRecentlyChanged(t *testing.T) {
+	ctx := context.Background()
+	p := NewOrderProjection(0, 2)
+	now := time.Now()
+
+	p.Apply(ctx, order("o1", "u1", models.OrderStatusQueued, now))
+	p.Apply(ctx, order("o2", "u1", models.OrderStatusQueued, now.Add(time.Second)))
+	p.Apply(ctx, order("o1", "u1", models.OrderStatusProcessing, now))
+	p.Apply(ctx, order("o3", "u2", models.OrderStatusQueued, now.Add(2*time.Second)))
+
+	if _, err := p.GetOrder(ctx, "o2"); !errors.Is(err, models.ErrOrderNotFound) {
+		t.Errorf("o2: err = %v, want it evicted", err)
+	}
+	for _, id := range []string{"o1", "o3"} {
+		if _, err := p.GetOrder(ctx, id); err != nil {
+			t.Errorf("%s: %v", id, err)
+		}
+	}
+
+	page, err := p.ListOrdersByUser(ctx, "u1", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Orders) != 1 || page.Orders[0].OrderID != "o1" {
+		t.Errorf("list = %+v, want only o1", page.Orders)
+	}
+}
+
+func TestProjectionExpiresOrders(t *testing.T) {
+	ctx := context.Background()
+	p := NewOrderProjection(20*time.Millisecond, 0)
+
+	p.Apply(ctx, order("o1", "u1", models.OrderStatusQueued, time.Now()))
+	time.Sleep(30 * time.Millisecond)
+
+	if _, err := p.GetOrder(ctx, "o1"); !errors.Is(err, models.ErrOrderNotFound) {
+		t.Errorf("GetOrder: err = %v, want not found", err)
+	}
+	if _, err := p.GetHistory(ctx, "o1"); !errors.Is(err, models.ErrOrderNotFound) {
+		t.Errorf("GetHistory: err = %v, want not found", err)
+	}
+	if page, _ := p.ListOrdersByUser(ctx, "u1", "", 10); len(page.Orders) != 0 {
+		t.Errorf("list = %+v, want empty", page.Orders)
+	}
+
+	// The next change evicts it for good
+	p.Apply(ctx, order("o2", "u1", models.OrderStatusQueued, time.Now()))
+	if n := len(p.(*orderProjection).byUser["u1"]); n != 1 {
+		t.Errorf("user index holds %d orders, want 1", n)
+	}
+}
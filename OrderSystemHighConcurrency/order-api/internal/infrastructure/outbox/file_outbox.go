@@ -0,0 +1,278 @@
+package outbox
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"OrderSystemHighConcurrency/shared/models"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	logFileName = "outbox.log"
+	ackFileName = "outbox.ack"
+)
+
+// entryRef locates a pending entry inside the log file
+type entryRef struct {
+	seq        uint64
+	offset     int64
+	length     int
+	enqueuedAt time.Time
+}
+
+// fileOutbox implements contracts.Outbox as an append-only, fsynced log of
+// JSON lines plus a small file holding the last relayed sequence number.
+// The log is truncated whenever every entry has been relayed.
+type fileOutbox struct {
+	mu      sync.Mutex
+	dir     string
+	file    *os.File
+	size    int64
+	nextSeq uint64
+	acked   uint64
+	pending []entryRef
+}
+
+// NewFileOutbox opens (or creates) an outbox in dir and recovers any
+// entries left over from a previous run
+func NewFileOutbox(dir string) (contracts.Outbox, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("outbox dir error: %w", err)
+	}
+
+	o := &fileOutbox{dir: dir, nextSeq: 1}
+
+	acked, err := o.readAck()
+	if err != nil {
+		return nil, err
+	}
+	o.acked = acked
+
+	file, err := os.OpenFile(filepath.Join(dir, logFileName), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("outbox open error: %w", err)
+	}
+	o.file = file
+
+	if err := o.recover(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// Append writes the event to the end of the log and syncs it to disk
+func (o *fileOutbox) Append(ctx context.Context, eventType models.EventType, order *models.Order) error {
+	if order == nil {
+		return errors.New("order is nil")
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entry := contracts.OutboxEntry{
+		Seq:        o.nextSeq,
+		Type:       eventType,
+		Order:      order,
+		EnqueuedAt: time.Now().UTC(),
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if _, err := o.file.Write(line); err != nil {
+		return fmt.Errorf("outbox write error: %w", err)
+	}
+	if err := o.file.Sync(); err != nil {
+		return fmt.Errorf("outbox sync error: %w", err)
+	}
+
+	o.pending = append(o.pending, entryRef{
+		seq:        entry.Seq,
+		offset:     o.size,
+		length:     len(line),
+		enqueuedAt: entry.EnqueuedAt,
+	})
+	o.size += int64(len(line))
+	o.nextSeq++
+
+	return nil
+}
+
+// Pending reads up to limit entries from the head of the log
+func (o *fileOutbox) Pending(ctx context.Context, limit int) ([]*contracts.OutboxEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	n := min(limit, len(o.pending))
+	entries := make([]*contracts.OutboxEntry, 0, n)
+
+	for _, ref := range o.pending[:n] {
+		buf := make([]byte, ref.length)
+		if _, err := o.file.ReadAt(buf, ref.offset); err != nil {
+			return nil, fmt.Errorf("outbox read error: %w", err)
+		}
+
+		var entry contracts.OutboxEntry
+		if err := json.Unmarshal(buf, &entry); err != nil {
+			return nil, fmt.Errorf("outbox decode error at seq %d: %w", ref.seq, err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, nil
+}
+
+// Ack records seq as relayed and compacts the log once it is fully drained
+func (o *fileOutbox) Ack(ctx context.Context, seq uint64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if seq <= o.acked {
+		return nil
+	}
+
+	if err := o.writeAck(seq); err != nil {
+		return err
+	}
+	o.acked = seq
+
+	i := 0
+	for i < len(o.pending) && o.pending[i].seq <= seq {
+		i++
+	}
+	o.pending = o.pending[i:]
+
+	if len(o.pending) == 0 && o.size > 0 {
+		if err := o.file.Truncate(0); err != nil {
+			return fmt.Errorf("outbox truncate error: %w", err)
+		}
+		o.size = 0
+	}
+
+	return nil
+}
+
+// Stats reports depth and the enqueue time of the oldest entry
+func (o *fileOutbox) Stats() contracts.OutboxStats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stats := contracts.OutboxStats{Depth: len(o.pending)}
+	if len(o.pending) > 0 {
+		stats.OldestAt = o.pending[0].enqueuedAt
+	}
+	return stats
+}
+
+// Close closes the log file
+func (o *fileOutbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.file.Close()
+}
+
+// recover rebuilds the pending index from the log. A torn final line left by
+// a crash mid-write is cut off.
+func (o *fileOutbox) recover() error {
+	if _, err := o.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(o.file)
+	var offset int64
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				if err := o.file.Truncate(offset); err != nil {
+					return fmt.Errorf("outbox truncate error: %w", err)
+				}
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("outbox recover error: %w", err)
+		}
+
+		var entry contracts.OutboxEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("outbox corrupt at offset %d: %w", offset, err)
+		}
+
+		if entry.Seq >= o.nextSeq {
+			o.nextSeq = entry.Seq + 1
+		}
+		if entry.Seq > o.acked {
+			o.pending = append(o.pending, entryRef{
+				seq:        entry.Seq,
+				offset:     offset,
+				length:     len(line),
+				enqueuedAt: entry.EnqueuedAt,
+			})
+		}
+		offset += int64(len(line))
+	}
+
+	o.size = offset
+	if o.acked >= o.nextSeq {
+		o.nextSeq = o.acked + 1
+	}
+	return nil
+}
+
+func (o *fileOutbox) readAck() (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(o.dir, ackFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("outbox ack read error: %w", err)
+	}
+
+	seq, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("outbox ack corrupt: %w", err)
+	}
+	return seq, nil
+}
+
+// writeAck replaces the ack file atomically
+func (o *fileOutbox) writeAck(seq uint64) error {
+	path := filepath.Join(o.dir, ackFileName)
+	tmp := path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("outbox ack write error: %w", err)
+	}
+	if _, err := f.WriteString(strconv.FormatUint(seq, 10)); err != nil {
+		f.Close()
+		return fmt.Errorf("outbox ack write error: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("outbox ack sync error: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
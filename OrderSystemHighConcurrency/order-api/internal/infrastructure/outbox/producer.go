@@ -0,0 +1,82 @@
+package outbox
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	sharedcontracts "OrderSystemHighConcurrency/shared/contracts"
+	"OrderSystemHighConcurrency/shared/metrics"
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+	"errors"
+	"log"
+	"sync"
+)
+
+// outboxProducer implements sharedcontracts.Producer.
+// It publishes directly while the outbox is empty and falls back to the
+// outbox when the broker is unavailable. Once anything is queued, new orders
+// go to the outbox too so the relay keeps them in order.
+type outboxProducer struct {
+	producer sharedcontracts.Producer
+	outbox   contracts.Outbox
+
+	// mu is held for reading across the depth check and a direct publish,
+	// and for writing across an append, so a direct publish never races an
+	// append and overtakes what was just queued
+	mu sync.RWMutex
+}
+
+// NewProducer wraps producer with the outbox
+func NewProducer(producer sharedcontracts.Producer, outbox contracts.Outbox) sharedcontracts.Producer {
+	return &outboxProducer{
+		producer: producer,
+		outbox:   outbox,
+	}
+}
+
+// Publish returns nil once the order is either on Kafka or durably in the outbox
+func (p *outboxProducer) Publish(ctx context.Context, order *models.Order) error {
+	return p.PublishEvent(ctx, models.EventOrderCreated, order)
+}
+
+// PublishEvent goes through the outbox like Publish, so an event never
+// overtakes an earlier one about the same order that is still queued
+func (p *outboxProducer) PublishEvent(ctx context.Context, eventType models.EventType, order *models.Order) error {
+	if order == nil {
+		return errors.New("order is nil")
+	}
+
+	if published, err := p.publishDirect(ctx, eventType, order); published {
+		return nil
+	} else if err != nil {
+		log.Printf("publish of %s failed for order %s, writing to outbox: %v", eventType, order.OrderID, err)
+	}
+
+	p.mu.Lock()
+	err := p.outbox.Append(ctx, eventType, order)
+	p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	metrics.IncrementCounter("order_api_outbox_appended_total")
+	return nil
+}
+
+// publishDirect publishes straight to the broker while the outbox is empty
+// and reports whether it did
+func (p *outboxProducer) publishDirect(ctx context.Context, eventType models.EventType, order *models.Order) (bool, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.outbox.Stats().Depth != 0 {
+		return false, nil
+	}
+	if err := p.producer.PublishEvent(ctx, eventType, order); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (p *outboxProducer) Close() error {
+	return p.producer.Close()
+}
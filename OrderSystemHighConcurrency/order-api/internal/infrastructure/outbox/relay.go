@@ -0,0 +1,86 @@
+package outbox
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	sharedcontracts "OrderSystemHighConcurrency/shared/contracts"
+	"OrderSystemHighConcurrency/shared/metrics"
+	"context"
+	"log"
+	"time"
+)
+
+const relayBatchSize = 100
+
+// Relay drains the outbox to the message queue in append order.
+// It stops at the first failed publish so later entries never overtake
+// earlier ones, and tries again on the next tick.
+type Relay struct {
+	outbox   contracts.Outbox
+	producer sharedcontracts.Producer
+	interval time.Duration
+}
+
+// NewRelay creates a relay that polls the outbox every interval
+func NewRelay(outbox contracts.Outbox, producer sharedcontracts.Producer, interval time.Duration) *Relay {
+	return &Relay{
+		outbox:   outbox,
+		producer: producer,
+		interval: interval,
+	}
+}
+
+// Run relays entries until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) drain(ctx context.Context) {
+	defer r.reportStats()
+
+	for {
+		entries, err := r.outbox.Pending(ctx, relayBatchSize)
+		if err != nil {
+			log.Printf("outbox relay read failed: %v", err)
+			return
+		}
+		if len(entries) == 0 {
+			return
+		}
+
+		for _, entry := range entries {
+			if err := r.producer.PublishEvent(ctx, entry.EventType(), entry.Order); err != nil {
+				log.Printf("outbox relay publish failed for order %s (seq %d): %v", entry.Order.OrderID, entry.Seq, err)
+				return
+			}
+
+			if err := r.outbox.Ack(ctx, entry.Seq); err != nil {
+				log.Printf("outbox relay ack failed at seq %d: %v", entry.Seq, err)
+				return
+			}
+			metrics.IncrementCounter("order_api_outbox_relayed_total")
+		}
+	}
+}
+
+func (r *Relay) reportStats() {
+	stats := r.outbox.Stats()
+
+	age := 0.0
+	if !stats.OldestAt.IsZero() {
+		age = time.Since(stats.OldestAt).Seconds()
+	}
+
+	metrics.SetGauge("order_api_outbox_depth", float64(stats.Depth))
+	metrics.SetGauge("order_api_outbox_oldest_entry_age_seconds", age)
+}
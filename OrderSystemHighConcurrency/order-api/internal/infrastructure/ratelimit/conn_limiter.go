@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"OrderSystemHighConcurrency/shared/auth"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// ConnLimiter caps concurrent long-lived connections such as event
+// streams, in total and per client. A limit of 0 means unlimited.
+type ConnLimiter struct {
+	mu           sync.Mutex
+	total        int
+	perClient    map[string]int
+	maxTotal     int
+	maxPerClient int
+	trusted      []*net.IPNet // proxies whose X-Real-IP is believed
+}
+
+// NewConnLimiter creates a new connection limiter. trustedProxies lists the
+// CIDRs of reverse proxies allowed to report the client's address.
+func NewConnLimiter(maxTotal, maxPerClient int, trustedProxies []string) (*ConnLimiter, error) {
+	l := &ConnLimiter{
+		perClient:    make(map[string]int),
+		maxTotal:     maxTotal,
+		maxPerClient: maxPerClient,
+	}
+
+	for _, cidr := range trustedProxies {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", cidr, err)
+		}
+		l.trusted = append(l.trusted, ipNet)
+	}
+	return l, nil
+}
+
+// Middleware holds a slot for as long as next is serving the connection
+func (l *ConnLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, err := l.clientKey(r)
+		if err != nil {
+			http.Error(w, "invalid IP", http.StatusBadRequest)
+			return
+		}
+
+		if !l.Acquire(client) {
+			http.Error(w, "too many open streams", http.StatusTooManyRequests)
+			return
+		}
+		defer l.Release(client)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientKey identifies who holds the connection: the authenticated user,
+// or else the client address. Behind a trusted proxy every request comes
+// from the proxy, so the address it reports in X-Real-IP is used instead.
+func (l *ConnLimiter) clientKey(r *http.Request) (string, error) {
+	if p := auth.PrincipalFrom(r.Context()); p != nil {
+		return "user:" + p.Subject, nil
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "", err
+	}
+
+	if ip := net.ParseIP(host); ip != nil && l.isTrusted(ip) {
+		if real := net.ParseIP(r.Header.Get("X-Real-IP")); real != nil {
+			return "ip:" + real.String(), nil
+		}
+	}
+	return "ip:" + host, nil
+}
+
+func (l *ConnLimiter) isTrusted(ip net.IP) bool {
+	for _, ipNet := range l.trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Acquire reserves a slot for client; every successful call must be
+// followed by Release
+func (l *ConnLimiter) Acquire(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxTotal > 0 && l.total >= l.maxTotal {
+		return false
+	}
+	if l.maxPerClient > 0 && l.perClient[client] >= l.maxPerClient {
+		return false
+	}
+
+	l.total++
+	l.perClient[client]++
+	return true
+}
+
+// Release frees a slot taken by Acquire
+func (l *ConnLimiter) Release(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.total--
+	if l.perClient[client]--; l.perClient[client] <= 0 {
+		delete(l.perClient, client)
+	}
+}
@@ -0,0 +1,80 @@
+package webhook
+
+import (
+	"sync"
+	"time"
+)
+
+// circuit is the breaker state of one endpoint
+type circuit struct {
+	failures  int // consecutive
+	openUntil time.Time
+	probing   bool // a half-open trial is in flight
+}
+
+// breaker is a consecutive-failure circuit breaker keyed by endpoint.
+// It is local to the replica; endpoints that recover are closed again by
+// the first successful probe.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	circuits  map[string]*circuit
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		circuits:  make(map[string]*circuit),
+	}
+}
+
+// allow reports whether a request to key may be attempted. Once the
+// cooldown has passed a single probe is let through.
+func (b *breaker) allow(key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[key]
+	if !ok || b.threshold <= 0 || c.failures < b.threshold {
+		return true
+	}
+	if time.Now().Before(c.openUntil) || c.probing {
+		return false
+	}
+
+	c.probing = true
+	return true
+}
+
+// forget drops the circuit of key
+func (b *breaker) forget(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.circuits, key)
+}
+
+// record closes the circuit on success and counts a failure otherwise
+func (b *breaker) record(key string, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		delete(b.circuits, key)
+		return
+	}
+
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+
+	c.failures++
+	c.probing = false
+	if b.threshold > 0 && c.failures >= b.threshold {
+		c.openUntil = time.Now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,189 @@
+package webhook_test
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"OrderSystemHighConcurrency/order-api/internal/infrastructure/webhook"
+	"OrderSystemHighConcurrency/order-api/internal/services"
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// attempt is a request seen by a scripted receiver
+type attempt struct {
+	at       time.Time
+	delivery string
+}
+
+// scriptedReceiver answers with codes in turn, repeating the last one
+type scriptedReceiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	codes    []int
+	attempts []attempt
+}
+
+func newScriptedReceiver(t *testing.T, codes ...int) *scriptedReceiver {
+	t.Helper()
+
+	r := &scriptedReceiver{codes: codes}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		code := r.codes[min(len(r.attempts), len(r.codes)-1)]
+		r.attempts = append(r.attempts, attempt{at: time.Now(), delivery: req.Header.Get(webhook.HeaderDelivery)})
+		r.mu.Unlock()
+
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *scriptedReceiver) seen() []attempt {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]attempt(nil), r.attempts...)
+}
+
+// deliver registers a webhook for url, runs a dispatcher and reports a
+// completed order, then returns the delivery once it is settled
+func deliver(t *testing.T, url string, maxAttempts int, backoff time.Duration) *contracts.WebhookDelivery {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	store := webhook.NewMemoryStore()
+	sender := newSender(0, 0)
+	service := services.NewWebhookService(store, sender)
+
+	hook := &contracts.Webhook{ID: "hook-1", UserID: "user-1", URL: url, Secret: "whsec_test", CreatedAt: time.Now()}
+	if err := store.CreateWebhook(ctx, hook); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := services.NewWebhookDispatcher(store, sender, maxAttempts, backoff, time.Second, 2)
+	go dispatcher.Run(ctx)
+
+	order := &models.Order{OrderID: "order-1", UserID: "user-1", Status: models.OrderStatusCompleted, UpdatedAt: time.Now()}
+	if err := dispatcher.HandleStatus(ctx, order); err != nil {
+		t.Fatalf("HandleStatus: %v", err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		log, err := service.Deliveries(ctx, hook.ID, 10)
+		if err != nil {
+			t.Fatalf("Deliveries: %v", err)
+		}
+		if len(log) != 1 {
+			t.Fatalf("delivery log has %d entries, want 1", len(log))
+		}
+		if log[0].State != contracts.DeliveryPending {
+			return log[0]
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("delivery still pending")
+	return nil
+}
+
+func TestDispatcherRetriesUntilDelivered(t *testing.T) {
+	const backoff = 50 * time.Millisecond
+
+	r := newScriptedReceiver(t, http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK)
+	delivery := deliver(t, r.URL, 5, backoff)
+
+	if delivery.State != contracts.DeliveryDelivered {
+		t.Fatalf("state = %s, want %s", delivery.State, contracts.DeliveryDelivered)
+	}
+	if delivery.Attempts != 3 || delivery.ResponseCode != http.StatusOK || delivery.LastError != "" || delivery.DeliveredAt == nil {
+		t.Errorf("delivery = %+v, want 3 attempts ending in 200", delivery)
+	}
+
+	seen := r.seen()
+	if len(seen) != 3 {
+		t.Fatalf("receiver got %d attempts, want 3", len(seen))
+	}
+	for i, a := range seen {
+		if a.delivery != delivery.ID {
+			t.Errorf("attempt %d: delivery header %q, want %q on every retry", i+1, a.delivery, delivery.ID)
+		}
+	}
+
+	// backoff after the first failure, twice that after the second
+	for i, want := range []time.Duration{backoff, 2 * backoff} {
+		if gap := seen[i+1].at.Sub(seen[i].at); gap < want {
+			t.Errorf("retry %d came after %s, want at least %s", i+1, gap, want)
+		}
+	}
+}
+
+func TestDispatcherGivesUpAfterMaxAttempts(t *testing.T) {
+	r := newScriptedReceiver(t, http.StatusInternalServerError)
+	delivery := deliver(t, r.URL, 2, 10*time.Millisecond)
+
+	if delivery.State != contracts.DeliveryFailed {
+		t.Fatalf("state = %s, want %s", delivery.State, contracts.DeliveryFailed)
+	}
+	if delivery.Attempts != 2 || delivery.ResponseCode != http.StatusInternalServerError {
+		t.Errorf("delivery = %+v, want 2 attempts ending in 500", delivery)
+	}
+	if !strings.Contains(delivery.LastError, "500") {
+		t.Errorf("last error = %q, want the endpoint's status", delivery.LastError)
+	}
+	if n := len(r.seen()); n != 2 {
+		t.Errorf("receiver got %d attempts, want 2", n)
+	}
+}
+
+func TestRegisterRequiresHTTPS(t *testing.T) {
+	service := services.NewWebhookService(webhook.NewMemoryStore(), newSender(0, 0))
+
+	for _, url := range []string{"http://example.com/hook", "ftp://example.com/hook", "/hook"} {
+		_, err := service.Register(context.Background(), &contracts.Webhook{UserID: "user-1", URL: url})
+		if !errors.Is(err, contracts.ErrInvalidWebhook) {
+			t.Errorf("Register(%s): err = %v, want %v", url, err, contracts.ErrInvalidWebhook)
+		}
+	}
+
+	hook, err := service.Register(context.Background(), &contracts.Webhook{UserID: "user-1", URL: "https://example.com/hook"})
+	if err != nil {
+		t.Fatalf("Register https: %v", err)
+	}
+	if hook.Secret == "" {
+		t.Error("registered webhook has no secret")
+	}
+}
+
+func TestDeleteDropsBreaker(t *testing.T) {
+	r := newScriptedReceiver(t, http.StatusInternalServerError, http.StatusOK)
+	store := webhook.NewMemoryStore()
+	sender := newSender(1, time.Hour)
+	service := services.NewWebhookService(store, sender)
+
+	hook := &contracts.Webhook{ID: "hook-1", UserID: "user-1", URL: r.URL, Secret: "whsec_test"}
+	if err := store.CreateWebhook(context.Background(), hook); err != nil {
+		t.Fatal(err)
+	}
+
+	sender.Send(context.Background(), hook, testDelivery())
+	if _, err := sender.Send(context.Background(), hook, testDelivery()); !errors.Is(err, contracts.ErrCircuitOpen) {
+		t.Fatalf("err = %v, want %v", err, contracts.ErrCircuitOpen)
+	}
+
+	if err := service.Delete(context.Background(), hook.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := sender.Send(context.Background(), hook, testDelivery()); err != nil {
+		t.Errorf("circuit of deleted webhook still open: %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package webhook
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"net/http"
+)
+
+// ErrBlockedAddress exposes the dial guard's error to tests
+var ErrBlockedAddress = errBlockedAddress
+
+// AllowLoopback lifts the dial guard so tests can deliver to httptest
+// servers on 127.0.0.1
+func AllowLoopback(sender contracts.WebhookSender) {
+	sender.(*httpSender).client.Transport = &http.Transport{}
+}
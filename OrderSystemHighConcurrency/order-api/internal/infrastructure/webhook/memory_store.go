@@ -0,0 +1,176 @@
+package webhook
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// memoryLogSize is how many deliveries the memory store keeps per webhook
+const memoryLogSize = 1000
+
+// memoryStore implements contracts.WebhookStore in memory.
+// It only suits a single order-api instance.
+type memoryStore struct {
+	mu         sync.Mutex
+	hooks      map[string]*contracts.Webhook
+	deliveries map[string]*contracts.WebhookDelivery
+	log        map[string][]string // delivery IDs per webhook, oldest first
+}
+
+// NewMemoryStore creates an in-memory webhook store
+func NewMemoryStore() contracts.WebhookStore {
+	return &memoryStore{
+		hooks:      make(map[string]*contracts.Webhook),
+		deliveries: make(map[string]*contracts.WebhookDelivery),
+		log:        make(map[string][]string),
+	}
+}
+
+func (s *memoryStore) CreateWebhook(ctx context.Context, hook *contracts.Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *hook
+	copied.Events = slices.Clone(hook.Events)
+	s.hooks[hook.ID] = &copied
+	return nil
+}
+
+func (s *memoryStore) GetWebhook(ctx context.Context, id string) (*contracts.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hook, ok := s.hooks[id]
+	if !ok {
+		return nil, contracts.ErrWebhookNotFound
+	}
+	copied := *hook
+	return &copied, nil
+}
+
+func (s *memoryStore) ListWebhooks(ctx context.Context, userID string) ([]*contracts.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var hooks []*contracts.Webhook
+	for _, hook := range s.hooks {
+		if hook.UserID == userID {
+			copied := *hook
+			hooks = append(hooks, &copied)
+		}
+	}
+
+	slices.SortFunc(hooks, func(a, b *contracts.Webhook) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return hooks, nil
+}
+
+func (s *memoryStore) DeleteWebhook(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.hooks[id]; !ok {
+		return contracts.ErrWebhookNotFound
+	}
+	delete(s.hooks, id)
+
+	for _, deliveryID := range s.log[id] {
+		delete(s.deliveries, deliveryID)
+	}
+	delete(s.log, id)
+	return nil
+}
+
+func (s *memoryStore) AddDelivery(ctx context.Context, delivery *contracts.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deliveries[delivery.ID]; ok {
+		return nil
+	}
+
+	copied := *delivery
+	s.deliveries[delivery.ID] = &copied
+	s.log[delivery.WebhookID] = append(s.log[delivery.WebhookID], delivery.ID)
+	s.trim(delivery.WebhookID)
+	return nil
+}
+
+// trim drops the oldest finished deliveries beyond memoryLogSize
+func (s *memoryStore) trim(webhookID string) {
+	ids := s.log[webhookID]
+	excess := len(ids) - memoryLogSize
+
+	kept := ids[:0]
+	for _, id := range ids {
+		if excess > 0 && s.deliveries[id].State != contracts.DeliveryPending {
+			delete(s.deliveries, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.log[webhookID] = kept
+}
+
+func (s *memoryStore) UpdateDelivery(ctx context.Context, delivery *contracts.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deliveries[delivery.ID]; !ok {
+		return nil // the webhook was deleted meanwhile
+	}
+
+	copied := *delivery
+	s.deliveries[delivery.ID] = &copied
+	return nil
+}
+
+func (s *memoryStore) ClaimDue(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+	lease time.Duration,
+) ([]*contracts.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*contracts.WebhookDelivery
+	for _, d := range s.deliveries {
+		if d.State == contracts.DeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+
+	slices.SortFunc(due, func(a, b *contracts.WebhookDelivery) int {
+		return a.NextAttemptAt.Compare(b.NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*contracts.WebhookDelivery, len(due))
+	for i, d := range due {
+		claimed[i] = new(contracts.WebhookDelivery)
+		*claimed[i] = *d
+		d.NextAttemptAt = now.Add(lease)
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]*contracts.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := s.log[webhookID]
+	deliveries := make([]*contracts.WebhookDelivery, 0, min(limit, len(ids)))
+	for i := len(ids) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		copied := *s.deliveries[ids[i]]
+		deliveries = append(deliveries, &copied)
+	}
+	return deliveries, nil
+}
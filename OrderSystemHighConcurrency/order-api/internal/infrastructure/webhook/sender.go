@@ -0,0 +1,140 @@
+package webhook
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderWebhookID = "X-Webhook-ID"
+	HeaderDelivery  = "X-Webhook-Delivery" // stable across retries, for deduplication
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp" // unix seconds, part of the signature
+	HeaderSignature = "X-Webhook-Signature" // "sha256=" + hex HMAC
+)
+
+// errBlockedAddress is returned for endpoints that resolve to an address
+// inside our own network
+var errBlockedAddress = errors.New("webhook endpoint address not allowed")
+
+// maxResponseBody is how much of an endpoint's answer is read before the
+// connection is reused
+const maxResponseBody = 64 << 10
+
+// Sign returns the signature header value for a body sent at timestamp:
+// the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret.
+// Receivers recompute it and reject stale timestamps to stop replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// httpSender implements contracts.WebhookSender over HTTP, with a circuit
+// breaker per webhook
+type httpSender struct {
+	client  *http.Client
+	breaker *breaker
+}
+
+// NewHTTPSender creates a sender. After breakerThreshold consecutive
+// failures an endpoint gets no attempts for breakerCooldown, then a single
+// probe decides whether it is closed again.
+func NewHTTPSender(timeout time.Duration, breakerThreshold int, breakerCooldown time.Duration) contracts.WebhookSender {
+	dialer := &net.Dialer{Timeout: timeout, Control: publicOnly}
+
+	return &httpSender{
+		client: &http.Client{
+			Timeout: timeout,
+			// No proxy, so the dialed address is the endpoint's own
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: timeout,
+				MaxIdleConnsPerHost: 2,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			// A redirect counts as a failed delivery instead of re-posting elsewhere
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		breaker: newBreaker(breakerThreshold, breakerCooldown),
+	}
+}
+
+// Send posts the delivery's payload; any status other than 2xx is an error
+func (s *httpSender) Send(ctx context.Context, hook *contracts.Webhook, delivery *contracts.WebhookDelivery) (int, error) {
+	if !s.breaker.allow(hook.ID) {
+		return 0, contracts.ErrCircuitOpen
+	}
+
+	code, err := s.post(ctx, hook, delivery)
+	s.breaker.record(hook.ID, err == nil)
+	return code, err
+}
+
+// Forget drops the breaker state of a deleted webhook
+func (s *httpSender) Forget(webhookID string) {
+	s.breaker.forget(webhookID)
+}
+
+// publicOnly refuses connections to loopback, private, link-local and
+// other non-public addresses. It runs on the resolved address of every
+// dial, so a hostname can't be pointed at the internal network after
+// registration.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("%w: %s", errBlockedAddress, host)
+	}
+	return nil
+}
+
+func (s *httpSender) post(ctx context.Context, hook *contracts.Webhook, delivery *contracts.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "OrderSystem-Webhooks/1.0")
+	req.Header.Set(HeaderWebhookID, hook.ID)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,196 @@
+package webhook_test
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"OrderSystemHighConcurrency/order-api/internal/infrastructure/webhook"
+	"context"
+	"crypto/hmac"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// receiver is an endpoint answering with the status in code
+type receiver struct {
+	*httptest.Server
+	code     atomic.Int32
+	requests atomic.Int32
+	last     atomic.Pointer[http.Request]
+	body     atomic.Pointer[[]byte]
+}
+
+func newReceiver(t *testing.T, code int) *receiver {
+	t.Helper()
+
+	r := &receiver{}
+	r.code.Store(int32(code))
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.body.Store(&body)
+		r.last.Store(req)
+		r.requests.Add(1)
+		w.WriteHeader(int(r.code.Load()))
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func newSender(threshold int, cooldown time.Duration) contracts.WebhookSender {
+	sender := webhook.NewHTTPSender(5*time.Second, threshold, cooldown)
+	webhook.AllowLoopback(sender)
+	return sender
+}
+
+func testDelivery() *contracts.WebhookDelivery {
+	return &contracts.WebhookDelivery{
+		ID:      "delivery-1",
+		Event:   "order.completed",
+		OrderID: "order-1",
+		Payload: []byte(`{"id":"delivery-1","event":"order.completed"}`),
+	}
+}
+
+func TestSendSignsDelivery(t *testing.T) {
+	r := newReceiver(t, http.StatusNoContent)
+	hook := &contracts.Webhook{ID: "hook-1", URL: r.URL, Secret: "whsec_test"}
+	delivery := testDelivery()
+
+	code, err := newSender(0, 0).Send(context.Background(), hook, delivery)
+	if err != nil || code != http.StatusNoContent {
+		t.Fatalf("Send = %d, %v; want 204, nil", code, err)
+	}
+
+	req, body := r.last.Load(), *r.body.Load()
+	if got := req.Header.Get(webhook.HeaderWebhookID); got != hook.ID {
+		t.Errorf("%s = %q, want %q", webhook.HeaderWebhookID, got, hook.ID)
+	}
+	if got := req.Header.Get(webhook.HeaderDelivery); got != delivery.ID {
+		t.Errorf("%s = %q, want %q", webhook.HeaderDelivery, got, delivery.ID)
+	}
+	if got := req.Header.Get(webhook.HeaderEvent); got != delivery.Event {
+		t.Errorf("%s = %q, want %q", webhook.HeaderEvent, got, delivery.Event)
+	}
+	if string(body) != string(delivery.Payload) {
+		t.Errorf("body = %s, want %s", body, delivery.Payload)
+	}
+
+	// Verify the way a receiver would
+	timestamp, err := strconv.ParseInt(req.Header.Get(webhook.HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("bad %s: %v", webhook.HeaderTimestamp, err)
+	}
+	if age := time.Since(time.Unix(timestamp, 0)); age < -time.Minute || age > time.Minute {
+		t.Errorf("timestamp is %s old", age)
+	}
+	want := webhook.Sign(hook.Secret, timestamp, body)
+	if got := req.Header.Get(webhook.HeaderSignature); !hmac.Equal([]byte(got), []byte(want)) {
+		t.Errorf("%s = %q, want %q", webhook.HeaderSignature, got, want)
+	}
+	if webhook.Sign("whsec_other", timestamp, body) == want {
+		t.Error("signature does not depend on the secret")
+	}
+	if webhook.Sign(hook.Secret, timestamp+1, body) == want {
+		t.Error("signature does not depend on the timestamp")
+	}
+}
+
+func TestSendReportsNon2xx(t *testing.T) {
+	r := newReceiver(t, http.StatusServiceUnavailable)
+	hook := &contracts.Webhook{ID: "hook-1", URL: r.URL, Secret: "whsec_test"}
+
+	code, err := newSender(0, 0).Send(context.Background(), hook, testDelivery())
+	if err == nil || code != http.StatusServiceUnavailable {
+		t.Fatalf("Send = %d, %v; want 503 and an error", code, err)
+	}
+}
+
+func TestSendRefusesInternalAddresses(t *testing.T) {
+	r := newReceiver(t, http.StatusOK)
+	sender := webhook.NewHTTPSender(5*time.Second, 0, 0)
+
+	for _, url := range []string{
+		r.URL, // 127.0.0.1
+		"http://[::1]:9/",
+		"http://10.0.0.1:9/",
+		"http://192.168.1.1:9/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://0.0.0.0:9/",
+	} {
+		hook := &contracts.Webhook{ID: "hook-1", URL: url, Secret: "whsec_test"}
+		if _, err := sender.Send(context.Background(), hook, testDelivery()); !errors.Is(err, webhook.ErrBlockedAddress) {
+			t.Errorf("Send to %s: err = %v, want %v", url, err, webhook.ErrBlockedAddress)
+		}
+	}
+	if n := r.requests.Load(); n != 0 {
+		t.Errorf("receiver got %d requests, want none", n)
+	}
+}
+
+func TestBreakerOpensAndCloses(t *testing.T) {
+	const cooldown = 100 * time.Millisecond
+
+	r := newReceiver(t, http.StatusInternalServerError)
+	hook := &contracts.Webhook{ID: "hook-1", URL: r.URL, Secret: "whsec_test"}
+	sender := newSender(2, cooldown)
+	send := func() error {
+		_, err := sender.Send(context.Background(), hook, testDelivery())
+		return err
+	}
+
+	// Two consecutive failures open the circuit
+	for i := 0; i < 2; i++ {
+		if err := send(); err == nil || errors.Is(err, contracts.ErrCircuitOpen) {
+			t.Fatalf("attempt %d: err = %v, want the endpoint's error", i+1, err)
+		}
+	}
+	if err := send(); !errors.Is(err, contracts.ErrCircuitOpen) {
+		t.Fatalf("after threshold: err = %v, want %v", err, contracts.ErrCircuitOpen)
+	}
+	if n := r.requests.Load(); n != 2 {
+		t.Fatalf("receiver got %d requests while open, want 2", n)
+	}
+
+	// A failed probe after the cooldown opens it again
+	time.Sleep(cooldown + 20*time.Millisecond)
+	if err := send(); err == nil || errors.Is(err, contracts.ErrCircuitOpen) {
+		t.Fatalf("probe: err = %v, want the endpoint's error", err)
+	}
+	if err := send(); !errors.Is(err, contracts.ErrCircuitOpen) {
+		t.Fatalf("after failed probe: err = %v, want %v", err, contracts.ErrCircuitOpen)
+	}
+
+	// A successful probe closes it
+	r.code.Store(http.StatusOK)
+	time.Sleep(cooldown + 20*time.Millisecond)
+	if err := send(); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if err := send(); err != nil {
+		t.Fatalf("after successful probe: %v", err)
+	}
+	if n := r.requests.Load(); n != 5 {
+		t.Errorf("receiver got %d requests, want 5", n)
+	}
+}
+
+func TestForgetClosesCircuit(t *testing.T) {
+	r := newReceiver(t, http.StatusInternalServerError)
+	hook := &contracts.Webhook{ID: "hook-1", URL: r.URL, Secret: "whsec_test"}
+	sender := newSender(1, time.Hour)
+
+	sender.Send(context.Background(), hook, testDelivery())
+	if _, err := sender.Send(context.Background(), hook, testDelivery()); !errors.Is(err, contracts.ErrCircuitOpen) {
+		t.Fatalf("err = %v, want %v", err, contracts.ErrCircuitOpen)
+	}
+
+	sender.Forget(hook.ID)
+	r.code.Store(http.StatusOK)
+	if _, err := sender.Send(context.Background(), hook, testDelivery()); err != nil {
+		t.Fatalf("after Forget: %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+package webhook
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+)
+
+// sqlStore implements contracts.WebhookStore on SQL Server so every
+// order-api replica shares the registrations and the delivery queue.
+//
+// Expected schema:
+//
+//	CREATE TABLE webhooks (
+//	    id         NVARCHAR(64)   NOT NULL PRIMARY KEY,
+//	    user_id    NVARCHAR(255)  NOT NULL,
+//	    url        NVARCHAR(2048) NOT NULL,
+//	    events     NVARCHAR(1024) NOT NULL, -- comma-separated, empty for all
+//	    secret     NVARCHAR(255)  NOT NULL,
+//	    created_at DATETIME2      NOT NULL,
+//	    INDEX ix_webhooks_user (user_id)
+//	);
+//
+//	CREATE TABLE webhook_deliveries (
+//	    id              NVARCHAR(64)   NOT NULL PRIMARY KEY,
+//	    webhook_id      NVARCHAR(64)   NOT NULL,
+//	    event           NVARCHAR(64)   NOT NULL,
+//	    order_id        NVARCHAR(255)  NOT NULL,
+//	    state           NVARCHAR(16)   NOT NULL,
+//	    attempts        INT            NOT NULL,
+//	    response_code   INT            NULL,
+//	    last_error      NVARCHAR(1024) NULL,
+//	    payload         VARBINARY(MAX) NOT NULL,
+//	    created_at      DATETIME2      NOT NULL,
+//	    next_attempt_at DATETIME2      NOT NULL,
+//	    delivered_at    DATETIME2      NULL,
+//	    INDEX ix_webhook_deliveries_due (state, next_attempt_at),
+//	    INDEX ix_webhook_deliveries_log (webhook_id, created_at)
+//	);
+type sqlStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore creates a SQL Server backed webhook store
+func NewSQLStore(db *sql.DB) contracts.WebhookStore {
+	return &sqlStore{db: db}
+}
+
+const deliveryColumns = `id, webhook_id, event, order_id, state, attempts, response_code,
+	last_error, payload, created_at, next_attempt_at, delivered_at`
+
+func (s *sqlStore) CreateWebhook(ctx context.Context, hook *contracts.Webhook) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO webhooks (id, user_id, url, events, secret, created_at) VALUES (@p1, @p2, @p3, @p4, @p5, @p6)`,
+		hook.ID, hook.UserID, hook.URL, strings.Join(hook.Events, ","), hook.Secret, hook.CreatedAt,
+	)
+	return err
+}
+
+func (s *sqlStore) GetWebhook(ctx context.Context, id string) (*contracts.Webhook, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT id, user_id, url, events, secret, created_at FROM webhooks WHERE id = @p1`,
+		id,
+	)
+
+	hook, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, contracts.ErrWebhookNotFound
+	}
+	return hook, err
+}
+
+func (s *sqlStore) ListWebhooks(ctx context.Context, userID string) ([]*contracts.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, user_id, url, events, secret, created_at FROM webhooks
+		WHERE user_id = @p1 ORDER BY created_at`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []*contracts.Webhook
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (s *sqlStore) DeleteWebhook(ctx context.Context, id string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM webhooks WHERE id = @p1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return contracts.ErrWebhookNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE webhook_id = @p1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) AddDelivery(ctx context.Context, d *contracts.WebhookDelivery) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (`+deliveryColumns+`)
+		SELECT @p1, @p2, @p3, @p4, @p5, @p6, NULL, NULL, @p7, @p8, @p9, NULL
+		WHERE NOT EXISTS (SELECT 1 FROM webhook_deliveries WHERE id = @p1)`,
+		d.ID, d.WebhookID, d.Event, d.OrderID, string(d.State), d.Attempts, d.Payload, d.CreatedAt, d.NextAttemptAt,
+	)
+	if isDuplicateKey(err) {
+		return nil // added concurrently
+	}
+	return err
+}
+
+func (s *sqlStore) UpdateDelivery(ctx context.Context, d *contracts.WebhookDelivery) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries
+		SET state = @p2, attempts = @p3, response_code = @p4, last_error = @p5,
+			next_attempt_at = @p6, delivered_at = @p7
+		WHERE id = @p1`,
+		d.ID, string(d.State), d.Attempts, nullInt(d.ResponseCode), nullString(d.LastError), d.NextAttemptAt, nullTime(d.DeliveredAt),
+	)
+	return err
+}
+
+// ClaimDue leases due rows with a single UPDATE; READPAST lets replicas
+// claim different rows concurrently
+func (s *sqlStore) ClaimDue(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+	lease time.Duration,
+) ([]*contracts.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`WITH due AS (
+			SELECT TOP (@p1) * FROM webhook_deliveries WITH (ROWLOCK, UPDLOCK, READPAST)
+			WHERE state = @p2 AND next_attempt_at <= @p3
+			ORDER BY next_attempt_at
+		)
+		UPDATE due SET next_attempt_at = @p4
+		OUTPUT deleted.id, deleted.webhook_id, deleted.event, deleted.order_id, deleted.state,
+			deleted.attempts, deleted.response_code, deleted.last_error, deleted.payload,
+			deleted.created_at, deleted.next_attempt_at, deleted.delivered_at`,
+		limit, string(contracts.DeliveryPending), now, now.Add(lease),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDeliveries(rows)
+}
+
+func (s *sqlStore) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]*contracts.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT TOP (@p2) `+deliveryColumns+` FROM webhook_deliveries
+		WHERE webhook_id = @p1 ORDER BY created_at DESC`,
+		webhookID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDeliveries(rows)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row scanner) (*contracts.Webhook, error) {
+	var (
+		hook   contracts.Webhook
+		events string
+	)
+	if err := row.Scan(&hook.ID, &hook.UserID, &hook.URL, &events, &hook.Secret, &hook.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if events != "" {
+		hook.Events = strings.Split(events, ",")
+	}
+	return &hook, nil
+}
+
+func scanDeliveries(rows *sql.Rows) ([]*contracts.WebhookDelivery, error) {
+	var deliveries []*contracts.WebhookDelivery
+	for rows.Next() {
+		var (
+			d            contracts.WebhookDelivery
+			state        string
+			responseCode sql.NullInt64
+			lastError    sql.NullString
+			deliveredAt  sql.NullTime
+		)
+
+		if err := rows.Scan(
+			&d.ID, &d.WebhookID, &d.Event, &d.OrderID, &state, &d.Attempts, &responseCode,
+			&lastError, &d.Payload, &d.CreatedAt, &d.NextAttemptAt, &deliveredAt,
+		); err != nil {
+			return nil, err
+		}
+
+		d.State = contracts.DeliveryState(state)
+		d.ResponseCode = int(responseCode.Int64)
+		d.LastError = lastError.String
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, rows.Err()
+}
+
+func nullInt(n int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(n), Valid: n != 0}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+// isDuplicateKey reports a primary key / unique index violation
+func isDuplicateKey(err error) bool {
+	var sqlErr mssql.Error
+	if errors.As(err, &sqlErr) {
+		return sqlErr.Number == 2627 || sqlErr.Number == 2601
+	}
+	return false
+}
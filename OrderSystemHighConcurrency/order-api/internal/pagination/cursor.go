@@ -0,0 +1,63 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor points at the last order of a page.
+// Orders are listed by (CreatedAt, OrderID) descending, so the next page
+// starts strictly after this position.
+type Cursor struct {
+	CreatedAt time.Time
+	OrderID   string
+}
+
+// Encode returns an opaque, URL-safe representation of the cursor
+func (c Cursor) Encode() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + c.OrderID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// After reports whether the given position sorts after the cursor
+// (i.e. belongs to the next page).
+func (c Cursor) After(createdAt time.Time, orderID string) bool {
+	if createdAt.Equal(c.CreatedAt) {
+		return orderID < c.OrderID
+	}
+	return createdAt.Before(c.CreatedAt)
+}
+
+// Decode parses a cursor produced by Encode.
+// An empty string yields a nil cursor (first page).
+func Decode(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	nanos, orderID, ok := strings.Cut(string(raw), ":")
+	if !ok || orderID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{
+		CreatedAt: time.Unix(0, n).UTC(),
+		OrderID:   orderID,
+	}, nil
+}
@@ -2,22 +2,36 @@ package services
 
 import (
 	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"OrderSystemHighConcurrency/shared/auth"
 	sharedkafa "OrderSystemHighConcurrency/shared/contracts"
 	"OrderSystemHighConcurrency/shared/models"
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"strings"
 	"time"
 )
 
 // orderService implements OrderService contract
 type orderService struct {
-	producer sharedkafa.Producer
+	producer   sharedkafa.Producer
+	reader     contracts.OrderReader
+	projection contracts.OrderProjection
 }
 
-// NewOrderService creates a new OrderService
-func NewOrderService(producer sharedkafa.Producer) contracts.OrderService {
+// NewOrderService creates a new OrderService.
+// reader serves lookups of persisted orders; projection tracks orders this
+// replica has accepted so they can be reported as QUEUED until persisted.
+func NewOrderService(
+	producer sharedkafa.Producer,
+	reader contracts.OrderReader,
+	projection contracts.OrderProjection,
+) contracts.OrderService {
 	return &orderService{
-		producer: producer,
+		producer:   producer,
+		reader:     reader,
+		projection: projection,
 	}
 }
 
@@ -27,30 +41,136 @@ func (s *orderService) CreateOrder(ctx context.Context, order *models.Order) err
 		return errors.New("order cannot be nil")
 	}
 
-	// Generate OrderID if not present (idempotency support)
+	// The client supplies the order ID, so retried requests are idempotent
 	if order.OrderID == "" {
-		return errors.New("amount must be greater than zero")
+		return fmt.Errorf("%w: order_id is required", contracts.ErrInvalidOrder)
 	}
 
+	// An authenticated caller can only submit orders of its own
+	userID, err := auth.BindUser(ctx, order.UserID)
+	if err != nil {
+		return err
+	}
+	order.UserID = userID
+
 	// Basic validation
 	if order.UserID == "" {
-		return errors.New("user_id is required")
+		return fmt.Errorf("%w: user_id is required", contracts.ErrInvalidOrder)
 	}
-	if order.Amount <= 0 {
-		return errors.New("amount must be greater than zero")
+	order.Currency = strings.ToUpper(order.CurrencyCode())
+	if err := order.ValidateAmount(); err != nil {
+		return fmt.Errorf("%w: %w", contracts.ErrInvalidOrder, err)
+	}
+	if err := order.ValidateItems(); err != nil {
+		return fmt.Errorf("%w: %w", contracts.ErrInvalidOrder, err)
 	}
 
 	// Set initial order state
 	now := time.Now().UTC()
-	order.Status = models.OrderStatusQueued
+	order.Status = models.OrderStatusCreated
+	order.Transitions = nil
 	order.RetryCount = 0
 	order.CreatedAt = now
 	order.UpdatedAt = now
+	if err := order.TransitionTo(models.OrderStatusQueued, "accepted by order-api"); err != nil {
+		return err
+	}
 
 	// Publish order to message queue (Kafka via Producer)
 	if err := s.producer.Publish(ctx, order); err != nil {
 		return err
 	}
 
+	// Remember the accepted order so lookups can report it as QUEUED
+	if err := s.projection.Apply(ctx, order); err != nil {
+		log.Printf("failed to project order %s: %v", order.OrderID, err)
+	}
+
 	return nil
 }
+
+// GetOrder looks the order up in the read store, falling back to the
+// projection for orders that have not been persisted yet
+func (s *orderService) GetOrder(ctx context.Context, orderID string) (*models.Order, error) {
+	if orderID == "" {
+		return nil, errors.New("order_id is required")
+	}
+
+	order, err := s.reader.GetOrder(ctx, orderID)
+	if errors.Is(err, models.ErrOrderNotFound) {
+		order, err = s.projection.GetOrder(ctx, orderID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Other users' orders are reported as unknown
+	if !auth.CanAccess(ctx, order.UserID) {
+		return nil, models.ErrOrderNotFound
+	}
+	return order, nil
+}
+
+// CancelOrder publishes an ORDER_CANCEL_REQUESTED event carrying the order
+// as currently known, so it is keyed like the order's other events
+func (s *orderService) CancelOrder(ctx context.Context, orderID string) (*models.Order, error) {
+	order, err := s.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch order.Status {
+	case models.OrderStatusCompleted:
+		return nil, models.ErrCancelTooLate
+	case models.OrderStatusCancelled:
+		return order, nil
+	}
+
+	if err := s.producer.PublishEvent(ctx, models.EventOrderCancelRequested, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// GetTimeline reads the history recorded by order-processor, falling back
+// to the transitions of orders that have not been processed yet
+func (s *orderService) GetTimeline(ctx context.Context, orderID string) (*contracts.OrderTimeline, error) {
+	if orderID == "" {
+		return nil, errors.New("order_id is required")
+	}
+
+	// History rows don't carry the owner, the order does
+	if auth.PrincipalFrom(ctx) != nil {
+		if _, err := s.GetOrder(ctx, orderID); err != nil {
+			return nil, err
+		}
+	}
+
+	transitions, err := s.reader.GetHistory(ctx, orderID)
+	if errors.Is(err, models.ErrOrderNotFound) {
+		transitions, err = s.projection.GetHistory(ctx, orderID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &contracts.OrderTimeline{OrderID: orderID, Transitions: transitions}, nil
+}
+
+// ListOrders pages through a user's orders from the read store
+func (s *orderService) ListOrders(
+	ctx context.Context,
+	userID string,
+	cursor string,
+	limit int,
+) (*contracts.OrderPage, error) {
+	userID, err := auth.BindUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, errors.New("user_id is required")
+	}
+
+	return s.reader.ListOrdersByUser(ctx, userID, cursor, limit)
+}
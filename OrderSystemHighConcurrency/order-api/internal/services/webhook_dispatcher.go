@@ -0,0 +1,240 @@
+package services
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"OrderSystemHighConcurrency/shared/metrics"
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// webhookPollInterval is how often due retries are looked for
+	webhookPollInterval = time.Second
+
+	// webhookClaimSize is how many due deliveries are claimed at once
+	webhookClaimSize = 100
+
+	// webhookLease keeps a claimed delivery from other replicas; a replica
+	// that dies mid-attempt leaves it to be retried once the lease ends
+	webhookLease = 5 * time.Minute
+
+	// maxLastError bounds the error text kept in the delivery log
+	maxLastError = 1000
+)
+
+// webhookPayload is the JSON body of a delivery
+type webhookPayload struct {
+	ID        string        `json:"id"`
+	Event     string        `json:"event"`
+	CreatedAt time.Time     `json:"created_at"`
+	Order     *models.Order `json:"order"`
+}
+
+// WebhookDispatcher turns order status changes into webhook deliveries and
+// sends them, retrying failed attempts with exponential backoff.
+// Deliveries are recorded in the store before the status event is
+// acknowledged, so a restart loses none.
+type WebhookDispatcher struct {
+	store       contracts.WebhookStore
+	sender      contracts.WebhookSender
+	maxAttempts int
+	backoff     time.Duration // delay after the first failed attempt
+	maxBackoff  time.Duration
+	workers     int
+	wake        chan struct{}
+}
+
+// NewWebhookDispatcher creates a dispatcher. A delivery is given up after
+// maxAttempts; the delay before retry n is backoff * 2^(n-1), capped at
+// maxBackoff.
+func NewWebhookDispatcher(
+	store contracts.WebhookStore,
+	sender contracts.WebhookSender,
+	maxAttempts int,
+	backoff time.Duration,
+	maxBackoff time.Duration,
+	workers int,
+) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		store:       store,
+		sender:      sender,
+		maxAttempts: max(maxAttempts, 1),
+		backoff:     backoff,
+		maxBackoff:  maxBackoff,
+		workers:     max(workers, 1),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// HandleStatus records a delivery for every webhook subscribed to the
+// order's new status. Its delivery ID is derived from the change, so a
+// redelivered status event doesn't notify twice.
+func (d *WebhookDispatcher) HandleStatus(ctx context.Context, order *models.Order) error {
+	hooks, err := d.store.ListWebhooks(ctx, order.UserID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	event := contracts.WebhookEvent(order.Status)
+	added := false
+
+	for _, hook := range hooks {
+		if !hook.Wants(order) {
+			continue
+		}
+
+		id := uuid.NewSHA1(uuid.NameSpaceOID, fmt.Appendf(nil, "%s|%s|%s|%d",
+			hook.ID, order.OrderID, order.Status, order.UpdatedAt.UnixNano())).String()
+
+		payload, err := json.Marshal(webhookPayload{ID: id, Event: event, CreatedAt: now, Order: order})
+		if err != nil {
+			return err
+		}
+
+		if err := d.store.AddDelivery(ctx, &contracts.WebhookDelivery{
+			ID:            id,
+			WebhookID:     hook.ID,
+			Event:         event,
+			OrderID:       order.OrderID,
+			State:         contracts.DeliveryPending,
+			Payload:       payload,
+			CreatedAt:     now,
+			NextAttemptAt: now,
+		}); err != nil {
+			return err
+		}
+		added = true
+	}
+
+	if added {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Run sends due deliveries until ctx is done
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// deliverDue claims due deliveries and attempts them, up to workers at a time
+func (d *WebhookDispatcher) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		due, err := d.store.ClaimDue(ctx, time.Now().UTC(), webhookClaimSize, webhookLease)
+		if err != nil {
+			log.Printf("failed to claim webhook deliveries: %v", err)
+			return
+		}
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, d.workers)
+		for _, delivery := range due {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				d.attempt(ctx, delivery)
+			}()
+		}
+		wg.Wait()
+
+		if len(due) < webhookClaimSize {
+			return
+		}
+	}
+}
+
+// attempt sends one delivery and records the outcome
+func (d *WebhookDispatcher) attempt(ctx context.Context, delivery *contracts.WebhookDelivery) {
+	hook, err := d.store.GetWebhook(ctx, delivery.WebhookID)
+	if errors.Is(err, contracts.ErrWebhookNotFound) {
+		// Deleted together with its deliveries, maybe on another replica
+		d.sender.Forget(delivery.WebhookID)
+		return
+	}
+	if err != nil {
+		log.Printf("failed to load webhook %s: %v", delivery.WebhookID, err)
+		return // retried once the lease ends
+	}
+
+	code, err := d.sender.Send(ctx, hook, delivery)
+	now := time.Now().UTC()
+
+	switch {
+	case err == nil:
+		delivery.Attempts++
+		delivery.State = contracts.DeliveryDelivered
+		delivery.ResponseCode = code
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		metrics.IncrementCounter("webhook_deliveries_succeeded_total")
+
+	case errors.Is(err, contracts.ErrCircuitOpen):
+		// Not attempted, so it doesn't use up an attempt
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(d.delay(delivery.Attempts + 1))
+
+	case ctx.Err() != nil:
+		return // shutting down; retried once the lease ends
+
+	default:
+		delivery.Attempts++
+		delivery.ResponseCode = code
+		delivery.LastError = truncate(err.Error(), maxLastError)
+		metrics.IncrementCounter("webhook_delivery_attempts_failed_total")
+
+		if delivery.Attempts >= d.maxAttempts {
+			delivery.State = contracts.DeliveryFailed
+			metrics.IncrementCounter("webhook_deliveries_failed_total")
+			log.Printf("webhook delivery %s to %s failed after %d attempts: %v",
+				delivery.ID, hook.URL, delivery.Attempts, err)
+		} else {
+			delivery.NextAttemptAt = now.Add(d.delay(delivery.Attempts))
+		}
+	}
+
+	if err := d.store.UpdateDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+		log.Printf("failed to record webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// delay returns the wait after the given number of failed attempts
+func (d *WebhookDispatcher) delay(attempts int) time.Duration {
+	delay := d.backoff
+	for i := 1; i < attempts && delay < d.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.maxBackoff)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"OrderSystemHighConcurrency/order-api/internal/contracts"
+	"OrderSystemHighConcurrency/shared/auth"
+	"OrderSystemHighConcurrency/shared/models"
+	"OrderSystemHighConcurrency/shared/utils"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// webhookService implements contracts.WebhookService
+type webhookService struct {
+	store  contracts.WebhookStore
+	sender contracts.WebhookSender
+}
+
+// NewWebhookService creates a new WebhookService
+func NewWebhookService(store contracts.WebhookStore, sender contracts.WebhookSender) contracts.WebhookService {
+	return &webhookService{store: store, sender: sender}
+}
+
+// Register validates the endpoint and event filter and stores the webhook
+// with a fresh signing secret
+func (s *webhookService) Register(ctx context.Context, hook *contracts.Webhook) (*contracts.Webhook, error) {
+	userID, err := auth.BindUser(ctx, hook.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, fmt.Errorf("%w: user_id is required", contracts.ErrInvalidWebhook)
+	}
+
+	u, err := url.Parse(hook.URL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute https URL", contracts.ErrInvalidWebhook)
+	}
+
+	events := make([]string, 0, len(hook.Events))
+	for _, event := range hook.Events {
+		if !knownWebhookEvent(event) {
+			return nil, fmt.Errorf("%w: unknown event %q", contracts.ErrInvalidWebhook, event)
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	registered := &contracts.Webhook{
+		ID:        utils.GenerateID(),
+		UserID:    userID,
+		URL:       u.String(),
+		Events:    events,
+		Secret:    secret,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.store.CreateWebhook(ctx, registered); err != nil {
+		return nil, err
+	}
+	return registered, nil
+}
+
+// Get returns a webhook without its secret
+func (s *webhookService) Get(ctx context.Context, id string) (*contracts.Webhook, error) {
+	hook, err := s.owned(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	hook.Secret = ""
+	return hook, nil
+}
+
+// List returns the user's webhooks without their secrets
+func (s *webhookService) List(ctx context.Context, userID string) ([]*contracts.Webhook, error) {
+	userID, err := auth.BindUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks, err := s.store.ListWebhooks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, hook := range hooks {
+		hook.Secret = ""
+	}
+	return hooks, nil
+}
+
+func (s *webhookService) Delete(ctx context.Context, id string) error {
+	if _, err := s.owned(ctx, id); err != nil {
+		return err
+	}
+	if err := s.store.DeleteWebhook(ctx, id); err != nil {
+		return err
+	}
+	s.sender.Forget(id)
+	return nil
+}
+
+func (s *webhookService) Deliveries(ctx context.Context, id string, limit int) ([]*contracts.WebhookDelivery, error) {
+	if _, err := s.owned(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.store.ListDeliveries(ctx, id, limit)
+}
+
+// owned returns the webhook if the caller may manage it; other users'
+// webhooks are reported as unknown
+func (s *webhookService) owned(ctx context.Context, id string) (*contracts.Webhook, error) {
+	hook, err := s.store.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !auth.CanAccess(ctx, hook.UserID) {
+		return nil, contracts.ErrWebhookNotFound
+	}
+	return hook, nil
+}
+
+// knownWebhookEvent reports whether event names an order status
+func knownWebhookEvent(event string) bool {
+	for status := range models.Transitions() {
+		if contracts.WebhookEvent(status) == event {
+			return true
+		}
+	}
+	return false
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"OrderSystemHighConcurrency/order-processor/internal/config"
+	"OrderSystemHighConcurrency/order-processor/internal/infrastructure/dlq"
+	"OrderSystemHighConcurrency/order-processor/internal/services"
+	sharedkafka "OrderSystemHighConcurrency/shared/kafka"
+)
+
+const usage = `dlqctl inspects and replays the order dead letter queue.
+
+Usage:
+  dlqctl list   [flags]   list DLQ entries
+  dlqctl stats  [flags]   histogram of failure reasons
+  dlqctl replay [flags]   republish entries to the orders topic
+
+Filters (all commands):
+  -reason   substring of the failure reason
+  -user     user ID
+  -order    comma separated order IDs
+  -since    RFC3339 time, inclusive
+  -until    RFC3339 time, exclusive
+
+replay requires at least one filter, or -all to replay everything.
+Run "dlqctl <command> -h" for every flag.
+`
+
+func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	switch cmd := os.Args[1]; cmd {
+	case "list":
+		err = runList(ctx, os.Args[2:])
+	case "stats":
+		err = runStats(ctx, os.Args[2:])
+	case "replay":
+		err = runReplay(ctx, os.Args[2:])
+	case "-h", "--help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("dlqctl %s: %v", os.Args[1], err)
+	}
+}
+
+// options holds the flags shared by every command
+type options struct {
+	brokers string
+	topic   string
+	reason  string
+	user    string
+	orders  string
+	since   string
+	until   string
+	json    bool
+}
+
+func newFlagSet(name string, cfg *config.Config) (*flag.FlagSet, *options) {
+	opts := &options{}
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+
+	fs.StringVar(&opts.brokers, "brokers", strings.Join(cfg.KafkaBrokers, ","), "comma separated Kafka brokers")
+	fs.StringVar(&opts.topic, "topic", cfg.DLQTopic, "DLQ topic")
+	fs.StringVar(&opts.reason, "reason", "", "filter: substring of the failure reason")
+	fs.StringVar(&opts.user, "user", "", "filter: user ID")
+	fs.StringVar(&opts.orders, "order", "", "filter: comma separated order IDs")
+	fs.StringVar(&opts.since, "since", "", "filter: entries at or after this RFC3339 time")
+	fs.StringVar(&opts.until, "until", "", "filter: entries before this RFC3339 time")
+	fs.BoolVar(&opts.json, "json", false, "print JSON instead of a table")
+
+	return fs, opts
+}
+
+func (o *options) brokerList() []string {
+	return splitList(o.brokers)
+}
+
+func (o *options) filter() (services.DLQFilter, error) {
+	f := services.DLQFilter{
+		Reason:   o.reason,
+		UserID:   o.user,
+		OrderIDs: splitList(o.orders),
+	}
+
+	var err error
+	if o.since != "" {
+		if f.Since, err = time.Parse(time.RFC3339, o.since); err != nil {
+			return f, fmt.Errorf("invalid -since: %w", err)
+		}
+	}
+	if o.until != "" {
+		if f.Until, err = time.Parse(time.RFC3339, o.until); err != nil {
+			return f, fmt.Errorf("invalid -until: %w", err)
+		}
+	}
+	return f, nil
+}
+
+func (o *options) hasFilter() bool {
+	return o.reason != "" || o.user != "" || o.orders != "" || o.since != "" || o.until != ""
+}
+
+func runList(ctx context.Context, args []string) error {
+	cfg := config.LoadConfig()
+	fs, opts := newFlagSet("list", cfg)
+	limit := fs.Int("limit", 0, "maximum entries to print, 0 for all")
+	fs.Parse(args)
+
+	filter, err := opts.filter()
+	if err != nil {
+		return err
+	}
+
+	reader, err := dlq.NewDLQReader(opts.brokerList(), opts.topic)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	entries, err := services.NewDLQAdminService(reader, nil).List(ctx, filter, *limit)
+	if err != nil {
+		return err
+	}
+
+	if opts.json {
+		return printJSON(entries)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tPARTITION\tOFFSET\tORDER\tUSER\tRETRIES\tREASON")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%d\t%s\n",
+			e.Time.Format(time.RFC3339), e.Partition, e.Offset,
+			e.Order.OrderID, e.Order.UserID, e.Order.RetryCount, e.Reason)
+	}
+	return w.Flush()
+}
+
+func runStats(ctx context.Context, args []string) error {
+	cfg := config.LoadConfig()
+	fs, opts := newFlagSet("stats", cfg)
+	fs.Parse(args)
+
+	filter, err := opts.filter()
+	if err != nil {
+		return err
+	}
+
+	reader, err := dlq.NewDLQReader(opts.brokerList(), opts.topic)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	histogram, err := services.NewDLQAdminService(reader, nil).Histogram(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if opts.json {
+		return printJSON(histogram)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COUNT\tREASON")
+	for _, bucket := range histogram {
+		fmt.Fprintf(w, "%d\t%s\n", bucket.Count, bucket.Reason)
+	}
+	return w.Flush()
+}
+
+func runReplay(ctx context.Context, args []string) error {
+	cfg := config.LoadConfig()
+	fs, opts := newFlagSet("replay", cfg)
+	target := fs.String("target", cfg.KafkaTopic, "topic to replay into")
+	all := fs.Bool("all", false, "replay every entry in the DLQ")
+	dryRun := fs.Bool("dry-run", false, "print what would be replayed without publishing")
+	operator := fs.String("operator", os.Getenv("USER"), "name recorded in the replay audit header")
+	keyStrategy := fs.String("key-strategy", cfg.KafkaKeyStrategy, "message key of replayed orders: order, user or tenant")
+	partitioner := fs.String("partitioner", cfg.KafkaPartitioner, "partitioner of replayed orders: hash, murmur2 or manual")
+	fs.Parse(args)
+
+	if !*all && !opts.hasFilter() {
+		return errors.New("refusing to replay without a filter; pass -all to replay everything")
+	}
+
+	filter, err := opts.filter()
+	if err != nil {
+		return err
+	}
+
+	reader, err := dlq.NewDLQReader(opts.brokerList(), opts.topic)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if *dryRun {
+		entries, err := services.NewDLQAdminService(reader, nil).List(ctx, filter, 0)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			fmt.Printf("would replay order %s (%d/%d): %s\n", e.Order.OrderID, e.Partition, e.Offset, e.Reason)
+		}
+		fmt.Printf("%d entries would be replayed to %s\n", len(entries), *target)
+		return nil
+	}
+
+	replayer, err := dlq.NewDLQReplayer(
+		opts.brokerList(),
+		sharedkafka.KeyStrategy(*keyStrategy),
+		sharedkafka.Partitioner(*partitioner),
+		opts.topic,
+		*target,
+		*operator,
+	)
+	if err != nil {
+		return err
+	}
+	defer replayer.Close()
+
+	n, err := services.NewDLQAdminService(reader, replayer).Replay(ctx, filter)
+	fmt.Printf("replayed %d entries to %s\n", n, *target)
+	return err
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -12,8 +12,12 @@ import (
 	"OrderSystemHighConcurrency/order-processor/internal/infrastructure/db"
 	"OrderSystemHighConcurrency/order-processor/internal/infrastructure/dlq"
 	"OrderSystemHighConcurrency/order-processor/internal/infrastructure/kafka"
+	"OrderSystemHighConcurrency/order-processor/internal/infrastructure/retry"
+	"OrderSystemHighConcurrency/order-processor/internal/infrastructure/status"
 	"OrderSystemHighConcurrency/order-processor/internal/services"
 	"OrderSystemHighConcurrency/order-processor/internal/worker"
+	sharedkafka "OrderSystemHighConcurrency/shared/kafka"
+	"OrderSystemHighConcurrency/shared/schema"
 )
 
 func main() {
@@ -32,6 +36,11 @@ func main() {
 	)
 	defer stop()
 
+	// The pipeline runs on its own context so it can keep draining
+	// after the shutdown signal has stopped the consumer
+	workCtx, cancelWork := context.WithCancel(context.Background())
+	defer cancelWork()
+
 	// ------------------------------------------------
 	// 3️⃣ Database Connection (SQL Server)
 	// ------------------------------------------------
@@ -44,7 +53,11 @@ func main() {
 	// ------------------------------------------------
 	// 4️⃣ Repository
 	// ------------------------------------------------
-	repository := db.NewOrderRepository(database)
+	repository := db.NewOrderRepository(
+		database,
+		db.WriteMode(cfg.DBWriteMode),
+		cfg.DBBulkThreshold,
+	)
 
 	// ------------------------------------------------
 	// 5️⃣ Batch Service
@@ -54,30 +67,82 @@ func main() {
 		cfg.BatchSize,
 		cfg.BatchFlushInterval,
 	)
+	batchService.Start(workCtx)
 
 	// ------------------------------------------------
 	// 6️⃣ Retry Service
 	// ------------------------------------------------
-	retryService := services.NewRetryService(cfg.MaxRetries)
+	retryService := services.NewRetryService(cfg.MaxRetries, cfg.RetryTiers, cfg.RetryJitter)
+
+	encoding, err := sharedkafka.ParseEncoding(cfg.KafkaEncoding)
+	if err != nil {
+		log.Fatalf("invalid kafka config: %v", err)
+	}
+
+	// The processor produces retry events too, so it registers its schema
+	// like any producer; the resolver skips messages it can't read
+	registry, err := schema.Open(cfg.SchemaRegistry, cfg.SchemaRegistryPath, cfg.SchemaRegistryURL, cfg.SchemaCompatibility)
+	if err != nil {
+		log.Fatalf("invalid schema registry config: %v", err)
+	}
+
+	var schemaID int
+	var schemaResolver *schema.Resolver
+	if registry != nil {
+		schemaID, err = schema.RegisterOrderEvent(ctx, registry, cfg.KafkaTopic)
+		if err != nil {
+			log.Fatalf("failed to register event schema: %v", err)
+		}
+		schemaResolver = schema.NewResolver(registry)
+	}
+
+	retryPublisher, err := retry.NewRetryProducer(
+		cfg.KafkaBrokers,
+		sharedkafka.KeyStrategy(cfg.KafkaKeyStrategy),
+		sharedkafka.Partitioner(cfg.KafkaPartitioner),
+		encoding,
+		schemaID,
+	)
+	if err != nil {
+		log.Fatalf("failed to init retry producer: %v", err)
+	}
 
 	// ------------------------------------------------
 	// 7️⃣ DLQ Producer
 	// ------------------------------------------------
 	dlqPublisher, err := dlq.NewDLQProducer(
 		cfg.KafkaBrokers,
-		"orders-dlq",
+		cfg.DLQTopic,
 	)
 	if err != nil {
 		log.Fatalf("failed to init DLQ producer: %v", err)
 	}
 
+	// Orders are only acknowledged once dead-lettered, so publishes are
+	// retried until they succeed or shutdown begins
+	dlqPublisher = dlq.NewRetryingPublisher(dlqPublisher)
+
+	// Status events are flushed last, after the final batch write
+	statusPublisher := status.NewNopPublisher()
+	if cfg.StatusTopic != "" {
+		statusPublisher, err = status.NewStatusProducer(cfg.KafkaBrokers, cfg.StatusTopic, encoding)
+		if err != nil {
+			log.Fatalf("failed to init status producer: %v", err)
+		}
+	}
+	defer statusPublisher.Close()
+
 	// ------------------------------------------------
 	// 8️⃣ Order Processor (Core Logic)
 	// ------------------------------------------------
 	orderProcessor := services.NewOrderProcessor(
 		batchService,
 		retryService,
+		retryPublisher,
 		dlqPublisher,
+		db.NewHistoryRepository(database),
+		repository,
+		statusPublisher,
 	)
 
 	// ------------------------------------------------
@@ -85,9 +150,13 @@ func main() {
 	// ------------------------------------------------
 	queueSize := 1000 // or any number of pending orders you want to buffer
 
-	workerPool := worker.NewWorkerPool(cfg.WorkerCount, queueSize, orderProcessor)
-	workerPool.Start(ctx)
-	defer workerPool.Stop()
+	dispatchMode, err := worker.ParseDispatchMode(cfg.WorkerDispatch)
+	if err != nil {
+		log.Fatalf("invalid worker config: %v", err)
+	}
+
+	workerPool := worker.NewWorkerPool(cfg.WorkerCount, queueSize, orderProcessor, dispatchMode)
+	workerPool.Start(workCtx)
 
 	// ------------------------------------------------
 	// 🔟 Kafka Consumer
@@ -95,18 +164,26 @@ func main() {
 	consumer, err := kafka.NewOrderConsumer(
 		cfg.KafkaBrokers,
 		cfg.ConsumerGroup,
-		cfg.KafkaTopic,
+		consumerTopics(cfg),
 		workerPool,
+		services.NewDrainService(workerPool, batchService),
+		cfg.ShutdownTimeout,
+		schemaResolver,
+		dlqPublisher,
+		retryPublisher,
+		retryHolds(cfg),
 	)
 	if err != nil {
 		log.Fatalf("failed to init kafka consumer: %v", err)
 	}
-	defer consumer.Close()
 
 	// ------------------------------------------------
 	// 1️⃣1️⃣ Start Consumer
 	// ------------------------------------------------
+	consumerDone := make(chan struct{})
 	go func() {
+		defer close(consumerDone)
+
 		log.Println("order-processor started")
 		if err := consumer.Start(ctx); err != nil {
 			log.Printf("consumer stopped: %v", err)
@@ -120,8 +197,43 @@ func main() {
 	<-ctx.Done()
 	log.Println("shutting down order-processor...")
 
-	// Allow final batch flush
-	time.Sleep(2 * time.Second)
+	// 1. Consumer stops reading; each claim drains its in-flight orders
+	<-consumerDone
+
+	// 2. Worker pool processes whatever is still queued
+	workerPool.Stop()
+
+	// 3. Final batch flush
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := batchService.Close(shutdownCtx); err != nil {
+		log.Printf("final batch flush failed: %v", err)
+	}
+
+	// 4. Commit marked offsets and leave the group
+	if err := consumer.Close(); err != nil {
+		log.Printf("consumer close failed: %v", err)
+	}
 
 	log.Println("order-processor stopped cleanly")
 }
+
+// consumerTopics returns the main topic followed by every retry topic
+func consumerTopics(cfg *config.Config) []string {
+	topics := []string{cfg.KafkaTopic}
+	for _, tier := range cfg.RetryTiers {
+		topics = append(topics, tier.Topic)
+	}
+	return topics
+}
+
+// retryHolds returns how long a message of each retry topic may wait for
+// its not-before time: the tier's delay plus the most jitter adds to it
+func retryHolds(cfg *config.Config) map[string]time.Duration {
+	holds := make(map[string]time.Duration, len(cfg.RetryTiers))
+	for _, tier := range cfg.RetryTiers {
+		holds[tier.Topic] = tier.Delay + time.Duration(float64(tier.Delay)*max(cfg.RetryJitter, 0))
+	}
+	return holds
+}
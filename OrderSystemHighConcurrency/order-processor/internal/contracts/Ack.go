@@ -0,0 +1,5 @@
+package contracts
+
+// Ack is called once an order has been durably handled, either persisted by
+// the repository or routed to the DLQ. Only then may its Kafka offset be committed.
+type Ack func()
@@ -3,9 +3,22 @@ package contracts
 import (
 	"OrderSystemHighConcurrency/shared/models"
 	"context"
+	"errors"
 )
 
+// ErrCancelled is reported to the done callback of an order removed from
+// the batch, or held back by the repository, because it was cancelled.
+var ErrCancelled = errors.New("order cancelled")
+
 type BatchService interface {
-	Add(ctx context.Context, order *models.Order) error
+	// Add buffers the order. done is called exactly once with the result of
+	// the write that included it (nil on success); write errors are not returned.
+	Add(ctx context.Context, order *models.Order, done func(err error)) error
 	Flush(ctx context.Context) error
+
+	// Remove takes every buffered entry of the order owned by userID out of
+	// the batch and calls its done with cause instead of writing it. It
+	// returns how many entries were removed; orders already being written
+	// are not affected.
+	Remove(orderID, userID string, cause error) int
 }
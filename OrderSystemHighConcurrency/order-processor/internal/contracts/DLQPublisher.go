@@ -8,4 +8,18 @@ import (
 // DLQPublisher defines how failed orders are sent to a dead letter queue.
 type DLQPublisher interface {
 	Publish(ctx context.Context, order *models.Order, reason string) error
+
+	// PublishMessage dead-letters a consumed message that can't be read as
+	// an order, keeping its payload and where it came from.
+	PublishMessage(ctx context.Context, msg *RawMessage, reason string) error
+}
+
+// RawMessage is a consumed message as it was received.
+type RawMessage struct {
+	Topic     string            `json:"topic"`
+	Partition int32             `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Key       []byte            `json:"key,omitempty"`
+	Value     []byte            `json:"value"`
+	Headers   map[string]string `json:"headers,omitempty"`
 }
@@ -0,0 +1,32 @@
+package contracts
+
+import (
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+	"time"
+)
+
+// DLQEntry is one dead-lettered order with the position it was read from.
+type DLQEntry struct {
+	Order     *models.Order `json:"order"`
+	Reason    string        `json:"reason"`
+	Time      time.Time     `json:"time"`
+	Partition int32         `json:"partition"`
+	Offset    int64         `json:"offset"`
+}
+
+// DLQReader reads the dead letter queue without consuming it.
+type DLQReader interface {
+	// ReadAll returns every entry currently in the DLQ, oldest first per partition.
+	ReadAll(ctx context.Context) ([]*DLQEntry, error)
+
+	Close() error
+}
+
+// DLQReplayer puts dead-lettered orders back onto the main topic.
+type DLQReplayer interface {
+	// Replay republishes the entry's order with an audit trail of where it came from.
+	Replay(ctx context.Context, entry *DLQEntry) error
+
+	Close() error
+}
@@ -0,0 +1,10 @@
+package contracts
+
+import "context"
+
+// Drainer finishes the work already handed to the processing pipeline,
+// e.g. before a consumer session ends and its offsets are committed.
+type Drainer interface {
+	// Drain blocks until in-flight orders have been written or ctx is done.
+	Drain(ctx context.Context) error
+}
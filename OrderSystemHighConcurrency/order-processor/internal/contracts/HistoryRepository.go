@@ -0,0 +1,14 @@
+package contracts
+
+import (
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+)
+
+// HistoryRepository stores the audit trail of order status changes.
+type HistoryRepository interface {
+	// Append records transitions of an order. Transitions that are already
+	// stored are skipped, so an order's full history may be appended again
+	// whenever it changes.
+	Append(ctx context.Context, orderID string, transitions []models.StatusTransition) error
+}
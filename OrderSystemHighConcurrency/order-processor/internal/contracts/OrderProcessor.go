@@ -9,5 +9,12 @@ import (
 type OrderProcessor interface {
 	// Process handles a single order.
 	// It may trigger retries, batching, or DLQ routing.
-	Process(ctx context.Context, order *models.Order) error
+	// ack is called once the order has been persisted or sent to the DLQ.
+	Process(ctx context.Context, order *models.Order, ack Ack) error
+
+	// Cancel handles a cancellation request for the order. Orders that have
+	// not completed are stored as CANCELLED; completed ones are left alone
+	// and the request is reported as too late.
+	// ack is called once the request has been handled.
+	Cancel(ctx context.Context, order *models.Order, reason string, ack Ack) error
 }
@@ -3,11 +3,41 @@ package contracts
 import (
 	"OrderSystemHighConcurrency/shared/models"
 	"context"
+	"fmt"
 )
 
 // Repository defines database operations for orders.
 type Repository interface {
 	// SaveBatch persists a batch of orders in the database.
 	// Implementations must ensure atomicity and performance.
+	// When only some rows fail, a *BatchError lists them and every other
+	// row has been persisted. The status transitions carried by each
+	// order are stored in its history along with it.
 	SaveBatch(ctx context.Context, orders []*models.Order) error
+
+	// RequestCancel returns the stored order. When the order has not been
+	// persisted yet, the cancellation is recorded durably and
+	// models.ErrOrderNotFound is returned; SaveBatch then rejects the
+	// order with ErrCancelled when it arrives, so it is stored CANCELLED.
+	// Cancelling an order of another user is sharedcontracts.ErrForbidden.
+	RequestCancel(ctx context.Context, order *models.Order, reason string) (*models.Order, error)
+}
+
+// BatchError reports the rows of a batch that could not be written.
+type BatchError struct {
+	Rows []RowError
+}
+
+// RowError is the failure of a single row within a batch.
+type RowError struct {
+	Index   int // position in the slice passed to SaveBatch
+	OrderID string
+	Err     error
+}
+
+func (e *BatchError) Error() string {
+	if len(e.Rows) == 1 {
+		return fmt.Sprintf("order %s: %v", e.Rows[0].OrderID, e.Rows[0].Err)
+	}
+	return fmt.Sprintf("%d orders in batch failed, first %s: %v", len(e.Rows), e.Rows[0].OrderID, e.Rows[0].Err)
 }
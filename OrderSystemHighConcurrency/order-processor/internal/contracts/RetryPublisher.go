@@ -0,0 +1,17 @@
+package contracts
+
+import (
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+	"time"
+)
+
+// RetryPublisher defines how failed orders are scheduled for another attempt.
+type RetryPublisher interface {
+	// Publish sends the order to topic; it must not be processed before notBefore.
+	Publish(ctx context.Context, order *models.Order, topic string, notBefore time.Time, reason string) error
+
+	// Requeue writes a consumed retry message that is not due yet back to
+	// the end of its topic, unchanged.
+	Requeue(ctx context.Context, msg *RawMessage) error
+}
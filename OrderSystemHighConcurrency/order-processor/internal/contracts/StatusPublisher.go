@@ -0,0 +1,12 @@
+package contracts
+
+import "OrderSystemHighConcurrency/shared/models"
+
+// StatusPublisher announces status changes of orders once they are
+// persisted, so clients can watch their orders.
+type StatusPublisher interface {
+	// Publish announces the order's current status. Delivery is best
+	// effort and never holds up processing; failures are only logged.
+	Publish(order *models.Order)
+	Close() error
+}
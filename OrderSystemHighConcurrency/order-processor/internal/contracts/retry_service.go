@@ -1,5 +1,28 @@
 package contracts
 
+import (
+	"errors"
+	"time"
+)
+
+// ErrPermanent marks failures that will not succeed on retry
+// (e.g. constraint violations); wrap it with fmt.Errorf("%w: ...").
+var ErrPermanent = errors.New("permanent failure")
+
 type RetryService interface {
 	ShouldRetry(attempt int) bool
+
+	// IsRetryable reports whether the error is transient.
+	// Fatal errors go to the DLQ without further attempts.
+	IsRetryable(err error) bool
+
+	// Next returns the retry topic for the given attempt (1-based) and the
+	// jittered delay before the order may be processed again.
+	Next(attempt int) (topic string, delay time.Duration)
+}
+
+// RetryTier is one retry topic and the delay its messages wait for.
+type RetryTier struct {
+	Topic string
+	Delay time.Duration
 }
@@ -0,0 +1,172 @@
+package db
+
+import (
+	"OrderSystemHighConcurrency/order-processor/internal/contracts"
+	sharedcontracts "OrderSystemHighConcurrency/shared/contracts"
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Cancellations of orders that have not been persisted yet wait here until
+// the order arrives, however long that takes:
+//
+//	CREATE TABLE order_cancellations (
+//		order_id     NVARCHAR(64)  NOT NULL,
+//		user_id      NVARCHAR(64)  NOT NULL,
+//		reason       NVARCHAR(MAX) NULL,
+//		requested_at DATETIME2(7)  NOT NULL,
+//		PRIMARY KEY (order_id, user_id)
+//	);
+//
+// Only a cancellation by the user the order arrives for holds it back; the
+// others are dropped with it. RequestCancel and the batch write both lock
+// the order's cancellation keys before touching its order row, so one of
+// them always sees the other.
+
+// RequestCancel returns the stored order, or records the cancellation and
+// returns models.ErrOrderNotFound. A stored order of another user is
+// sharedcontracts.ErrForbidden.
+func (r *orderRepository) RequestCancel(ctx context.Context, order *models.Order, reason string) (*models.Order, error) {
+	var stored *models.Order
+	recorded := false
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var pending int
+		err := tx.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM order_cancellations WITH (UPDLOCK, HOLDLOCK) WHERE order_id = @p1 AND user_id = @p2`,
+			order.OrderID, order.UserID,
+		).Scan(&pending)
+		if err != nil {
+			return err
+		}
+
+		stored, err = scanOrder(tx.QueryRowContext(ctx,
+			`SELECT `+strings.Join(orderColumnNames, ", ")+` FROM orders WITH (UPDLOCK, HOLDLOCK) WHERE order_id = @p1`,
+			order.OrderID,
+		))
+		if err == nil && stored.UserID != order.UserID {
+			return fmt.Errorf("%w: order %s belongs to another user", sharedcontracts.ErrForbidden, order.OrderID)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		recorded = true
+		if pending > 0 {
+			return nil
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO order_cancellations (order_id, user_id, reason, requested_at) VALUES (@p1, @p2, @p3, @p4)`,
+			order.OrderID, order.UserID, reason, time.Now().UTC(),
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if recorded {
+		return nil, models.ErrOrderNotFound
+	}
+	return stored, nil
+}
+
+// pendingCancel is a cancellation waiting for its order
+type pendingCancel struct {
+	userID string
+	reason string
+}
+
+// pendingCancels locks the cancellation keys of orders and returns the
+// cancellations requested for each
+func pendingCancels(ctx context.Context, tx *sql.Tx, orders []*models.Order) (map[string][]pendingCancel, error) {
+	pending := make(map[string][]pendingCancel)
+
+	for start := 0; start < len(orders); start += maxParamsPerStatement {
+		chunk := orders[start:min(start+maxParamsPerStatement, len(orders))]
+		args := make([]interface{}, len(chunk))
+		for i, o := range chunk {
+			args[i] = o.OrderID
+		}
+
+		rows, err := tx.QueryContext(ctx,
+			`SELECT order_id, user_id, reason FROM order_cancellations WITH (UPDLOCK, HOLDLOCK) WHERE order_id IN `+placeholders(1, len(chunk)),
+			args...,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id string
+			var p pendingCancel
+			var reason sql.NullString
+			if err := rows.Scan(&id, &p.userID, &reason); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			p.reason = reason.String
+			pending[id] = append(pending[id], p)
+		}
+		if err := rows.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	return pending, nil
+}
+
+// holdCancelled takes the orders their own user cancelled out of batch.
+// They are rejected with contracts.ErrCancelled so the processor stores
+// them as CANCELLED instead; orders already CANCELLED go through.
+func holdCancelled(batch []*models.Order, pending map[string][]pendingCancel) ([]*models.Order, map[string]error) {
+	if len(pending) == 0 {
+		return batch, nil
+	}
+
+	held := make(map[string]error)
+	kept := make([]*models.Order, 0, len(batch))
+	for _, o := range batch {
+		if o.Status != models.OrderStatusCancelled {
+			if reason, ok := cancelledBy(pending[o.OrderID], o.UserID); ok {
+				held[o.OrderID] = fmt.Errorf("%w: %s", contracts.ErrCancelled, reason)
+				continue
+			}
+		}
+		kept = append(kept, o)
+	}
+	return kept, held
+}
+
+// cancelledBy returns the reason of the cancellation userID requested
+func cancelledBy(pending []pendingCancel, userID string) (string, bool) {
+	for _, p := range pending {
+		if p.userID == userID {
+			return p.reason, true
+		}
+	}
+	return "", false
+}
+
+// resolveCancels deletes the pending cancellations of orders that were
+// just written; from now on they are cancelled through their order row
+func resolveCancels(ctx context.Context, tx *sql.Tx, written []*models.Order, pending map[string][]pendingCancel) error {
+	var ids []interface{}
+	for _, o := range written {
+		if _, ok := pending[o.OrderID]; ok {
+			ids = append(ids, o.OrderID)
+		}
+	}
+
+	for start := 0; start < len(ids); start += maxParamsPerStatement {
+		chunk := ids[start:min(start+maxParamsPerStatement, len(ids))]
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM order_cancellations WHERE order_id IN `+placeholders(1, len(chunk)), chunk...,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
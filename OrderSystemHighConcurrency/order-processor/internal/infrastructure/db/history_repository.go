@@ -0,0 +1,114 @@
+package db
+
+import (
+	"OrderSystemHighConcurrency/order-processor/internal/contracts"
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// The history table is append-only. A transition is identified by its
+// order, target status and time, which stay the same however often the
+// order carrying it is redelivered:
+//
+//	CREATE TABLE order_status_history (
+//		order_id    NVARCHAR(64)  NOT NULL,
+//		from_status NVARCHAR(20)  NOT NULL,
+//		to_status   NVARCHAR(20)  NOT NULL,
+//		reason      NVARCHAR(MAX) NULL,
+//		occurred_at DATETIME2(7)  NOT NULL,
+//		CONSTRAINT pk_order_status_history PRIMARY KEY (order_id, occurred_at, to_status)
+//	);
+const (
+	columnsPerTransition = 5
+	historyChunkSize     = maxParamsPerStatement / columnsPerTransition
+)
+
+// historyRow is one transition of one order
+type historyRow struct {
+	orderID string
+	models.StatusTransition
+}
+
+// historyRepository implements contracts.HistoryRepository
+type historyRepository struct {
+	db *sql.DB
+}
+
+// NewHistoryRepository creates a SQL Server backed status history store
+func NewHistoryRepository(db *sql.DB) contracts.HistoryRepository {
+	return &historyRepository{db: db}
+}
+
+// Append inserts the transitions that are not stored yet
+func (r *historyRepository) Append(ctx context.Context, orderID string, transitions []models.StatusTransition) error {
+	rows := make([]historyRow, len(transitions))
+	for i, t := range transitions {
+		rows[i] = historyRow{orderID: orderID, StatusTransition: t}
+	}
+	return insertHistory(ctx, r.db, rows)
+}
+
+// historyOf collects the transitions carried by orders. Versions of the same
+// order share their earlier transitions, which are only kept once.
+func historyOf(orders []*models.Order) []historyRow {
+	type key struct {
+		orderID string
+		to      models.OrderStatus
+		at      time.Time
+	}
+
+	seen := make(map[key]bool)
+	var rows []historyRow
+	for _, o := range orders {
+		for _, t := range o.Transitions {
+			k := key{o.OrderID, t.To, t.At.UTC()}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			rows = append(rows, historyRow{orderID: o.OrderID, StatusTransition: t})
+		}
+	}
+	return rows
+}
+
+// insertHistory writes rows in parameter-safe chunks, skipping transitions
+// that are already stored
+func insertHistory(ctx context.Context, db execer, rows []historyRow) error {
+	for start := 0; start < len(rows); start += historyChunkSize {
+		chunk := rows[start:min(start+historyChunkSize, len(rows))]
+
+		var query strings.Builder
+		query.WriteString(`
+			INSERT INTO order_status_history (order_id, from_status, to_status, reason, occurred_at)
+			SELECT v.order_id, v.from_status, v.to_status, v.reason, v.occurred_at
+			FROM (VALUES `)
+		query.WriteString(placeholders(len(chunk), columnsPerTransition))
+		query.WriteString(`) AS v (order_id, from_status, to_status, reason, occurred_at)
+			WHERE NOT EXISTS (
+				SELECT 1 FROM order_status_history h WITH (UPDLOCK, HOLDLOCK)
+				WHERE h.order_id = v.order_id
+				AND h.occurred_at = v.occurred_at
+				AND h.to_status = v.to_status
+			)`)
+
+		args := make([]interface{}, 0, len(chunk)*columnsPerTransition)
+		for _, row := range chunk {
+			args = append(args,
+				row.orderID,
+				string(row.From),
+				string(row.To),
+				sql.NullString{String: row.Reason, Valid: row.Reason != ""},
+				row.At.UTC(),
+			)
+		}
+
+		if _, err := db.ExecContext(ctx, query.String(), args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
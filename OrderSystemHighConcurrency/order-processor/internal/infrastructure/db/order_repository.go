@@ -5,39 +5,620 @@ import (
 	"OrderSystemHighConcurrency/shared/models"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
 	"strings"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+)
+
+// WriteMode selects how SaveBatch writes orders
+type WriteMode string
+
+const (
+	// WriteModeInsert performs a plain multi-row INSERT
+	WriteModeInsert WriteMode = "insert"
+
+	// WriteModeUpsert MERGEs on order_id so redelivered orders are harmless
+	WriteModeUpsert WriteMode = "upsert"
 )
 
+const (
+	columnsPerOrder = 11
+
+	// SQL Server rejects statements with more than 2100 parameters;
+	// stay a little below to leave room for the driver
+	maxParamsPerStatement = 2000
+	chunkSize             = maxParamsPerStatement / columnsPerOrder
+)
+
+// metadata and items are stored as JSON (NVARCHAR(MAX)) columns.
+// amount is an exact DECIMAL(19,4), written from its decimal string:
+//
+//	ALTER TABLE orders ALTER COLUMN amount DECIMAL(19,4) NOT NULL;
+var orderColumnNames = []string{
+	"order_id", "user_id", "amount", "currency", "status",
+	"source", "retry_count", "created_at", "updated_at",
+	"metadata", "items",
+}
+
+// execer is satisfied by *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
 // orderRepository implements contracts.Repository
 type orderRepository struct {
-	db *sql.DB
+	db            *sql.DB
+	mode          WriteMode
+	bulkThreshold int
 }
 
-// NewOrderRepository creates a new order repository
-func NewOrderRepository(db *sql.DB) contracts.Repository {
-	return &orderRepository{db: db}
+// NewOrderRepository creates a new order repository.
+// Batches of at least bulkThreshold orders are written with bulk copy
+// instead of parameterised statements; 0 disables bulk copy.
+func NewOrderRepository(db *sql.DB, mode WriteMode, bulkThreshold int) contracts.Repository {
+	return &orderRepository{
+		db:            db,
+		mode:          mode,
+		bulkThreshold: bulkThreshold,
+	}
 }
 
-// SaveBatch inserts orders in bulk (SQL Server compatible)
+// SaveBatch writes orders in bulk (SQL Server compatible).
+// If the batch statement fails on a row's data, rows are retried one by
+// one and the failing ones are reported in a *contracts.BatchError. Any
+// other error fails the whole batch.
 func (r *orderRepository) SaveBatch(ctx context.Context, orders []*models.Order) error {
 	if len(orders) == 0 {
 		return nil
 	}
 
-	var (
-		query strings.Builder
-		args  []interface{}
-	)
+	// Unknown statuses can never satisfy the transition guard, so reject
+	// them up front rather than letting MERGE skip them silently
+	var rejected contracts.BatchError
+	accepted := make([]*models.Order, 0, len(orders))
+	positions := make([]int, 0, len(orders))
+	for i, o := range orders {
+		if !o.Status.Valid() {
+			rejected.Rows = append(rejected.Rows, contracts.RowError{
+				Index:   i,
+				OrderID: o.OrderID,
+				Err:     fmt.Errorf("%w: %w: unknown status %q", contracts.ErrPermanent, models.ErrInvalidTransition, o.Status),
+			})
+			continue
+		}
+		accepted = append(accepted, o)
+		positions = append(positions, i)
+	}
+	if len(rejected.Rows) == 0 {
+		return r.save(ctx, accepted)
+	}
+
+	err := r.save(ctx, accepted)
+	var batchErr *contracts.BatchError
+	switch {
+	case err == nil:
+	case errors.As(err, &batchErr):
+		for _, row := range batchErr.Rows {
+			row.Index = positions[row.Index]
+			rejected.Rows = append(rejected.Rows, row)
+		}
+		slices.SortFunc(rejected.Rows, func(a, b contracts.RowError) int { return a.Index - b.Index })
+	default:
+		return err
+	}
+	return &rejected
+}
+
+// save writes orders whose statuses are known
+func (r *orderRepository) save(ctx context.Context, orders []*models.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	bulk := r.bulkThreshold > 0 && len(orders) >= r.bulkThreshold
+
+	if r.mode != WriteModeUpsert {
+		var held map[string]error
+		err := r.inTx(ctx, func(tx *sql.Tx) error {
+			pending, err := pendingCancels(ctx, tx, orders)
+			if err != nil {
+				return err
+			}
+			var kept []*models.Order
+			kept, held = holdCancelled(orders, pending)
+
+			if bulk {
+				err = copyIn(ctx, tx, "orders", kept)
+			} else {
+				err = forEachChunk(kept, func(chunk []*models.Order) error {
+					return insert(ctx, tx, chunk)
+				})
+			}
+			if err != nil {
+				return err
+			}
+			if err := resolveCancels(ctx, tx, kept, pending); err != nil {
+				return err
+			}
+			return insertHistory(ctx, tx, historyOf(kept))
+		})
+		if err != nil {
+			return err
+		}
+		return rejectedRows(orders, held)
+	}
+
+	batch, superseded := latestPerOrder(orders)
+	if superseded > 0 {
+		log.Printf("ignored %d superseded duplicate orders in batch", superseded)
+	}
+
+	var rejected map[string]error
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		rejected, err = upsert(ctx, tx, orders, batch, bulk)
+		return err
+	})
+	if err == nil {
+		return rejectedRows(orders, rejected)
+	}
+	if ctx.Err() != nil || !rowLevel(err) {
+		return err
+	}
+
+	// Isolate the bad rows so they don't poison the rest of the batch.
+	// Only a row's own data can make it fail alone; losing the connection
+	// would fail every row, so that is returned as is.
+	var batchErr contracts.BatchError
+	for i, o := range orders {
+		var rejected map[string]error
+		err := r.inTx(ctx, func(tx *sql.Tx) error {
+			row := []*models.Order{o}
+			var err error
+			rejected, err = upsert(ctx, tx, row, row, false)
+			return err
+		})
+		if err == nil {
+			if rowErr, ok := rejected[o.OrderID]; ok {
+				batchErr.Rows = append(batchErr.Rows, contracts.RowError{Index: i, OrderID: o.OrderID, Err: rowErr})
+			}
+			continue
+		}
+		if !rowLevel(err) {
+			// Rows written so far are upserted again on the retry
+			return err
+		}
+		batchErr.Rows = append(batchErr.Rows, contracts.RowError{
+			Index:   i,
+			OrderID: o.OrderID,
+			Err:     classify(err),
+		})
+	}
+
+	if len(batchErr.Rows) == 0 {
+		return nil
+	}
+	return &batchErr
+}
+
+// upsert merges batch, the latest version of each order in orders, and
+// writes the history of orders in the same transaction, so every persisted
+// status has its trail. Superseded versions still contribute the
+// transitions they carry. Orders with a pending cancellation or turned
+// down by the transition guard are returned by ID and get no history.
+func upsert(ctx context.Context, tx *sql.Tx, orders, batch []*models.Order, bulk bool) (map[string]error, error) {
+	pending, err := pendingCancels(ctx, tx, batch)
+	if err != nil {
+		return nil, err
+	}
+	batch, held := holdCancelled(batch, pending)
+
+	var applied []string
+	if bulk {
+		ids, err := mergeStaged(ctx, tx, batch)
+		if err != nil {
+			return nil, err
+		}
+		applied = ids
+	} else {
+		err := forEachChunk(batch, func(chunk []*models.Order) error {
+			ids, err := merge(ctx, tx, chunk)
+			applied = append(applied, ids...)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rejected, err := rejectedTransitions(ctx, tx, batch, applied)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveCancels(ctx, tx, batch, pending); err != nil {
+		return nil, err
+	}
+	for id, err := range held {
+		if rejected == nil {
+			rejected = make(map[string]error, len(held))
+		}
+		rejected[id] = err
+	}
+
+	history := orders
+	if len(rejected) > 0 {
+		history = slices.DeleteFunc(slices.Clone(orders), func(o *models.Order) bool {
+			_, ok := rejected[o.OrderID]
+			return ok
+		})
+	}
+	return rejected, insertHistory(ctx, tx, historyOf(history))
+}
+
+// rejectedTransitions finds the orders MERGE left untouched whose stored
+// status differs from theirs. Those were illegal transitions, such as
+// CANCELLED to COMPLETED, rather than stale redeliveries of a status the
+// row already holds.
+func rejectedTransitions(ctx context.Context, tx *sql.Tx, batch []*models.Order, applied []string) (map[string]error, error) {
+	written := make(map[string]bool, len(applied))
+	for _, id := range applied {
+		written[id] = true
+	}
+
+	var skipped []*models.Order
+	for _, o := range batch {
+		if !written[o.OrderID] {
+			skipped = append(skipped, o)
+		}
+	}
+	if len(skipped) == 0 {
+		return nil, nil
+	}
+
+	stored := make(map[string]models.OrderStatus, len(skipped))
+	for start := 0; start < len(skipped); start += maxParamsPerStatement {
+		chunk := skipped[start:min(start+maxParamsPerStatement, len(skipped))]
+		args := make([]interface{}, len(chunk))
+		for i, o := range chunk {
+			args[i] = o.OrderID
+		}
+
+		rows, err := tx.QueryContext(ctx,
+			`SELECT order_id, status FROM orders WHERE order_id IN `+placeholders(1, len(chunk)), args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id string
+			var status models.OrderStatus
+			if err := rows.Scan(&id, &status); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			stored[id] = status
+		}
+		if err := rows.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	rejected := make(map[string]error)
+	for _, o := range skipped {
+		if status, ok := stored[o.OrderID]; ok && status != o.Status {
+			rejected[o.OrderID] = fmt.Errorf("%w: %w: stored order is %s, cannot become %s",
+				contracts.ErrPermanent, models.ErrInvalidTransition, status, o.Status)
+		}
+	}
+	return rejected, nil
+}
+
+// rejectedRows reports every version of a rejected order in a
+// *contracts.BatchError, or returns nil when nothing was rejected
+func rejectedRows(orders []*models.Order, rejected map[string]error) error {
+	if len(rejected) == 0 {
+		return nil
+	}
+
+	var batchErr contracts.BatchError
+	for i, o := range orders {
+		if err, ok := rejected[o.OrderID]; ok {
+			batchErr.Rows = append(batchErr.Rows, contracts.RowError{Index: i, OrderID: o.OrderID, Err: err})
+		}
+	}
+	return &batchErr
+}
+
+// permanentErrors are SQL Server errors caused by the row itself, which no
+// amount of retrying will fix
+var permanentErrors = map[int32]bool{
+	245:  true, // conversion failed
+	515:  true, // NULL into NOT NULL column
+	547:  true, // constraint violation
+	2628: true, // string or binary data would be truncated
+	8114: true, // error converting data type
+	8115: true, // arithmetic overflow
+	8152: true, // string or binary data would be truncated
+}
+
+// rowLevel reports whether err was caused by the data of a row rather than
+// by the connection or the server
+func rowLevel(err error) bool {
+	var sqlErr mssql.Error
+	return errors.As(err, &sqlErr) && permanentErrors[sqlErr.Number]
+}
+
+// classify wraps row-level SQL errors that will never succeed with
+// contracts.ErrPermanent so they skip the retry topics
+func classify(err error) error {
+	if rowLevel(err) {
+		return fmt.Errorf("%w: %v", contracts.ErrPermanent, err)
+	}
+	return err
+}
+
+// inTx runs fn in a transaction so a chunked batch is written atomically
+func (r *orderRepository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// forEachChunk splits orders into parameter-safe chunks
+func forEachChunk(orders []*models.Order, fn func(chunk []*models.Order) error) error {
+	for start := 0; start < len(orders); start += chunkSize {
+		end := min(start+chunkSize, len(orders))
+		if err := fn(orders[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insert performs a plain multi-row INSERT
+func insert(ctx context.Context, db execer, orders []*models.Order) error {
+	var query strings.Builder
 
 	query.WriteString(`
 		INSERT INTO orders (
 			order_id, user_id, amount, currency, status,
-			source, retry_count, created_at, updated_at
+			source, retry_count, created_at, updated_at,
+			metadata, items
 		) VALUES 
 	`)
+	query.WriteString(placeholders(len(orders), columnsPerOrder))
+
+	args, err := orderArgs(orders)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, query.String(), args...)
+	return err
+}
+
+// merge upserts orders keyed on order_id and returns the IDs it wrote. An
+// existing row is only updated when the incoming order is newer and its
+// status is a legal transition from the stored one, so stale or
+// redelivered messages are ignored instead of failing the batch and the
+// status never moves backwards.
+func merge(ctx context.Context, db queryer, orders []*models.Order) ([]string, error) {
+	source := `(VALUES ` + placeholders(len(orders), columnsPerOrder) + `) AS source (
+			order_id, user_id, amount, currency, status,
+			source, retry_count, created_at, updated_at,
+			metadata, items
+		)`
+
+	args, err := orderArgs(orders)
+	if err != nil {
+		return nil, err
+	}
+
+	return queryIDs(ctx, db, mergeInto(source), args...)
+}
+
+// mergeStaged bulk copies orders into a temporary table and merges from it,
+// avoiding the parameter limit altogether
+func mergeStaged(ctx context.Context, tx *sql.Tx, orders []*models.Order) ([]string, error) {
+	if _, err := tx.ExecContext(ctx,
+		`SELECT TOP 0 `+strings.Join(orderColumnNames, ", ")+` INTO #orders_staging FROM orders`,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := copyIn(ctx, tx, "#orders_staging", orders); err != nil {
+		return nil, err
+	}
+
+	ids, err := queryIDs(ctx, tx, mergeInto(`#orders_staging AS source`))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DROP TABLE #orders_staging`)
+	return ids, err
+}
+
+// queryIDs runs a statement that outputs one order ID per row
+func queryIDs(ctx context.Context, db queryer, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// copyIn streams orders into table using the TDS bulk load protocol
+func copyIn(ctx context.Context, tx *sql.Tx, table string, orders []*models.Order) error {
+	stmt, err := tx.PrepareContext(ctx, mssql.CopyIn(table, mssql.BulkOptions{}, orderColumnNames...))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, o := range orders {
+		metadata, items, err := encodeDetails(o)
+		if err != nil {
+			return err
+		}
+
+		if _, err := stmt.ExecContext(ctx,
+			o.OrderID,
+			o.UserID,
+			o.Amount,
+			o.Currency,
+			string(o.Status),
+			o.Source,
+			o.RetryCount,
+			o.CreatedAt,
+			o.UpdatedAt,
+			metadata,
+			items,
+		); err != nil {
+			return err
+		}
+	}
+
+	// An Exec without arguments flushes the buffered rows
+	_, err = stmt.ExecContext(ctx)
+	return err
+}
+
+// mergeInto builds the MERGE statement for the given source clause. It
+// outputs the ID of every inserted or updated row.
+func mergeInto(source string) string {
+	return `
+		MERGE orders WITH (HOLDLOCK) AS target
+		USING ` + source + `
+		ON target.order_id = source.order_id
+		WHEN MATCHED AND source.updated_at > target.updated_at AND (
+			` + transitionGuard("target.status", "source.status") + `
+		) THEN UPDATE SET
+			user_id     = source.user_id,
+			amount      = source.amount,
+			currency    = source.currency,
+			status      = source.status,
+			source      = source.source,
+			retry_count = source.retry_count,
+			updated_at  = source.updated_at,
+			metadata    = source.metadata,
+			items       = source.items
+		WHEN NOT MATCHED THEN INSERT (
+			order_id, user_id, amount, currency, status,
+			source, retry_count, created_at, updated_at,
+			metadata, items
+		) VALUES (
+			source.order_id, source.user_id, source.amount, source.currency, source.status,
+			source.source, source.retry_count, source.created_at, source.updated_at,
+			source.metadata, source.items
+		)
+		OUTPUT inserted.order_id;`
+}
+
+// transitionGuard renders the order state machine as a SQL predicate that
+// holds when the status may move from the from column to the to column.
+// Rewriting the same status is allowed so newer details still land.
+func transitionGuard(from, to string) string {
+	allowed := models.Transitions()
+
+	statuses := make([]models.OrderStatus, 0, len(allowed))
+	for status := range allowed {
+		statuses = append(statuses, status)
+	}
+	slices.Sort(statuses)
+
+	clauses := []string{to + " = " + from}
+	for _, status := range statuses {
+		next := allowed[status]
+		if len(next) == 0 {
+			continue
+		}
+		quoted := make([]string, len(next))
+		for i, n := range next {
+			quoted[i] = "'" + string(n) + "'"
+		}
+		clauses = append(clauses, fmt.Sprintf("(%s = '%s' AND %s IN (%s))", from, status, to, strings.Join(quoted, ", ")))
+	}
+
+	return strings.Join(clauses, "\n\t\t\tOR ")
+}
+
+// latestPerOrder keeps only the most recent version of each order, since a
+// MERGE source may not contain the same key twice
+func latestPerOrder(orders []*models.Order) ([]*models.Order, int) {
+	latest := make(map[string]int, len(orders))
+	result := make([]*models.Order, 0, len(orders))
+
+	for _, o := range orders {
+		i, seen := latest[o.OrderID]
+		if !seen {
+			latest[o.OrderID] = len(result)
+			result = append(result, o)
+			continue
+		}
+		if o.UpdatedAt.After(result[i].UpdatedAt) {
+			result[i] = o
+		}
+	}
+
+	return result, len(orders) - len(result)
+}
+
+// placeholders returns "(@p1,...,@pN),(@pN+1,...)" for rows of columns values
+func placeholders(rows, columns int) string {
+	var b strings.Builder
+
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("(")
+		for c := 1; c <= columns; c++ {
+			if c > 1 {
+				b.WriteString(",")
+			}
+			fmt.Fprintf(&b, "@p%d", i*columns+c)
+		}
+		b.WriteString(")")
+	}
+
+	return b.String()
+}
+
+func orderArgs(orders []*models.Order) ([]interface{}, error) {
+	args := make([]interface{}, 0, len(orders)*columnsPerOrder)
 
 	for _, o := range orders {
-		query.WriteString("(?,?,?,?,?,?,?,?,?),")
+		metadata, items, err := encodeDetails(o)
+		if err != nil {
+			return nil, err
+		}
 
 		args = append(args,
 			o.OrderID,
@@ -49,12 +630,78 @@ func (r *orderRepository) SaveBatch(ctx context.Context, orders []*models.Order)
 			o.RetryCount,
 			o.CreatedAt,
 			o.UpdatedAt,
+			metadata,
+			items,
 		)
 	}
 
-	// Remove trailing comma
-	sqlQuery := strings.TrimSuffix(query.String(), ",")
+	return args, nil
+}
+
+// scanner is satisfied by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
 
-	_, err := r.db.ExecContext(ctx, sqlQuery, args...)
-	return err
+// scanOrder reads a row of orderColumnNames
+func scanOrder(s scanner) (*models.Order, error) {
+	var (
+		o        models.Order
+		metadata sql.NullString
+		items    sql.NullString
+	)
+
+	err := s.Scan(
+		&o.OrderID,
+		&o.UserID,
+		&o.Amount,
+		&o.Currency,
+		&o.Status,
+		&o.Source,
+		&o.RetryCount,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+		&metadata,
+		&items,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata.Valid {
+		if err := json.Unmarshal([]byte(metadata.String), &o.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	if items.Valid {
+		if err := json.Unmarshal([]byte(items.String), &o.Items); err != nil {
+			return nil, err
+		}
+	}
+
+	o.CreatedAt = o.CreatedAt.UTC()
+	o.UpdatedAt = o.UpdatedAt.UTC()
+	return &o, nil
+}
+
+// encodeDetails serialises metadata and line items for their JSON columns.
+// Empty values are stored as NULL.
+func encodeDetails(o *models.Order) (metadata, items sql.NullString, err error) {
+	if len(o.Metadata) > 0 {
+		b, err := json.Marshal(o.Metadata)
+		if err != nil {
+			return metadata, items, err
+		}
+		metadata = sql.NullString{String: string(b), Valid: true}
+	}
+
+	if len(o.Items) > 0 {
+		b, err := json.Marshal(o.Items)
+		if err != nil {
+			return metadata, items, err
+		}
+		items = sql.NullString{String: string(b), Valid: true}
+	}
+
+	return metadata, items, nil
 }
@@ -7,11 +7,24 @@ import (
 	"time"
 
 	"OrderSystemHighConcurrency/order-processor/internal/contracts"
+	sharedkafka "OrderSystemHighConcurrency/shared/kafka"
 	"OrderSystemHighConcurrency/shared/models"
 
 	"github.com/IBM/sarama"
 )
 
+// producerName is recorded as the producer of DLQ events
+const producerName = "order-processor"
+
+// envelope is the payload of an ORDER_DEAD_LETTERED event. Messages that
+// could not be read as an order carry the message instead.
+type envelope struct {
+	Order   *models.Order         `json:"order"`
+	Message *contracts.RawMessage `json:"message,omitempty"`
+	Reason  string                `json:"reason"`
+	Time    time.Time             `json:"time"`
+}
+
 type dlqProducer struct {
 	producer sarama.SyncProducer
 	topic    string
@@ -51,15 +64,35 @@ func (d *dlqProducer) Publish(
 		return errors.New("order is nil")
 	}
 
-	payload, err := json.Marshal(struct {
-		Order  *models.Order `json:"order"`
-		Reason string        `json:"reason"`
-		Time   time.Time     `json:"time"`
-	}{
+	return d.send(ctx, order.OrderID, envelope{
 		Order:  order,
 		Reason: reason,
 		Time:   time.Now().UTC(),
 	})
+}
+
+// PublishMessage sends a message that can't be read as an order to the DLQ.
+// Messages are keyed by order ID, so the key stands in for it.
+func (d *dlqProducer) PublishMessage(ctx context.Context, msg *contracts.RawMessage, reason string) error {
+	if msg == nil {
+		return errors.New("message is nil")
+	}
+
+	return d.send(ctx, string(msg.Key), envelope{
+		Message: msg,
+		Reason:  reason,
+		Time:    time.Now().UTC(),
+	})
+}
+
+// send wraps env in an ORDER_DEAD_LETTERED event and publishes it
+func (d *dlqProducer) send(ctx context.Context, orderID string, env envelope) error {
+	event, err := models.NewOrderEvent(models.EventOrderDeadLettered, producerName, orderID, env)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
 	if err != nil {
 		return err
 	}
@@ -67,6 +100,9 @@ func (d *dlqProducer) Publish(
 	msg := &sarama.ProducerMessage{
 		Topic: d.topic,
 		Value: sarama.ByteEncoder(payload),
+		Headers: []sarama.RecordHeader{
+			sharedkafka.ContentTypeHeader(sharedkafka.EncodingJSON),
+		},
 	}
 
 	select {
@@ -0,0 +1,164 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"OrderSystemHighConcurrency/order-processor/internal/contracts"
+	"OrderSystemHighConcurrency/shared/models"
+
+	"github.com/IBM/sarama"
+)
+
+// defaultIdleTimeout is how long a partition may stay silent before
+// everything it has is considered read
+const defaultIdleTimeout = 10 * time.Second
+
+// dlqReader implements contracts.DLQReader with a plain partition consumer,
+// so reading never commits offsets or joins a consumer group
+type dlqReader struct {
+	client   sarama.Client
+	consumer sarama.Consumer
+	topic    string
+	idle     time.Duration
+}
+
+// NewDLQReader creates a reader for the DLQ topic
+func NewDLQReader(brokers []string, topic string) (contracts.DLQReader, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("brokers required")
+	}
+
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.V2_8_0_0
+	cfg.Consumer.Return.Errors = true
+
+	client, err := sarama.NewClient(brokers, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &dlqReader{
+		client:   client,
+		consumer: consumer,
+		topic:    topic,
+		idle:     defaultIdleTimeout,
+	}, nil
+}
+
+// ReadAll reads every partition from the oldest offset up to the
+// high-water mark observed when the call started
+func (r *dlqReader) ReadAll(ctx context.Context) ([]*contracts.DLQEntry, error) {
+	partitions, err := r.client.Partitions(r.topic)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*contracts.DLQEntry
+	for _, partition := range partitions {
+		part, err := r.readPartition(ctx, partition)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, part...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return entries, nil
+}
+
+func (r *dlqReader) readPartition(ctx context.Context, partition int32) ([]*contracts.DLQEntry, error) {
+	oldest, err := r.client.GetOffset(r.topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		return nil, err
+	}
+	newest, err := r.client.GetOffset(r.topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return nil, err
+	}
+	if oldest >= newest {
+		return nil, nil
+	}
+
+	pc, err := r.consumer.ConsumePartition(r.topic, partition, oldest)
+	if err != nil {
+		return nil, err
+	}
+	defer pc.Close()
+
+	// Compaction and transaction markers leave gaps, so the offset just
+	// below newest may never be delivered. A silent partition has nothing
+	// more to give.
+	idle := time.NewTimer(r.idle)
+	defer idle.Stop()
+
+	var entries []*contracts.DLQEntry
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case err := <-pc.Errors():
+			return nil, err
+
+		case msg := <-pc.Messages():
+			env, err := decodeEnvelope(msg.Value)
+			if err != nil || env.Order == nil {
+				// Messages dead-lettered before they could be read as an
+				// order stay on the topic for inspection
+				log.Printf("skipping DLQ message without an order at %d/%d", partition, msg.Offset)
+			} else {
+				entries = append(entries, &contracts.DLQEntry{
+					Order:     env.Order,
+					Reason:    env.Reason,
+					Time:      env.Time,
+					Partition: partition,
+					Offset:    msg.Offset,
+				})
+			}
+
+			if msg.Offset+1 >= newest || msg.Offset+1 >= pc.HighWaterMarkOffset() {
+				return entries, nil
+			}
+			idle.Reset(r.idle)
+
+		case <-idle.C:
+			log.Printf("DLQ partition %d idle after %d entries, stopping below offset %d", partition, len(entries), newest)
+			return entries, nil
+		}
+	}
+}
+
+// decodeEnvelope unwraps the DLQ envelope from its event. Messages written
+// before events existed are the bare envelope and decode the same way.
+func decodeEnvelope(data []byte) (*envelope, error) {
+	event, err := models.DecodeOrderEvent(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var env envelope
+	if err := json.Unmarshal(event.Payload, &env); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+func (r *dlqReader) Close() error {
+	if err := r.consumer.Close(); err != nil {
+		return err
+	}
+	return r.client.Close()
+}
@@ -0,0 +1,143 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"OrderSystemHighConcurrency/order-processor/internal/contracts"
+	"OrderSystemHighConcurrency/shared/models"
+
+	"github.com/IBM/sarama"
+)
+
+const testTopic = "orders-dlq"
+
+// deadLettered encodes an order the way dlqProducer does
+func deadLettered(t *testing.T, orderID, reason string, at time.Time) sarama.Encoder {
+	t.Helper()
+
+	order := &models.Order{OrderID: orderID, UserID: "user-1", Status: models.OrderStatusFailed, RetryCount: 3}
+	event, err := models.NewOrderEvent(models.EventOrderDeadLettered, producerName, orderID, envelope{
+		Order:  order,
+		Reason: reason,
+		Time:   at,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sarama.ByteEncoder(payload)
+}
+
+// newTestReader serves one DLQ partition holding offsets [oldest, newest)
+// from a mock broker. fetch lists the messages the broker returns.
+func newTestReader(t *testing.T, oldest, newest int64, fetch *sarama.MockFetchResponse) *dlqReader {
+	t.Helper()
+
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(testTopic, 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset(testTopic, 0, sarama.OffsetOldest, oldest).
+			SetOffset(testTopic, 0, sarama.OffsetNewest, newest),
+		"FetchRequest": fetch.SetHighWaterMark(testTopic, 0, newest),
+	})
+
+	reader, err := NewDLQReader([]string{broker.Addr()}, testTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { reader.Close() })
+
+	r := reader.(*dlqReader)
+	r.idle = 200 * time.Millisecond
+	return r
+}
+
+func TestReadAllStopsAtHighWaterMark(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	fetch := sarama.NewMockFetchResponse(t, 1).
+		SetMessage(testTopic, 0, 0, deadLettered(t, "order-1", "amount mismatch", at)).
+		SetMessage(testTopic, 0, 1, sarama.StringEncoder("not an event")).
+		SetMessage(testTopic, 0, 2, deadLettered(t, "order-2", "db down", at.Add(time.Minute)))
+
+	reader := newTestReader(t, 0, 3, fetch)
+
+	start := time.Now()
+	entries, err := reader.ReadAll(context.Background())
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= reader.idle {
+		t.Errorf("ReadAll waited %s for the idle timeout instead of stopping at the last offset", elapsed)
+	}
+
+	want := []struct {
+		orderID string
+		reason  string
+		offset  int64
+	}{
+		{"order-1", "amount mismatch", 0},
+		{"order-2", "db down", 2},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Order.OrderID != w.orderID || e.Reason != w.reason || e.Offset != w.offset || e.Partition != 0 {
+			t.Errorf("entry %d = %s %q at %d/%d, want %s %q at 0/%d",
+				i, e.Order.OrderID, e.Reason, e.Partition, e.Offset, w.orderID, w.reason, w.offset)
+		}
+	}
+}
+
+func TestReadAllToleratesOffsetGaps(t *testing.T) {
+	// Offsets 2 and 3 are transaction markers that are never delivered
+	fetch := sarama.NewMockFetchResponse(t, 1).
+		SetMessage(testTopic, 0, 0, deadLettered(t, "order-1", "timeout", time.Now())).
+		SetMessage(testTopic, 0, 1, deadLettered(t, "order-2", "timeout", time.Now()))
+
+	reader := newTestReader(t, 0, 4, fetch)
+
+	entries, err := reader.ReadAll(context.Background())
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+}
+
+func TestReadAllEmptyPartition(t *testing.T) {
+	reader := newTestReader(t, 5, 5, sarama.NewMockFetchResponse(t, 1))
+
+	entries, err := reader.ReadAll(context.Background())
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("got %d entries from an empty partition", len(entries))
+	}
+}
+
+// entryFor builds a DLQ entry as ReadAll returns it
+func entryFor(orderID string, status models.OrderStatus) *contracts.DLQEntry {
+	return &contracts.DLQEntry{
+		Order:     &models.Order{OrderID: orderID, UserID: "user-1", Status: status, RetryCount: 3},
+		Reason:    "db down",
+		Time:      time.Now().UTC(),
+		Partition: 0,
+		Offset:    7,
+	}
+}
@@ -0,0 +1,137 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"OrderSystemHighConcurrency/order-processor/internal/contracts"
+	sharedkafka "OrderSystemHighConcurrency/shared/kafka"
+	"OrderSystemHighConcurrency/shared/models"
+
+	"github.com/IBM/sarama"
+)
+
+// replayAudit is the value of the replay-audit header
+type replayAudit struct {
+	SourceTopic string    `json:"source_topic"`
+	Partition   int32     `json:"partition"`
+	Offset      int64     `json:"offset"`
+	Reason      string    `json:"reason"`
+	RetryCount  int       `json:"retry_count"`
+	ReplayedAt  time.Time `json:"replayed_at"`
+	ReplayedBy  string    `json:"replayed_by,omitempty"`
+}
+
+type dlqReplayer struct {
+	producer    sarama.SyncProducer
+	keyStrategy sharedkafka.KeyStrategy
+	sourceTopic string
+	targetTopic string
+	operator    string
+}
+
+// NewDLQReplayer creates a replayer from sourceTopic (the DLQ) to targetTopic.
+// Orders are keyed and partitioned like order-api's events, so they rejoin
+// their user's partition. operator is recorded in the audit header.
+func NewDLQReplayer(
+	brokers []string,
+	keyStrategy sharedkafka.KeyStrategy,
+	partitioner sharedkafka.Partitioner,
+	sourceTopic, targetTopic, operator string,
+) (contracts.DLQReplayer, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("brokers required")
+	}
+
+	keyStrategy, err := sharedkafka.ParseKeyStrategy(string(keyStrategy))
+	if err != nil {
+		return nil, err
+	}
+	partitionerFor, err := sharedkafka.NewPartitioner(partitioner)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.V2_8_0_0
+	cfg.Producer.RequiredAcks = sarama.WaitForAll
+	cfg.Producer.Retry.Max = 5
+	cfg.Producer.Return.Successes = true
+	cfg.Producer.Timeout = 5 * time.Second
+	cfg.Producer.Partitioner = partitionerFor
+
+	producer, err := sarama.NewSyncProducer(brokers, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dlqReplayer{
+		producer:    producer,
+		keyStrategy: keyStrategy,
+		sourceTopic: sourceTopic,
+		targetTopic: targetTopic,
+		operator:    operator,
+	}, nil
+}
+
+// Replay republishes the order with RetryCount reset
+func (d *dlqReplayer) Replay(ctx context.Context, entry *contracts.DLQEntry) error {
+	if entry == nil || entry.Order == nil {
+		return errors.New("entry has no order")
+	}
+
+	audit, err := json.Marshal(replayAudit{
+		SourceTopic: d.sourceTopic,
+		Partition:   entry.Partition,
+		Offset:      entry.Offset,
+		Reason:      entry.Reason,
+		RetryCount:  entry.Order.RetryCount,
+		ReplayedAt:  time.Now().UTC(),
+		ReplayedBy:  d.operator,
+	})
+	if err != nil {
+		return err
+	}
+
+	order := *entry.Order
+	order.RetryCount = 0
+	order.Transitions = slices.Clone(entry.Order.Transitions)
+
+	// Orders dead-lettered before statuses were enforced are left to the
+	// processor to admit
+	if order.Status == models.OrderStatusFailed {
+		if err := order.TransitionTo(models.OrderStatusQueued, "replayed from DLQ by "+d.operator); err != nil {
+			return err
+		}
+	}
+
+	payload, err := sharedkafka.EncodeOrderEvent(sharedkafka.EncodingJSON, models.EventOrderCreated, "dlqctl", &order)
+	if err != nil {
+		return err
+	}
+
+	msg := &sarama.ProducerMessage{
+		Topic: d.targetTopic,
+		Key:   sarama.StringEncoder(sharedkafka.MessageKey(d.keyStrategy, &order)),
+		Value: sarama.ByteEncoder(payload),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte(sharedkafka.HeaderReplayAudit), Value: audit},
+			sharedkafka.ContentTypeHeader(sharedkafka.EncodingJSON),
+		},
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		_, _, err := d.producer.SendMessage(msg)
+		return err
+	}
+}
+
+func (d *dlqReplayer) Close() error {
+	return d.producer.Close()
+}
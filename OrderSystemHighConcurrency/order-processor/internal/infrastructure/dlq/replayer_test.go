@@ -0,0 +1,102 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"OrderSystemHighConcurrency/order-processor/internal/contracts"
+	sharedkafka "OrderSystemHighConcurrency/shared/kafka"
+	"OrderSystemHighConcurrency/shared/models"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+)
+
+func TestReplayPublishesToMockBroker(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("orders", 0, broker.BrokerID()),
+		"ProduceRequest": sarama.NewMockProduceResponse(t).
+			SetError("orders", 0, sarama.ErrNoError),
+	})
+
+	replayer, err := NewDLQReplayer([]string{broker.Addr()}, sharedkafka.KeyByUser, sharedkafka.PartitionerMurmur2, testTopic, "orders", "ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replayer.Close()
+
+	if err := replayer.Replay(context.Background(), entryFor("order-1", models.OrderStatusFailed)); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+}
+
+func TestReplayResetsRetriesAndAddsAudit(t *testing.T) {
+	producer := mocks.NewSyncProducer(t, nil)
+	defer producer.Close()
+
+	var sent *sarama.ProducerMessage
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		sent = msg
+		return nil
+	})
+
+	replayer := &dlqReplayer{producer: producer, keyStrategy: sharedkafka.KeyByUser, sourceTopic: testTopic, targetTopic: "orders", operator: "ops"}
+	entry := entryFor("order-1", models.OrderStatusFailed)
+
+	if err := replayer.Replay(context.Background(), entry); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+
+	if sent.Topic != "orders" {
+		t.Errorf("topic = %s, want orders", sent.Topic)
+	}
+	// Keyed like order-api's events, so the replay joins the user's partition
+	if key, _ := sent.Key.Encode(); string(key) != "user-1" {
+		t.Errorf("key = %s, want user-1", key)
+	}
+
+	value, _ := sent.Value.Encode()
+	event, err := models.DecodeOrderEvent(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err := event.Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.RetryCount != 0 {
+		t.Errorf("retry count = %d, want 0", order.RetryCount)
+	}
+	if order.Status != models.OrderStatusQueued {
+		t.Errorf("status = %s, want %s", order.Status, models.OrderStatusQueued)
+	}
+	if entry.Order.RetryCount != 3 {
+		t.Error("Replay modified the DLQ entry")
+	}
+
+	var audit replayAudit
+	for _, h := range sent.Headers {
+		if string(h.Key) == sharedkafka.HeaderReplayAudit {
+			if err := json.Unmarshal(h.Value, &audit); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if audit.SourceTopic != testTopic || audit.Offset != 7 || audit.RetryCount != 3 || audit.ReplayedBy != "ops" {
+		t.Errorf("audit header = %+v", audit)
+	}
+}
+
+func TestReplayRejectsEmptyEntry(t *testing.T) {
+	replayer := &dlqReplayer{producer: mocks.NewSyncProducer(t, nil)}
+	if err := replayer.Replay(context.Background(), &contracts.DLQEntry{}); err == nil {
+		t.Fatal("Replay accepted an entry without an order")
+	}
+}
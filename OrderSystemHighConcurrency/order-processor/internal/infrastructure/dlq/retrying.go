@@ -0,0 +1,78 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"OrderSystemHighConcurrency/order-processor/internal/contracts"
+	"OrderSystemHighConcurrency/shared/metrics"
+	"OrderSystemHighConcurrency/shared/models"
+)
+
+// DLQ publishes are retried with a backoff growing from retryBase to
+// retryMax. A message that is neither handled nor dead-lettered can't be
+// acknowledged, so giving up would leave the partition's offset stuck.
+const (
+	retryBase = 200 * time.Millisecond
+	retryMax  = 30 * time.Second
+)
+
+// retryingPublisher implements contracts.DLQPublisher on top of another
+// publisher, retrying every publish until it succeeds
+type retryingPublisher struct {
+	publisher contracts.DLQPublisher
+	base      time.Duration
+	max       time.Duration
+}
+
+// NewRetryingPublisher wraps publisher so publishes are retried until they
+// succeed. They only give up when ctx ends, e.g. on shutdown, returning the
+// last error; the source message is then redelivered.
+func NewRetryingPublisher(publisher contracts.DLQPublisher) contracts.DLQPublisher {
+	return &retryingPublisher{
+		publisher: publisher,
+		base:      retryBase,
+		max:       retryMax,
+	}
+}
+
+func (r *retryingPublisher) Publish(ctx context.Context, order *models.Order, reason string) error {
+	if order == nil {
+		return errors.New("order is nil")
+	}
+	return r.retry(ctx, "order "+order.OrderID, func() error {
+		return r.publisher.Publish(ctx, order, reason)
+	})
+}
+
+func (r *retryingPublisher) PublishMessage(ctx context.Context, msg *contracts.RawMessage, reason string) error {
+	if msg == nil {
+		return errors.New("message is nil")
+	}
+	return r.retry(ctx, fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Partition, msg.Offset), func() error {
+		return r.publisher.PublishMessage(ctx, msg, reason)
+	})
+}
+
+func (r *retryingPublisher) retry(ctx context.Context, what string, publish func() error) error {
+	delay := r.base
+	for attempt := 1; ; attempt++ {
+		err := publish()
+		if err == nil {
+			return nil
+		}
+
+		metrics.IncrementCounter("order_processor_dlq_publish_failures_total")
+		log.Printf("DLQ publish of %s failed (attempt %d), retrying in %s: %v", what, attempt, delay, err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, r.max)
+	}
+}
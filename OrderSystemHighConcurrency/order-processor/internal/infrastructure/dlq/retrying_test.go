@@ -0,0 +1,68 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"OrderSystemHighConcurrency/order-processor/internal/contracts"
+	"OrderSystemHighConcurrency/shared/models"
+)
+
+// flakyPublisher fails the first failures calls
+type flakyPublisher struct {
+	failures int
+	calls    int
+}
+
+func (f *flakyPublisher) publish() error {
+	f.calls++
+	if f.calls <= f.failures {
+		return errors.New("broker down")
+	}
+	return nil
+}
+
+func (f *flakyPublisher) Publish(context.Context, *models.Order, string) error {
+	return f.publish()
+}
+
+func (f *flakyPublisher) PublishMessage(context.Context, *contracts.RawMessage, string) error {
+	return f.publish()
+}
+
+func newTestRetrying(failures int) (*retryingPublisher, *flakyPublisher) {
+	flaky := &flakyPublisher{failures: failures}
+	return &retryingPublisher{publisher: flaky, base: time.Millisecond, max: 4 * time.Millisecond}, flaky
+}
+
+func TestRetryingPublisherRetriesUntilPublished(t *testing.T) {
+	r, flaky := newTestRetrying(5)
+
+	if err := r.Publish(context.Background(), &models.Order{OrderID: "order-1"}, "db down"); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if flaky.calls != 6 {
+		t.Errorf("published %d times, want 6", flaky.calls)
+	}
+
+	r, flaky = newTestRetrying(2)
+	if err := r.PublishMessage(context.Background(), &contracts.RawMessage{Topic: "orders"}, "unreadable"); err != nil {
+		t.Fatalf("PublishMessage: %v", err)
+	}
+	if flaky.calls != 3 {
+		t.Errorf("published %d times, want 3", flaky.calls)
+	}
+}
+
+func TestRetryingPublisherGivesUpWhenContextEnds(t *testing.T) {
+	r, _ := newTestRetrying(1 << 30)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := r.Publish(ctx, &models.Order{OrderID: "order-1"}, "db down"); err == nil {
+		t.Fatal("Publish succeeded while the DLQ was down")
+	}
+}
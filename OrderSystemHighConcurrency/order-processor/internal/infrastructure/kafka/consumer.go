@@ -3,10 +3,16 @@ package kafka
 import (
 	"OrderSystemHighConcurrency/order-processor/internal/contracts"
 	"OrderSystemHighConcurrency/order-processor/internal/worker"
+	sharedkafka "OrderSystemHighConcurrency/shared/kafka"
+	"OrderSystemHighConcurrency/shared/metrics"
 	"OrderSystemHighConcurrency/shared/models"
+	"OrderSystemHighConcurrency/shared/schema"
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/IBM/sarama"
 )
@@ -14,16 +20,35 @@ import (
 // orderConsumer implements contracts.Consumer
 type orderConsumer struct {
 	consumerGroup sarama.ConsumerGroup
-	topic         string
+	topics        []string
 	workerPool    *worker.WorkerPool
+	drainer       contracts.Drainer
+	drainTimeout  time.Duration
+	schemas       *schema.Resolver
+	dlq           contracts.DLQPublisher
+	retries       contracts.RetryPublisher
+	holds         map[string]time.Duration
 }
 
-// NewOrderConsumer creates a new Kafka consumer
+// NewOrderConsumer creates a new Kafka consumer.
+// When a session ends (shutdown or rebalance) each claim stops reading, uses
+// drainer to finish in-flight orders and waits up to drainTimeout for their
+// offsets to be marked before the session commits.
+// schemas may be nil when no schema registry is configured; messages it
+// finds unreadable are sent to dlq.
+// holds is the longest a message of each retry topic may wait at the head of
+// its partition; one that is still not due then is requeued through retries.
 func NewOrderConsumer(
 	brokers []string,
 	groupID string,
-	topic string,
+	topics []string,
 	workerPool *worker.WorkerPool,
+	drainer contracts.Drainer,
+	drainTimeout time.Duration,
+	schemas *schema.Resolver,
+	dlq contracts.DLQPublisher,
+	retries contracts.RetryPublisher,
+	holds map[string]time.Duration,
 ) (contracts.Consumer, error) {
 
 	config := sarama.NewConfig()
@@ -39,19 +64,31 @@ func NewOrderConsumer(
 
 	return &orderConsumer{
 		consumerGroup: cg,
-		topic:         topic,
+		topics:        topics,
 		workerPool:    workerPool,
+		drainer:       drainer,
+		drainTimeout:  drainTimeout,
+		schemas:       schemas,
+		dlq:           dlq,
+		retries:       retries,
+		holds:         holds,
 	}, nil
 }
 
 // Start begins consuming Kafka messages
 func (c *orderConsumer) Start(ctx context.Context) error {
 	handler := &consumerHandler{
-		workerPool: c.workerPool,
+		workerPool:   c.workerPool,
+		drainer:      c.drainer,
+		drainTimeout: c.drainTimeout,
+		schemas:      c.schemas,
+		dlq:          c.dlq,
+		retries:      c.retries,
+		holds:        c.holds,
 	}
 
 	for {
-		if err := c.consumerGroup.Consume(ctx, []string{c.topic}, handler); err != nil {
+		if err := c.consumerGroup.Consume(ctx, c.topics, handler); err != nil {
 			log.Printf("kafka consume error: %v", err)
 		}
 
@@ -67,7 +104,13 @@ func (c *orderConsumer) Close() error {
 }
 
 type consumerHandler struct {
-	workerPool *worker.WorkerPool
+	workerPool   *worker.WorkerPool
+	drainer      contracts.Drainer
+	drainTimeout time.Duration
+	schemas      *schema.Resolver
+	dlq          contracts.DLQPublisher
+	retries      contracts.RetryPublisher
+	holds        map[string]time.Duration
 }
 
 func (h *consumerHandler) Setup(sarama.ConsumerGroupSession) error {
@@ -83,21 +126,286 @@ func (h *consumerHandler) ConsumeClaim(
 	claim sarama.ConsumerGroupClaim,
 ) error {
 
-	for msg := range claim.Messages() {
-		var order models.Order
+	tracker := newOffsetTracker()
 
-		if err := json.Unmarshal(msg.Value, &order); err != nil {
-			log.Printf("failed to unmarshal order: %v", err)
-			session.MarkMessage(msg, "")
-			continue
+	for {
+		select {
+		case <-session.Context().Done():
+			h.drain(claim, tracker)
+			return nil
+
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				h.drain(claim, tracker)
+				return nil
+			}
+
+			// Retried orders carry a not-before time. Attempts past the
+			// last tier wait longer than the rest of their topic, so a
+			// message is only held for its topic's delay; one still not
+			// due then goes to the back of the topic instead of stalling
+			// the messages behind it.
+			due, ok := h.hold(session.Context(), msg)
+			if !ok {
+				h.drain(claim, tracker)
+				return nil
+			}
+			if !due && h.requeue(session.Context(), msg) {
+				tracker.Track(msg.Offset)
+				newAck(session, tracker, msg)()
+				continue
+			}
+			if !due && !waitUntilDue(session.Context(), msg) {
+				h.drain(claim, tracker)
+				return nil
+			}
+
+			tracker.Track(msg.Offset)
+			ack := newAck(session, tracker, msg)
+
+			if err := h.schemaReadable(session.Context(), msg); err != nil {
+				if !h.deadLetter(session.Context(), msg, err.Error()) {
+					h.drain(claim, tracker)
+					return nil
+				}
+				ack()
+				continue
+			}
+
+			if !h.dispatch(session.Context(), msg, ack) {
+				h.drain(claim, tracker)
+				return nil
+			}
 		}
+	}
+}
+
+// dispatch routes an event by type. Unknown types come from newer
+// producers; they are acknowledged and skipped rather than failing.
+// Messages that don't decode are dead-lettered; dispatch reports false
+// when that failed, leaving the message unacknowledged.
+func (h *consumerHandler) dispatch(ctx context.Context, msg *sarama.ConsumerMessage, ack contracts.Ack) bool {
+	event, err := sharedkafka.DecodeEvent(msg)
+	if err != nil {
+		return h.undecodable(ctx, msg, ack, fmt.Errorf("decoding event: %w", err))
+	}
+
+	switch event.Type {
+	case models.EventOrderCreated, models.EventOrderCancelRequested:
+	default:
+		log.Printf("skipping event %s of unknown type %q (schema v%d)", event.EventID, event.Type, event.SchemaVersion)
+		metrics.IncrementCounter("order_processor_events_skipped_total")
+		ack()
+		return true
+	}
+
+	order, err := event.Order()
+	if err != nil {
+		return h.undecodable(ctx, msg, ack, fmt.Errorf("decoding order of event %s: %w", event.EventID, err))
+	}
+
+	if event.Type == models.EventOrderCancelRequested {
+		h.workerPool.SubmitCancel(order, "cancellation requested via "+event.Producer, ack)
+		return true
+	}
+
+	// Send order to worker pool (async); the offset is marked
+	// only after the order has been persisted or dead-lettered
+	h.workerPool.Submit(order, ack)
+	return true
+}
 
-		// Send order to worker pool (async)
-		h.workerPool.Submit(&order)
+// undecodable dead-letters a message that can't be read and acknowledges it
+// once the DLQ has it
+func (h *consumerHandler) undecodable(ctx context.Context, msg *sarama.ConsumerMessage, ack contracts.Ack, cause error) bool {
+	log.Printf("dead-lettering message %s/%d/%d: %v", msg.Topic, msg.Partition, msg.Offset, cause)
+	metrics.IncrementCounter("order_processor_undecodable_total")
 
-		// Mark message as consumed
-		session.MarkMessage(msg, "")
+	if !h.deadLetter(ctx, msg, cause.Error()) {
+		return false
+	}
+	ack()
+	return true
+}
+
+// schemaReadable checks the message's registered schema against ours and
+// returns an error wrapping schema.ErrIncompatible for messages we provably
+// can't read. If the registry is unreachable the message is decoded anyway.
+func (h *consumerHandler) schemaReadable(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	if h.schemas == nil {
+		return nil
+	}
+
+	header := sharedkafka.Header(msg, sharedkafka.HeaderSchemaID)
+	if header == "" {
+		return nil
 	}
 
+	id, err := schema.ParseID(header)
+	if err != nil {
+		log.Printf("invalid schema id %q at %s/%d/%d", header, msg.Topic, msg.Partition, msg.Offset)
+		return nil
+	}
+
+	err = h.schemas.Readable(ctx, id)
+	if errors.Is(err, schema.ErrIncompatible) {
+		log.Printf("dead-lettering message %s/%d/%d: %v", msg.Topic, msg.Partition, msg.Offset, err)
+		metrics.IncrementCounter("order_processor_schema_incompatible_total")
+		return err
+	}
+	if err != nil {
+		log.Printf("schema %d lookup failed, decoding anyway: %v", id, err)
+	}
 	return nil
 }
+
+// deadLetter sends a message we can't process to the DLQ: as an order when
+// it still decodes as one, so dlqctl can replay it, and as the raw message
+// otherwise. The DLQ publisher retries until it succeeds; false means it
+// gave up, leaving the message for redelivery.
+func (h *consumerHandler) deadLetter(ctx context.Context, msg *sarama.ConsumerMessage, reason string) bool {
+	publish := func() error {
+		return h.dlq.PublishMessage(ctx, rawMessage(msg), reason)
+	}
+	if event, err := sharedkafka.DecodeEvent(msg); err == nil {
+		if order, err := event.Order(); err == nil {
+			publish = func() error { return h.dlq.Publish(ctx, order, reason) }
+		}
+	}
+
+	if err := publish(); err != nil {
+		log.Printf("message %s/%d/%d left unacknowledged for redelivery: %v", msg.Topic, msg.Partition, msg.Offset, err)
+		return false
+	}
+	return true
+}
+
+// rawMessage copies what the DLQ keeps of a consumed message
+func rawMessage(msg *sarama.ConsumerMessage) *contracts.RawMessage {
+	raw := &contracts.RawMessage{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       msg.Key,
+		Value:     msg.Value,
+	}
+	if len(msg.Headers) > 0 {
+		raw.Headers = make(map[string]string, len(msg.Headers))
+		for _, h := range msg.Headers {
+			if h != nil {
+				raw.Headers[string(h.Key)] = string(h.Value)
+			}
+		}
+	}
+	return raw
+}
+
+// drain finishes the claim's in-flight orders so their offsets are marked
+// before the session commits. Whatever is still pending after drainTimeout
+// is redelivered to the next owner of the partition.
+func (h *consumerHandler) drain(claim sarama.ConsumerGroupClaim, tracker *offsetTracker) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.drainTimeout)
+	defer cancel()
+
+	for tracker.Pending() > 0 {
+		if err := h.drainer.Drain(ctx); err != nil {
+			log.Printf("drain of partition %d failed: %v", claim.Partition(), err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("partition %d: %d orders still in flight after drain timeout", claim.Partition(), tracker.Pending())
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// hold waits for the message's not-before time, but no longer than its
+// topic's hold counted from when the message was written. due is false when
+// the message is still early after that; ok is false if ctx ends first.
+func (h *consumerHandler) hold(ctx context.Context, msg *sarama.ConsumerMessage) (due, ok bool) {
+	notBefore, found := sharedkafka.NotBefore(msg)
+	if !found {
+		return true, true
+	}
+
+	until := notBefore
+	if hold, bounded := h.holds[msg.Topic]; bounded {
+		written := msg.Timestamp
+		if written.IsZero() {
+			written = time.Now()
+		}
+		if limit := written.Add(hold); limit.Before(until) {
+			until = limit
+		}
+	}
+
+	if !sleepUntil(ctx, until) {
+		return false, false
+	}
+	return !time.Now().Before(notBefore), true
+}
+
+// requeue sends a message that is not due yet to the end of its topic.
+// On failure the caller falls back to waiting for it.
+func (h *consumerHandler) requeue(ctx context.Context, msg *sarama.ConsumerMessage) bool {
+	if h.retries == nil {
+		return false
+	}
+
+	if err := h.retries.Requeue(ctx, rawMessage(msg)); err != nil {
+		metrics.IncrementCounter("order_processor_retry_requeue_failures_total")
+		log.Printf("requeue of %s/%d/%d failed, waiting for it instead: %v", msg.Topic, msg.Partition, msg.Offset, err)
+		return false
+	}
+
+	metrics.IncrementCounter("order_processor_retry_requeued_total")
+	return true
+}
+
+// waitUntilDue blocks until the message's not-before time has passed.
+// It returns false if ctx ends first; the message is then left unmarked.
+func waitUntilDue(ctx context.Context, msg *sarama.ConsumerMessage) bool {
+	notBefore, ok := sharedkafka.NotBefore(msg)
+	if !ok {
+		return true
+	}
+	return sleepUntil(ctx, notBefore)
+}
+
+// sleepUntil blocks until t; it returns false if ctx ends first
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	wait := time.Until(t)
+	if wait <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// newAck marks the message's offset once it and every earlier message of
+// the partition have been handled
+func newAck(
+	session sarama.ConsumerGroupSession,
+	tracker *offsetTracker,
+	msg *sarama.ConsumerMessage,
+) contracts.Ack {
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			if next, ok := tracker.Done(msg.Offset); ok {
+				session.MarkOffset(msg.Topic, msg.Partition, next, "")
+			}
+		})
+	}
+}
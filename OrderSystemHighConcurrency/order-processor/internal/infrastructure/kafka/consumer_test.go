@@ -0,0 +1,320 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"OrderSystemHighConcurrency/order-processor/internal/contracts"
+	"OrderSystemHighConcurrency/order-processor/internal/worker"
+	sharedkafka "OrderSystemHighConcurrency/shared/kafka"
+	"OrderSystemHighConcurrency/shared/models"
+
+	"github.com/IBM/sarama"
+)
+
+const retryTopic = "orders-retry-1m"
+
+// recordingProcessor acknowledges every order and records when it saw it
+type recordingProcessor struct {
+	mu        sync.Mutex
+	processed []string
+	at        map[string]time.Time
+}
+
+func (p *recordingProcessor) Process(_ context.Context, order *models.Order, ack contracts.Ack) error {
+	p.mu.Lock()
+	p.processed = append(p.processed, order.OrderID)
+	p.at[order.OrderID] = time.Now()
+	p.mu.Unlock()
+	ack()
+	return nil
+}
+
+func (p *recordingProcessor) Cancel(_ context.Context, _ *models.Order, _ string, ack contracts.Ack) error {
+	ack()
+	return nil
+}
+
+func (p *recordingProcessor) snapshot() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.processed...)
+}
+
+// topicTail stands in for the retry topic: requeued messages are appended
+// to the claim's partition like Kafka would
+type topicTail struct {
+	mu       sync.Mutex
+	messages chan *sarama.ConsumerMessage
+	offset   int64
+	requeued []string
+	fail     bool
+}
+
+func (t *topicTail) Publish(context.Context, *models.Order, string, time.Time, string) error {
+	return nil
+}
+
+func (t *topicTail) Requeue(_ context.Context, raw *contracts.RawMessage) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.fail {
+		return sarama.ErrNotEnoughReplicas
+	}
+
+	msg := &sarama.ConsumerMessage{
+		Topic:     raw.Topic,
+		Partition: raw.Partition,
+		Offset:    t.offset,
+		Key:       raw.Key,
+		Value:     raw.Value,
+		Timestamp: time.Now(),
+	}
+	for k, v := range raw.Headers {
+		msg.Headers = append(msg.Headers, &sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+	t.offset++
+	t.requeued = append(t.requeued, string(raw.Key))
+	t.messages <- msg
+	return nil
+}
+
+func (t *topicTail) append(t0 time.Time, orderID string, notBefore time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	payload, err := sharedkafka.EncodeOrderEvent(sharedkafka.EncodingJSON, models.EventOrderCreated, "test", &models.Order{OrderID: orderID})
+	if err != nil {
+		panic(err)
+	}
+	t.messages <- &sarama.ConsumerMessage{
+		Topic:     retryTopic,
+		Offset:    t.offset,
+		Key:       []byte(orderID),
+		Value:     payload,
+		Timestamp: t0,
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(sharedkafka.HeaderNotBefore), Value: []byte(sharedkafka.EncodeNotBefore(notBefore))},
+			{Key: []byte(sharedkafka.HeaderContentType), Value: []byte(sharedkafka.EncodingJSON.ContentType())},
+		},
+	}
+	t.offset++
+}
+
+type fakeClaim struct{ messages chan *sarama.ConsumerMessage }
+
+func (c fakeClaim) Topic() string                            { return retryTopic }
+func (c fakeClaim) Partition() int32                         { return 0 }
+func (c fakeClaim) InitialOffset() int64                     { return 0 }
+func (c fakeClaim) HighWaterMarkOffset() int64               { return 0 }
+func (c fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+type fakeSession struct {
+	ctx    context.Context
+	mu     sync.Mutex
+	marked int64
+}
+
+func (s *fakeSession) Claims() map[string][]int32 { return nil }
+func (s *fakeSession) MemberID() string           { return "test" }
+func (s *fakeSession) GenerationID() int32        { return 1 }
+func (s *fakeSession) MarkOffset(_ string, _ int32, offset int64, _ string) {
+	s.mu.Lock()
+	s.marked = offset
+	s.mu.Unlock()
+}
+func (s *fakeSession) Commit()                                     {}
+func (s *fakeSession) ResetOffset(string, int32, int64, string)    {}
+func (s *fakeSession) MarkMessage(*sarama.ConsumerMessage, string) {}
+func (s *fakeSession) Context() context.Context                    { return s.ctx }
+func (s *fakeSession) committed() int64                            { s.mu.Lock(); defer s.mu.Unlock(); return s.marked }
+
+type nopDrainer struct{}
+
+func (nopDrainer) Drain(context.Context) error { return nil }
+
+// consume runs ConsumeClaim over tail with hold as the retry topic's hold
+// until stop is called
+func consume(t *testing.T, tail *topicTail, hold time.Duration, dlq contracts.DLQPublisher) (*recordingProcessor, *fakeSession, func()) {
+	t.Helper()
+
+	processor := &recordingProcessor{at: make(map[string]time.Time)}
+	pool := worker.NewWorkerPool(1, 16, processor, worker.DispatchUnordered)
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.Start(ctx)
+
+	handler := &consumerHandler{
+		workerPool:   pool,
+		drainer:      nopDrainer{},
+		drainTimeout: time.Second,
+		dlq:          dlq,
+		retries:      tail,
+		holds:        map[string]time.Duration{retryTopic: hold},
+	}
+	session := &fakeSession{ctx: ctx}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ConsumeClaim(session, fakeClaim{messages: tail.messages})
+	}()
+
+	return processor, session, func() {
+		cancel()
+		<-done
+	}
+}
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// An attempt past the last tier waits longer than the tier's delay. It must
+// not hold up the shorter delays written behind it on the same partition.
+func TestConsumeClaimMixedDelaysOnOneTopic(t *testing.T) {
+	tail := &topicTail{messages: make(chan *sarama.ConsumerMessage, 16)}
+	t0 := time.Now().Truncate(time.Millisecond) // the precision of not-before
+	tail.append(t0, "extended", t0.Add(400*time.Millisecond))
+	tail.append(t0, "tier-1", t0.Add(50*time.Millisecond))
+	tail.append(t0, "tier-2", t0.Add(60*time.Millisecond))
+
+	processor, session, stop := consume(t, tail, 100*time.Millisecond, nil)
+	defer stop()
+
+	waitFor(t, 2*time.Second, func() bool { return len(processor.snapshot()) == 3 })
+
+	if got := processor.snapshot(); got[0] != "tier-1" || got[1] != "tier-2" || got[2] != "extended" {
+		t.Fatalf("processed %v, want tier-1 and tier-2 before extended", got)
+	}
+	for _, id := range []string{"tier-1", "tier-2"} {
+		if at := processor.at[id]; at.After(t0.Add(300 * time.Millisecond)) {
+			t.Errorf("%s processed after %s, stalled behind the extended delay", id, at.Sub(t0))
+		}
+	}
+	if at := processor.at["extended"]; at.Before(t0.Add(400 * time.Millisecond)) {
+		t.Errorf("extended processed %s early", t0.Add(400*time.Millisecond).Sub(at))
+	}
+	if len(tail.requeued) == 0 || tail.requeued[0] != "extended" {
+		t.Errorf("requeued %v, want the extended attempt", tail.requeued)
+	}
+
+	// Every consumed offset, the requeued ones included, is committed
+	waitFor(t, time.Second, func() bool { return session.committed() == tail.offset })
+}
+
+// Without a working requeue the consumer still never processes a message early
+func TestConsumeClaimWaitsWhenRequeueFails(t *testing.T) {
+	tail := &topicTail{messages: make(chan *sarama.ConsumerMessage, 16), fail: true}
+	t0 := time.Now().Truncate(time.Millisecond)
+	tail.append(t0, "extended", t0.Add(200*time.Millisecond))
+	tail.append(t0, "tier-1", t0.Add(20*time.Millisecond))
+
+	processor, _, stop := consume(t, tail, 50*time.Millisecond, nil)
+	defer stop()
+
+	waitFor(t, 2*time.Second, func() bool { return len(processor.snapshot()) == 2 })
+
+	if got := processor.snapshot(); got[0] != "extended" {
+		t.Fatalf("processed %v, want partition order", got)
+	}
+	if at := processor.at["extended"]; at.Before(t0.Add(200 * time.Millisecond)) {
+		t.Errorf("extended processed %s early", t0.Add(200*time.Millisecond).Sub(at))
+	}
+}
+
+// fakeDLQ records dead-lettered raw messages; it fails while down is set
+type fakeDLQ struct {
+	mu       sync.Mutex
+	down     bool
+	messages []*contracts.RawMessage
+	reasons  []string
+}
+
+func (d *fakeDLQ) Publish(context.Context, *models.Order, string) error { return nil }
+
+func (d *fakeDLQ) PublishMessage(_ context.Context, msg *contracts.RawMessage, reason string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.down {
+		return sarama.ErrOutOfBrokers
+	}
+	d.messages = append(d.messages, msg)
+	d.reasons = append(d.reasons, reason)
+	return nil
+}
+
+func (d *fakeDLQ) count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.messages)
+}
+
+// appendRaw writes a message with the given payload to the topic
+func (t *topicTail) appendRaw(value []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.messages <- &sarama.ConsumerMessage{
+		Topic:     retryTopic,
+		Offset:    t.offset,
+		Value:     value,
+		Timestamp: time.Now(),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(sharedkafka.HeaderContentType), Value: []byte(sharedkafka.EncodingJSON.ContentType())},
+		},
+	}
+	t.offset++
+}
+
+func TestConsumeClaimDeadLettersUndecodableMessages(t *testing.T) {
+	tail := &topicTail{messages: make(chan *sarama.ConsumerMessage, 16)}
+	tail.appendRaw([]byte("not json"))
+	tail.appendRaw([]byte(`{"event_id":"e1","type":"ORDER_CREATED","payload":"not an order"}`))
+	tail.append(time.Now(), "valid", time.Now())
+
+	dlq := &fakeDLQ{}
+	processor, session, stop := consume(t, tail, time.Second, dlq)
+	defer stop()
+
+	waitFor(t, 2*time.Second, func() bool { return len(processor.snapshot()) == 1 })
+	waitFor(t, time.Second, func() bool { return session.committed() == tail.offset })
+
+	if n := dlq.count(); n != 2 {
+		t.Fatalf("dead-lettered %d messages, want 2", n)
+	}
+	for i, msg := range dlq.messages {
+		if msg.Offset != int64(i) {
+			t.Errorf("dead letter %d has offset %d", i, msg.Offset)
+		}
+	}
+}
+
+func TestConsumeClaimKeepsUndecodableMessageWhenDLQFails(t *testing.T) {
+	tail := &topicTail{messages: make(chan *sarama.ConsumerMessage, 16)}
+	tail.appendRaw([]byte("not json"))
+
+	dlq := &fakeDLQ{down: true}
+	_, session, stop := consume(t, tail, time.Second, dlq)
+
+	time.Sleep(50 * time.Millisecond)
+	stop()
+
+	if dlq.count() != 0 {
+		t.Fatal("message dead-lettered while the DLQ was down")
+	}
+	if offset := session.committed(); offset != 0 {
+		t.Errorf("committed offset %d, want the message left for redelivery", offset)
+	}
+}
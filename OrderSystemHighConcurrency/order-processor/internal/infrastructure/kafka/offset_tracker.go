@@ -0,0 +1,56 @@
+package kafka
+
+import "sync"
+
+// offsetTracker tracks in-flight messages of one partition claim.
+// Workers finish out of order, so an offset may only be committed once every
+// message before it has been handled as well. Offsets need not be contiguous.
+type offsetTracker struct {
+	mu       sync.Mutex
+	inFlight []int64 // offsets in consumption order
+	done     map[int64]bool
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		done: make(map[int64]bool),
+	}
+}
+
+// Track registers a consumed offset
+func (t *offsetTracker) Track(offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inFlight = append(t.inFlight, offset)
+}
+
+// Done marks offset as handled. It returns the next offset to commit when
+// the contiguous prefix of handled messages advanced.
+func (t *offsetTracker) Done(offset int64) (int64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.done[offset] = true
+
+	n := 0
+	for n < len(t.inFlight) && t.done[t.inFlight[n]] {
+		delete(t.done, t.inFlight[n])
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+
+	next := t.inFlight[n-1] + 1
+	t.inFlight = t.inFlight[n:]
+	return next, true
+}
+
+// Pending returns the number of messages not yet handled
+func (t *offsetTracker) Pending() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.inFlight)
+}
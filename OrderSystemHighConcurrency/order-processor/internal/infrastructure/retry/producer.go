@@ -0,0 +1,138 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"OrderSystemHighConcurrency/order-processor/internal/contracts"
+	sharedkafka "OrderSystemHighConcurrency/shared/kafka"
+	"OrderSystemHighConcurrency/shared/models"
+
+	"github.com/IBM/sarama"
+)
+
+// producerName is recorded as the producer of retried events
+const producerName = "order-processor"
+
+type retryProducer struct {
+	producer    sarama.SyncProducer
+	keyStrategy sharedkafka.KeyStrategy
+	encoding    sharedkafka.Encoding
+	schemaID    int
+}
+
+// NewRetryProducer creates a producer for the tiered retry topics.
+// Orders are keyed and partitioned like order-api's events, so a user's
+// retries stay in order. schemaID is the registry ID sent with every
+// event, 0 when none is used.
+func NewRetryProducer(
+	brokers []string,
+	keyStrategy sharedkafka.KeyStrategy,
+	partitioner sharedkafka.Partitioner,
+	encoding sharedkafka.Encoding,
+	schemaID int,
+) (contracts.RetryPublisher, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("brokers required")
+	}
+
+	keyStrategy, err := sharedkafka.ParseKeyStrategy(string(keyStrategy))
+	if err != nil {
+		return nil, err
+	}
+	partitionerFor, err := sharedkafka.NewPartitioner(partitioner)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.V2_8_0_0
+	cfg.Producer.RequiredAcks = sarama.WaitForAll
+	cfg.Producer.Retry.Max = 5
+	cfg.Producer.Return.Successes = true
+	cfg.Producer.Timeout = 5 * time.Second
+	cfg.Producer.Partitioner = partitionerFor
+
+	producer, err := sarama.NewSyncProducer(brokers, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &retryProducer{
+		producer:    producer,
+		keyStrategy: keyStrategy,
+		encoding:    encoding,
+		schemaID:    schemaID,
+	}, nil
+}
+
+// Publish sends the order to a retry topic with a not-before header
+func (r *retryProducer) Publish(
+	ctx context.Context,
+	order *models.Order,
+	topic string,
+	notBefore time.Time,
+	reason string,
+) error {
+	if order == nil {
+		return errors.New("order is nil")
+	}
+
+	payload, err := sharedkafka.EncodeOrderEvent(r.encoding, models.EventOrderCreated, producerName, order)
+	if err != nil {
+		return err
+	}
+
+	msg := &sarama.ProducerMessage{
+		Topic: topic,
+		Key:   sarama.StringEncoder(sharedkafka.MessageKey(r.keyStrategy, order)),
+		Value: sarama.ByteEncoder(payload),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte(sharedkafka.HeaderNotBefore), Value: []byte(sharedkafka.EncodeNotBefore(notBefore))},
+			{Key: []byte(sharedkafka.HeaderRetryAttempt), Value: []byte(strconv.Itoa(order.RetryCount))},
+			{Key: []byte(sharedkafka.HeaderRetryReason), Value: []byte(reason)},
+			sharedkafka.ContentTypeHeader(r.encoding),
+		},
+	}
+	if r.schemaID > 0 {
+		msg.Headers = append(msg.Headers, sarama.RecordHeader{
+			Key:   []byte(sharedkafka.HeaderSchemaID),
+			Value: []byte(strconv.Itoa(r.schemaID)),
+		})
+	}
+
+	return r.send(ctx, msg)
+}
+
+// Requeue sends the message to the end of its topic with its key, value
+// and headers; the partitioner keeps it on the partition it came from
+func (r *retryProducer) Requeue(ctx context.Context, raw *contracts.RawMessage) error {
+	if raw == nil {
+		return errors.New("message is nil")
+	}
+
+	msg := &sarama.ProducerMessage{
+		Topic: raw.Topic,
+		Value: sarama.ByteEncoder(raw.Value),
+	}
+	if raw.Key != nil {
+		msg.Key = sarama.ByteEncoder(raw.Key)
+	}
+	for k, v := range raw.Headers {
+		msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+
+	return r.send(ctx, msg)
+}
+
+func (r *retryProducer) send(ctx context.Context, msg *sarama.ProducerMessage) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		_, _, err := r.producer.SendMessage(msg)
+		return err
+	}
+}
@@ -0,0 +1,76 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"OrderSystemHighConcurrency/order-processor/internal/contracts"
+	sharedkafka "OrderSystemHighConcurrency/shared/kafka"
+	"OrderSystemHighConcurrency/shared/models"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+)
+
+func newTestProducer(t *testing.T, strategy sharedkafka.KeyStrategy) (*retryProducer, func() *sarama.ProducerMessage) {
+	t.Helper()
+
+	producer := mocks.NewSyncProducer(t, nil)
+	t.Cleanup(func() { producer.Close() })
+
+	var sent *sarama.ProducerMessage
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		sent = msg
+		return nil
+	})
+
+	return &retryProducer{producer: producer, keyStrategy: strategy, encoding: sharedkafka.EncodingJSON}, func() *sarama.ProducerMessage { return sent }
+}
+
+func TestPublishKeysLikeOrderAPI(t *testing.T) {
+	order := &models.Order{
+		OrderID:  "order-1",
+		UserID:   "user-1",
+		Metadata: map[string]string{models.MetadataTenantID: "tenant-1"},
+	}
+
+	for strategy, want := range map[sharedkafka.KeyStrategy]string{
+		sharedkafka.KeyByUser:   "user-1",
+		sharedkafka.KeyByOrder:  "order-1",
+		sharedkafka.KeyByTenant: "tenant-1",
+	} {
+		r, sent := newTestProducer(t, strategy)
+
+		if err := r.Publish(context.Background(), order, "orders-retry-5s", time.Now(), "db down"); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+		if key, _ := sent().Key.Encode(); string(key) != want {
+			t.Errorf("%s: key = %s, want %s", strategy, key, want)
+		}
+	}
+}
+
+func TestRequeueKeepsTheMessage(t *testing.T) {
+	r, sent := newTestProducer(t, sharedkafka.KeyByUser)
+
+	raw := &contracts.RawMessage{
+		Topic:   "orders-retry-1m",
+		Key:     []byte("user-1"),
+		Value:   []byte(`{"event_id":"e1"}`),
+		Headers: map[string]string{sharedkafka.HeaderNotBefore: "1700000000000"},
+	}
+	if err := r.Requeue(context.Background(), raw); err != nil {
+		t.Fatalf("Requeue: %v", err)
+	}
+
+	msg := sent()
+	key, _ := msg.Key.Encode()
+	value, _ := msg.Value.Encode()
+	if msg.Topic != raw.Topic || string(key) != "user-1" || string(value) != string(raw.Value) {
+		t.Errorf("requeued %s/%s/%s, want the message unchanged", msg.Topic, key, value)
+	}
+	if len(msg.Headers) != 1 || string(msg.Headers[0].Value) != "1700000000000" {
+		t.Errorf("headers = %v, want the not-before header", msg.Headers)
+	}
+}
@@ -0,0 +1,151 @@
+package status
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"OrderSystemHighConcurrency/order-processor/internal/contracts"
+	sharedkafka "OrderSystemHighConcurrency/shared/kafka"
+	"OrderSystemHighConcurrency/shared/metrics"
+	"OrderSystemHighConcurrency/shared/models"
+
+	"github.com/IBM/sarama"
+)
+
+// producerName is recorded as the producer of status events
+const producerName = "order-processor"
+
+// queueSize is how many events may wait for the producer before new ones
+// are dropped
+const queueSize = 4096
+
+type statusProducer struct {
+	producer sarama.AsyncProducer
+	topic    string
+	encoding sharedkafka.Encoding
+	done     chan struct{}
+
+	mu        sync.RWMutex
+	closed    bool
+	queue     chan *sarama.ProducerMessage
+	forwarded chan struct{}
+}
+
+// NewStatusProducer creates a publisher of ORDER_STATUS_CHANGED events.
+// Events are keyed by order ID and sent asynchronously, one request at a
+// time per broker so the changes of an order keep their order.
+func NewStatusProducer(brokers []string, topic string, encoding sharedkafka.Encoding) (contracts.StatusPublisher, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("brokers required")
+	}
+
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.V2_8_0_0
+	cfg.Producer.RequiredAcks = sarama.WaitForLocal
+	cfg.Producer.Retry.Max = 5
+	cfg.Producer.Return.Errors = true
+	cfg.Producer.Flush.Frequency = 10 * time.Millisecond
+	cfg.Net.MaxOpenRequests = 1
+
+	producer, err := sarama.NewAsyncProducer(brokers, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &statusProducer{
+		producer:  producer,
+		topic:     topic,
+		encoding:  encoding,
+		done:      make(chan struct{}),
+		queue:     make(chan *sarama.ProducerMessage, queueSize),
+		forwarded: make(chan struct{}),
+	}
+	go p.logErrors()
+	go p.forward()
+
+	return p, nil
+}
+
+// Publish queues an ORDER_STATUS_CHANGED event carrying the order. When
+// the producer's queue is full the event is dropped rather than blocking
+// the batch callback.
+func (p *statusProducer) Publish(order *models.Order) {
+	if order == nil {
+		return
+	}
+
+	payload, err := sharedkafka.EncodeOrderEvent(p.encoding, models.EventOrderStatusChanged, producerName, order)
+	if err != nil {
+		log.Printf("failed to encode status of order %s: %v", order.OrderID, err)
+		return
+	}
+
+	msg := &sarama.ProducerMessage{
+		Topic: p.topic,
+		Key:   sarama.StringEncoder(order.OrderID),
+		Value: sarama.ByteEncoder(payload),
+		Headers: []sarama.RecordHeader{
+			sharedkafka.ContentTypeHeader(p.encoding),
+		},
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return
+	}
+
+	select {
+	case p.queue <- msg:
+	default:
+		log.Printf("status queue full, dropped status of order %s", order.OrderID)
+		metrics.IncrementCounter("order_processor_status_dropped_total")
+	}
+}
+
+// forward hands queued events to the producer, whose input is unbuffered
+func (p *statusProducer) forward() {
+	defer close(p.forwarded)
+
+	for msg := range p.queue {
+		p.producer.Input() <- msg
+	}
+}
+
+func (p *statusProducer) logErrors() {
+	defer close(p.done)
+
+	for err := range p.producer.Errors() {
+		log.Printf("failed to publish order status: %v", err)
+		metrics.IncrementCounter("order_processor_status_publish_failed_total")
+	}
+}
+
+// Close flushes queued events
+func (p *statusProducer) Close() error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+	<-p.forwarded
+
+	err := p.producer.Close()
+	<-p.done
+	return err
+}
+
+// nopPublisher is used when no status topic is configured
+type nopPublisher struct{}
+
+// NewNopPublisher creates a StatusPublisher that discards every event
+func NewNopPublisher() contracts.StatusPublisher {
+	return nopPublisher{}
+}
+
+func (nopPublisher) Publish(*models.Order) {}
+
+func (nopPublisher) Close() error { return nil }
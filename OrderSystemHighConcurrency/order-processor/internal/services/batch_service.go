@@ -4,10 +4,21 @@ import (
 	"OrderSystemHighConcurrency/order-processor/internal/contracts"
 	"OrderSystemHighConcurrency/shared/models"
 	"context"
+	"errors"
+	"log"
 	"sync"
 	"time"
 )
 
+// ErrBatchClosed is returned by Add once Close has been called
+var ErrBatchClosed = errors.New("batch service closed")
+
+// batchEntry is a buffered order and the callback for its write result
+type batchEntry struct {
+	order *models.Order
+	done  func(err error)
+}
+
 // BatchService handles order batching
 type BatchService struct {
 	repo      contracts.Repository
@@ -15,7 +26,11 @@ type BatchService struct {
 	timeout   time.Duration
 
 	mu     sync.Mutex
-	buffer []*models.Order
+	buffer []batchEntry
+	closed bool
+
+	stop    chan struct{}
+	flusher sync.WaitGroup
 }
 
 // NewBatchService creates a batch service
@@ -24,30 +39,166 @@ func NewBatchService(repo contracts.Repository, size int, timeout time.Duration)
 		repo:      repo,
 		batchSize: size,
 		timeout:   timeout,
-		buffer:    make([]*models.Order, 0),
+		buffer:    make([]batchEntry, 0, size),
+		stop:      make(chan struct{}),
 	}
 }
 
-// Add adds order to batch and flushes if needed
-func (b *BatchService) Add(ctx context.Context, order *models.Order) error {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+// Start flushes partial batches every timeout so quiet periods don't
+// leave orders sitting in the buffer
+func (b *BatchService) Start(ctx context.Context) {
+	if b.timeout <= 0 {
+		return
+	}
+
+	b.flusher.Add(1)
+	go func() {
+		defer b.flusher.Done()
+
+		ticker := time.NewTicker(b.timeout)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-b.stop:
+				return
+			case <-ticker.C:
+				if err := b.Flush(ctx); err != nil {
+					log.Printf("timed batch flush failed: %v", err)
+				}
+			}
+		}
+	}()
+}
 
-	b.buffer = append(b.buffer, order)
+// Close stops the background flusher and drains the buffer, including orders
+// re-added for retry, until it is empty or ctx is done
+func (b *BatchService) Close(ctx context.Context) error {
+	close(b.stop)
+	b.flusher.Wait()
 
+	for {
+		b.mu.Lock()
+		batch := b.take()
+		if len(batch) == 0 {
+			b.closed = true
+		}
+		b.mu.Unlock()
+
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			// Unwritten orders are never acknowledged, so Kafka redelivers them
+			b.mu.Lock()
+			b.closed = true
+			b.mu.Unlock()
+			return err
+		}
+
+		_ = b.write(ctx, batch)
+	}
+}
+
+// Add adds order to batch and flushes if needed.
+// Write errors are reported through done rather than returned.
+func (b *BatchService) Add(ctx context.Context, order *models.Order, done func(err error)) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrBatchClosed
+	}
+	b.buffer = append(b.buffer, batchEntry{order: order, done: done})
+
+	var batch []batchEntry
 	if len(b.buffer) >= b.batchSize {
-		return b.Flush(ctx)
+		batch = b.take()
 	}
+	b.mu.Unlock()
+
+	_ = b.write(ctx, batch)
 	return nil
 }
 
 // Flush writes batch to repository
 func (b *BatchService) Flush(ctx context.Context) error {
-	if len(b.buffer) == 0 {
+	b.mu.Lock()
+	batch := b.take()
+	b.mu.Unlock()
+
+	return b.write(ctx, batch)
+}
+
+// Remove drops the buffered entries of orderID owned by userID and reports
+// cause to them. Callbacks run without the lock held so they may re-add
+// orders.
+func (b *BatchService) Remove(orderID, userID string, cause error) int {
+	b.mu.Lock()
+	var removed []batchEntry
+	kept := b.buffer[:0]
+	for _, e := range b.buffer {
+		if e.order.OrderID == orderID && e.order.UserID == userID {
+			removed = append(removed, e)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	clear(b.buffer[len(kept):])
+	b.buffer = kept
+	b.mu.Unlock()
+
+	for _, e := range removed {
+		if e.done != nil {
+			e.done(cause)
+		}
+	}
+	return len(removed)
+}
+
+// take swaps out the buffer; callers must hold b.mu
+func (b *BatchService) take() []batchEntry {
+	batch := b.buffer
+	b.buffer = make([]batchEntry, 0, b.batchSize)
+	return batch
+}
+
+// write persists the batch and reports the result to every entry.
+// Callbacks run without the lock held so they may re-add orders.
+func (b *BatchService) write(ctx context.Context, batch []batchEntry) error {
+	if len(batch) == 0 {
 		return nil
 	}
 
-	err := b.repo.SaveBatch(ctx, b.buffer)
-	b.buffer = make([]*models.Order, 0)
+	orders := make([]*models.Order, len(batch))
+	for i, e := range batch {
+		orders[i] = e.order
+	}
+
+	err := b.repo.SaveBatch(ctx, orders)
+
+	// A BatchError only fails the listed rows; the rest were written
+	var (
+		batchErr *contracts.BatchError
+		rowErrs  map[int]error
+	)
+	if errors.As(err, &batchErr) {
+		rowErrs = make(map[int]error, len(batchErr.Rows))
+		for _, row := range batchErr.Rows {
+			rowErrs[row.Index] = row.Err
+		}
+	}
+
+	for i, e := range batch {
+		if e.done == nil {
+			continue
+		}
+		if batchErr != nil {
+			e.done(rowErrs[i])
+		} else {
+			e.done(err)
+		}
+	}
 	return err
 }
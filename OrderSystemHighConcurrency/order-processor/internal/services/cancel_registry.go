@@ -0,0 +1,56 @@
+package services
+
+import (
+	"OrderSystemHighConcurrency/shared/models"
+	"sync"
+	"time"
+)
+
+// cancelMemory is how long a cancellation is remembered for copies of the
+// order that are still on their way, e.g. on a retry topic
+const cancelMemory = 24 * time.Hour
+
+// cancelKey is an order as cancelled by a user
+type cancelKey struct {
+	orderID string
+	userID  string
+}
+
+// cancelRegistry remembers recently cancelled orders and who cancelled them
+type cancelRegistry struct {
+	mu        sync.Mutex
+	at        map[cancelKey]time.Time
+	lastPrune time.Time
+}
+
+func newCancelRegistry() *cancelRegistry {
+	return &cancelRegistry{at: make(map[cancelKey]time.Time)}
+}
+
+// add records the cancellation and forgets expired ones
+func (r *cancelRegistry) add(orderID, userID string) {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.at[cancelKey{orderID, userID}] = now
+	if now.Sub(r.lastPrune) < cancelMemory {
+		return
+	}
+	for key, at := range r.at {
+		if now.Sub(at) > cancelMemory {
+			delete(r.at, key)
+		}
+	}
+	r.lastPrune = now
+}
+
+// has reports whether the order was cancelled recently by its own user
+func (r *cancelRegistry) has(order *models.Order) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	at, ok := r.at[cancelKey{order.OrderID, order.UserID}]
+	return ok && time.Since(at) <= cancelMemory
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"OrderSystemHighConcurrency/order-processor/internal/contracts"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DLQFilter selects DLQ entries; zero-valued fields match everything
+type DLQFilter struct {
+	Reason   string // case-insensitive substring of the failure reason
+	UserID   string
+	OrderIDs []string
+	Since    time.Time
+	Until    time.Time
+}
+
+// Match reports whether the entry passes every set criterion
+func (f DLQFilter) Match(e *contracts.DLQEntry) bool {
+	if f.Reason != "" && !strings.Contains(strings.ToLower(e.Reason), strings.ToLower(f.Reason)) {
+		return false
+	}
+	if f.UserID != "" && e.Order.UserID != f.UserID {
+		return false
+	}
+	if len(f.OrderIDs) > 0 && !contains(f.OrderIDs, e.Order.OrderID) {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// ReasonCount is one bucket of the reason histogram
+type ReasonCount struct {
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+// DLQAdminService inspects and replays the dead letter queue
+type DLQAdminService struct {
+	reader   contracts.DLQReader
+	replayer contracts.DLQReplayer
+}
+
+// NewDLQAdminService creates the service; replayer may be nil for read-only use
+func NewDLQAdminService(reader contracts.DLQReader, replayer contracts.DLQReplayer) *DLQAdminService {
+	return &DLQAdminService{
+		reader:   reader,
+		replayer: replayer,
+	}
+}
+
+// List returns the matching entries, oldest first; limit <= 0 means no limit
+func (s *DLQAdminService) List(ctx context.Context, filter DLQFilter, limit int) ([]*contracts.DLQEntry, error) {
+	entries, err := s.reader.ReadAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]*contracts.DLQEntry, 0, len(entries))
+	for _, e := range entries {
+		if !filter.Match(e) {
+			continue
+		}
+		matched = append(matched, e)
+		if limit > 0 && len(matched) == limit {
+			break
+		}
+	}
+	return matched, nil
+}
+
+// Histogram counts matching entries per reason, most frequent first
+func (s *DLQAdminService) Histogram(ctx context.Context, filter DLQFilter) ([]ReasonCount, error) {
+	entries, err := s.List(ctx, filter, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, e := range entries {
+		counts[e.Reason]++
+	}
+
+	histogram := make([]ReasonCount, 0, len(counts))
+	for reason, n := range counts {
+		histogram = append(histogram, ReasonCount{Reason: reason, Count: n})
+	}
+	sort.Slice(histogram, func(i, j int) bool {
+		if histogram[i].Count == histogram[j].Count {
+			return histogram[i].Reason < histogram[j].Reason
+		}
+		return histogram[i].Count > histogram[j].Count
+	})
+	return histogram, nil
+}
+
+// Replay republishes every matching entry and returns how many were sent.
+// It stops at the first failure so nothing is skipped silently.
+func (s *DLQAdminService) Replay(ctx context.Context, filter DLQFilter) (int, error) {
+	if s.replayer == nil {
+		return 0, errors.New("replay not configured")
+	}
+
+	entries, err := s.List(ctx, filter, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, e := range entries {
+		if err := s.replayer.Replay(ctx, e); err != nil {
+			return i, err
+		}
+	}
+	return len(entries), nil
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"OrderSystemHighConcurrency/order-processor/internal/contracts"
+	"OrderSystemHighConcurrency/order-processor/internal/worker"
+	"context"
+)
+
+// drainService implements contracts.Drainer for the processing pipeline:
+// it waits for the worker pool to go idle and then flushes the batch
+type drainService struct {
+	workerPool   *worker.WorkerPool
+	batchService contracts.BatchService
+}
+
+// NewDrainService creates a Drainer over the worker pool and batch service
+func NewDrainService(workerPool *worker.WorkerPool, batchService contracts.BatchService) contracts.Drainer {
+	return &drainService{
+		workerPool:   workerPool,
+		batchService: batchService,
+	}
+}
+
+// Drain waits for queued orders to be processed, then writes the partial batch
+func (d *drainService) Drain(ctx context.Context) error {
+	if err := d.workerPool.WaitIdle(ctx); err != nil {
+		return err
+	}
+	return d.batchService.Flush(ctx)
+}
@@ -3,55 +3,332 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
 
 	"OrderSystemHighConcurrency/order-processor/internal/contracts"
+	sharedcontracts "OrderSystemHighConcurrency/shared/contracts"
+	"OrderSystemHighConcurrency/shared/metrics"
 	"OrderSystemHighConcurrency/shared/models"
 )
 
 // processorService implements contracts.OrderProcessor
 type processorService struct {
-	batchService contracts.BatchService
-	retryService contracts.RetryService
-	dlq          contracts.DLQPublisher
+	batchService   contracts.BatchService
+	retryService   contracts.RetryService
+	retryPublisher contracts.RetryPublisher
+	dlq            contracts.DLQPublisher
+	history        contracts.HistoryRepository
+	repository     contracts.Repository
+	status         contracts.StatusPublisher
+	cancelled      *cancelRegistry
 }
 
 // NewOrderProcessor creates OrderProcessor
 func NewOrderProcessor(
 	batchService contracts.BatchService,
 	retryService contracts.RetryService,
+	retryPublisher contracts.RetryPublisher,
 	dlq contracts.DLQPublisher,
+	history contracts.HistoryRepository,
+	repository contracts.Repository,
+	status contracts.StatusPublisher,
 ) contracts.OrderProcessor {
 	return &processorService{
-		batchService: batchService,
-		retryService: retryService,
-		dlq:          dlq,
+		batchService:   batchService,
+		retryService:   retryService,
+		retryPublisher: retryPublisher,
+		dlq:            dlq,
+		history:        history,
+		repository:     repository,
+		status:         status,
+		cancelled:      newCancelRegistry(),
 	}
 }
 
 // Process processes a single order
-func (p *processorService) Process(ctx context.Context, order *models.Order) error {
+func (p *processorService) Process(ctx context.Context, order *models.Order, ack contracts.Ack) error {
 	if order == nil {
 		return errors.New("order is nil")
 	}
 
-	order.Status = models.OrderStatusProcessing
+	// Cancelled orders are stored as they are, never processed
+	if order.Status == models.OrderStatusCancelled || p.cancelled.has(order) {
+		return p.storeCancelled(ctx, order, ack, "cancellation requested before processing")
+	}
 
-	// Try batching
-	err := p.batchService.Add(ctx, order)
-	if err != nil {
-		// Increment retry count
-		order.RetryCount++
+	// An order in a status we can't start from is not ours to process
+	if err := admit(order); err != nil {
+		return p.deadLetter(ctx, order, ack, err)
+	}
 
-		// Check if max retries reached
-		if !p.retryService.ShouldRetry(order.RetryCount) {
-			// Send to DLQ
-			return p.dlq.Publish(ctx, order, err.Error())
+	// Invalid orders will never succeed, so don't retry them
+	if err := validate(order); err != nil {
+		if terr := order.TransitionTo(models.OrderStatusFailed, err.Error()); terr != nil {
+			return terr
 		}
+		return p.deadLetter(ctx, order, ack, err)
+	}
+
+	return p.enqueue(ctx, order, ack)
+}
+
+// deadLetter publishes the order to the DLQ and acknowledges it
+func (p *processorService) deadLetter(ctx context.Context, order *models.Order, ack contracts.Ack, cause error) error {
+	if err := p.publishDeadLetter(ctx, order, cause.Error()); err != nil {
+		return err
+	}
+	p.recordDeadLetter(ctx, order, cause.Error())
+	ack()
+	return nil
+}
+
+// enqueue hands a COMPLETED copy of the order to the batch, so the status
+// only becomes true once the row is written. The outcome of the write
+// decides whether the order is acknowledged, retried or dead-lettered.
+func (p *processorService) enqueue(ctx context.Context, order *models.Order, ack contracts.Ack) error {
+	completed := *order
+	completed.Transitions = slices.Clone(order.Transitions)
+	if err := completed.TransitionTo(models.OrderStatusCompleted, "persisted"); err != nil {
+		return err
+	}
+
+	return p.batchService.Add(ctx, &completed, func(err error) {
+		switch {
+		case err == nil:
+			p.status.Publish(&completed)
+			ack()
+		case errors.Is(err, contracts.ErrCancelled):
+			// Pulled out of the batch by Cancel before it was written
+			if err := p.storeCancelled(ctx, order, ack, err.Error()); err != nil {
+				log.Printf("failed to store cancelled order %s: %v", order.OrderID, err)
+			}
+		case errors.Is(err, models.ErrInvalidTransition):
+			// The stored order moved on, e.g. it was cancelled; it keeps
+			// that status, so none is published for this copy
+			p.skipCompletion(ctx, order, err)
+			ack()
+		default:
+			p.handleFailure(ctx, order, ack, err)
+		}
+	})
+}
+
+// skipCompletion records a completion the stored order no longer allows.
+// That is the expected end of an order cancelled while it was processed,
+// not a failure, so the order is not dead-lettered.
+func (p *processorService) skipCompletion(ctx context.Context, order *models.Order, cause error) {
+	log.Printf("order %s not completed: %v", order.OrderID, cause)
+	metrics.IncrementCounter("order_processor_completion_skipped_total")
+
+	// Only the skip is recorded; this copy's transitions never took effect
+	skipped := models.StatusTransition{
+		From:   order.Status,
+		To:     order.Status,
+		At:     time.Now().UTC(),
+		Reason: "completion skipped, " + cause.Error(),
+	}
+	if err := p.history.Append(ctx, order.OrderID, []models.StatusTransition{skipped}); err != nil {
+		log.Printf("failed to record history of order %s: %v", order.OrderID, err)
+	}
+}
+
+// Cancel pulls a buffered order out of the batch, records the cancellation
+// of an order not written yet, or otherwise stores the stored order as
+// CANCELLED unless it has already been completed. The request itself only
+// names the order; its amount and items are not written.
+func (p *processorService) Cancel(ctx context.Context, order *models.Order, reason string, ack contracts.Ack) error {
+	if order == nil {
+		return errors.New("order is nil")
+	}
+
+	// Copies still in flight, e.g. on a retry topic, are cancelled on
+	// arrival, provided they belong to the user who cancelled
+	p.cancelled.add(order.OrderID, order.UserID)
+
+	if p.batchService.Remove(order.OrderID, order.UserID, fmt.Errorf("%w: %s", contracts.ErrCancelled, reason)) > 0 {
+		ack()
+		return nil
+	}
 
-		// Return error to retry later
+	stored, err := p.repository.RequestCancel(ctx, order, reason)
+	if errors.Is(err, sharedcontracts.ErrForbidden) {
+		// Only the order's own user may cancel it
+		log.Printf("rejected cancellation by user %s: %v", order.UserID, err)
+		metrics.IncrementCounter("order_processor_cancel_forbidden_total")
+		ack()
+		return nil
+	}
+	if errors.Is(err, models.ErrOrderNotFound) {
+		// Held durably; the order is stored CANCELLED when it is written
+		log.Printf("order %s: cancellation recorded before processing", order.OrderID)
+		ack()
+		return nil
+	}
+	if err != nil {
 		return err
 	}
 
-	order.Status = models.OrderStatusCompleted
+	switch stored.Status {
+	case models.OrderStatusCancelled:
+		ack()
+		return nil
+	case models.OrderStatusCompleted:
+		p.rejectCancel(ctx, stored, models.ErrCancelTooLate)
+		ack()
+		return nil
+	}
+
+	return p.storeCancelled(ctx, stored, ack, reason)
+}
+
+// rejectCancel records a cancellation that came after the order completed.
+// Only the rejection is recorded; the CANCELLED transition never happened.
+func (p *processorService) rejectCancel(ctx context.Context, order *models.Order, cause error) {
+	log.Printf("order %s: %v", order.OrderID, cause)
+	metrics.IncrementCounter("order_processor_cancel_too_late_total")
+
+	rejection := models.StatusTransition{
+		From:   models.OrderStatusCompleted,
+		To:     models.OrderStatusCompleted,
+		At:     time.Now().UTC(),
+		Reason: "cancellation rejected, " + models.ErrCancelTooLate.Error(),
+	}
+	if err := p.history.Append(ctx, order.OrderID, []models.StatusTransition{rejection}); err != nil {
+		log.Printf("failed to record history of order %s: %v", order.OrderID, err)
+	}
+}
+
+// storeCancelled moves the order to CANCELLED and writes it. The source
+// message is acknowledged once the row is written.
+func (p *processorService) storeCancelled(ctx context.Context, order *models.Order, ack contracts.Ack, reason string) error {
+	if order.Status == "" {
+		order.Status = models.OrderStatusCreated
+	}
+	if order.Status != models.OrderStatusCancelled {
+		if err := order.TransitionTo(models.OrderStatusCancelled, reason); err != nil {
+			return p.deadLetter(ctx, order, ack, err)
+		}
+	}
+
+	return p.batchService.Add(ctx, order, func(err error) {
+		switch {
+		case err == nil:
+			p.status.Publish(order)
+			ack()
+		case errors.Is(err, contracts.ErrCancelled):
+			// A repeated request pulled the cancelled order itself; put it back
+			if err := p.storeCancelled(ctx, order, ack, err.Error()); err != nil {
+				log.Printf("failed to store cancelled order %s: %v", order.OrderID, err)
+			}
+		case errors.Is(err, models.ErrInvalidTransition):
+			// The order completed before the cancellation was written
+			p.rejectCancel(ctx, order, err)
+			ack()
+		default:
+			p.handleFailure(ctx, order, ack, err)
+		}
+	})
+}
+
+// handleFailure schedules the order on a retry topic, or sends it to the DLQ
+// when the error is fatal or retries are exhausted. The source message is
+// only acknowledged once one of those publishes succeeded.
+func (p *processorService) handleFailure(ctx context.Context, order *models.Order, ack contracts.Ack, cause error) {
+	// Increment retry count
+	order.RetryCount++
+
+	if p.retryService.IsRetryable(cause) && p.retryService.ShouldRetry(order.RetryCount) {
+		topic, delay := p.retryService.Next(order.RetryCount)
+
+		if err := order.TransitionTo(models.OrderStatusQueued, "retry scheduled: "+cause.Error()); err != nil {
+			log.Printf("order %s: %v", order.OrderID, err)
+		}
+
+		err := p.retryPublisher.Publish(ctx, order, topic, time.Now().Add(delay), cause.Error())
+		if err == nil {
+			p.record(ctx, order)
+			p.status.Publish(order)
+			ack()
+			return
+		}
+		log.Printf("failed to schedule retry of order %s on %s, sending to DLQ: %v", order.OrderID, topic, err)
+	}
+
+	// Send to DLQ
+	if err := order.TransitionTo(models.OrderStatusFailed, cause.Error()); err != nil {
+		log.Printf("order %s: %v", order.OrderID, err)
+	}
+	if err := p.publishDeadLetter(ctx, order, cause.Error()); err != nil {
+		log.Printf("order %s left unacknowledged for redelivery: %v", order.OrderID, err)
+		return
+	}
+	p.recordDeadLetter(ctx, order, cause.Error())
+	ack()
+}
+
+// publishDeadLetter publishes the order to the DLQ. The publisher retries
+// until it succeeds and only gives up when ctx ends, e.g. on shutdown; the
+// source message is then redelivered.
+func (p *processorService) publishDeadLetter(ctx context.Context, order *models.Order, reason string) error {
+	if err := p.dlq.Publish(ctx, order, reason); err != nil {
+		return fmt.Errorf("dead-lettering order %s: %w", order.OrderID, err)
+	}
 	return nil
 }
+
+// record appends the order's transitions to its history. Completed orders
+// are recorded by the repository with the order row; a failed write only
+// costs audit detail, so it doesn't hold up the message.
+func (p *processorService) record(ctx context.Context, order *models.Order, extra ...models.StatusTransition) {
+	transitions := append(slices.Clone(order.Transitions), extra...)
+	if err := p.history.Append(ctx, order.OrderID, transitions); err != nil {
+		log.Printf("failed to record history of order %s: %v", order.OrderID, err)
+	}
+}
+
+// recordDeadLetter records the routing to the DLQ with the reason given to
+// the DLQ publisher. Orders that could not move to FAILED, because their
+// status did not allow processing at all, get an entry that keeps their
+// status.
+func (p *processorService) recordDeadLetter(ctx context.Context, order *models.Order, reason string) {
+	if order.Status == models.OrderStatusFailed {
+		p.record(ctx, order)
+		p.status.Publish(order)
+		return
+	}
+
+	p.record(ctx, order, models.StatusTransition{
+		From:   order.Status,
+		To:     order.Status,
+		At:     time.Now().UTC(),
+		Reason: "dead-lettered: " + reason,
+	})
+}
+
+// admit moves an incoming order to PROCESSING. Orders published before
+// statuses were enforced may still arrive without a status, as CREATED, or
+// as PROCESSING from an old retry; they are queued first.
+func admit(order *models.Order) error {
+	switch order.Status {
+	case "":
+		order.Status = models.OrderStatusCreated
+		fallthrough
+	case models.OrderStatusCreated, models.OrderStatusProcessing:
+		if err := order.TransitionTo(models.OrderStatusQueued, "received by order-processor"); err != nil {
+			return err
+		}
+	}
+
+	return order.TransitionTo(models.OrderStatusProcessing, "picked up by worker")
+}
+
+// validate runs the checks that no retry can fix
+func validate(order *models.Order) error {
+	if err := order.ValidateAmount(); err != nil {
+		return err
+	}
+	return order.ValidateItems()
+}
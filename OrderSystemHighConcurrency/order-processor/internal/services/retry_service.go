@@ -1,15 +1,74 @@
 package services
 
+import (
+	"OrderSystemHighConcurrency/order-processor/internal/contracts"
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// maxRetryDelay caps the backoff of attempts beyond the last tier
+const maxRetryDelay = time.Hour
+
+// RetryService is the retry policy: how many attempts an order gets, which
+// tiered retry topic each attempt goes to, how long it waits there
+// (with jitter) and which errors are worth retrying at all.
 type RetryService struct {
 	maxRetries int
+	tiers      []contracts.RetryTier
+	jitter     float64 // fraction of the delay, e.g. 0.2 = ±20%
 }
 
-func NewRetryService(maxRetries int) *RetryService {
+func NewRetryService(maxRetries int, tiers []contracts.RetryTier, jitter float64) *RetryService {
 	return &RetryService{
 		maxRetries: maxRetries,
+		tiers:      tiers,
+		jitter:     jitter,
 	}
 }
 
 func (r *RetryService) ShouldRetry(attempt int) bool {
-	return attempt < r.maxRetries
+	return attempt < r.maxRetries && len(r.tiers) > 0
+}
+
+// IsRetryable treats validation failures, cancelled work and errors marked
+// contracts.ErrPermanent as fatal; everything else is assumed transient
+func (r *RetryService) IsRetryable(err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, contracts.ErrPermanent),
+		errors.Is(err, models.ErrAmountMismatch),
+		errors.Is(err, models.ErrAmountPrecision),
+		errors.Is(err, models.ErrUnknownCurrency),
+		errors.Is(err, context.Canceled):
+		return false
+	default:
+		return true
+	}
+}
+
+// Next picks the tier for the attempt. Tiers are expected to grow, giving
+// exponential backoff; attempts beyond the last tier stay on its topic and
+// double its delay for every extra attempt, up to maxRetryDelay. The
+// consumer requeues those on their topic until they are due, so they don't
+// hold up the tier's own messages.
+func (r *RetryService) Next(attempt int) (string, time.Duration) {
+	i := min(max(attempt-1, 0), len(r.tiers)-1)
+	tier := r.tiers[i]
+
+	delay := tier.Delay
+	for extra := attempt - len(r.tiers); extra > 0 && delay < maxRetryDelay; extra-- {
+		delay *= 2
+	}
+	delay = min(delay, max(maxRetryDelay, tier.Delay))
+
+	if r.jitter > 0 {
+		spread := float64(delay) * r.jitter
+		delay += time.Duration(spread * (2*rand.Float64() - 1))
+	}
+
+	return tier.Topic, delay
 }
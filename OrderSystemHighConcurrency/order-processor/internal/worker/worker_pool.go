@@ -4,23 +4,85 @@ import (
 	"OrderSystemHighConcurrency/order-processor/internal/contracts"
 	"OrderSystemHighConcurrency/shared/models"
 	"context"
+	"fmt"
+	"hash/fnv"
 	"log"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// WorkerPool controls concurrent order processing
+// job is an order together with the acknowledgement for its source message.
+// Cancellation requests travel the same lanes so they stay behind the
+// order they cancel.
+type job struct {
+	order  *models.Order
+	ack    contracts.Ack
+	cancel bool
+	reason string // why the order is cancelled
+}
+
+// DispatchMode decides which worker picks up an order
+type DispatchMode string
+
+const (
+	// DispatchUnordered lets any idle worker take the next order
+	DispatchUnordered DispatchMode = "unordered"
+
+	// DispatchByOrder pins every order ID to one worker lane
+	DispatchByOrder DispatchMode = "order"
+
+	// DispatchByUser pins every user ID to one worker lane
+	DispatchByUser DispatchMode = "user"
+)
+
+// ParseDispatchMode validates a dispatch mode name
+func ParseDispatchMode(s string) (DispatchMode, error) {
+	switch mode := DispatchMode(s); mode {
+	case DispatchUnordered, DispatchByOrder, DispatchByUser:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown dispatch mode %q", s)
+	}
+}
+
+// WorkerPool controls concurrent order processing.
+// In unordered mode all workers share one queue. In keyed modes each worker
+// owns a lane and orders are hashed to lanes by key, so orders with the same
+// key are processed one at a time in submission order while different keys
+// still run in parallel.
 type WorkerPool struct {
 	workerCount int
-	jobs        chan *models.Order
+	mode        DispatchMode
+	lanes       []chan job
 	processor   contracts.OrderProcessor
 	wg          sync.WaitGroup
+	inFlight    atomic.Int64 // submitted but not yet processed
 }
 
-// NewWorkerPool creates a new worker pool
-func NewWorkerPool(workerCount int, bufferSize int, processor contracts.OrderProcessor) *WorkerPool {
+// NewWorkerPool creates a new worker pool.
+// bufferSize is the total queue capacity, split across lanes in keyed modes.
+func NewWorkerPool(workerCount int, bufferSize int, processor contracts.OrderProcessor, mode DispatchMode) *WorkerPool {
+	if workerCount < 1 {
+		workerCount = 1
+	}
+
+	var lanes []chan job
+	if mode == DispatchByOrder || mode == DispatchByUser {
+		laneSize := max(bufferSize/workerCount, 1)
+		lanes = make([]chan job, workerCount)
+		for i := range lanes {
+			lanes[i] = make(chan job, laneSize)
+		}
+	} else {
+		mode = DispatchUnordered
+		lanes = []chan job{make(chan job, bufferSize)}
+	}
+
 	return &WorkerPool{
 		workerCount: workerCount,
-		jobs:        make(chan *models.Order, bufferSize),
+		mode:        mode,
+		lanes:       lanes,
 		processor:   processor,
 	}
 }
@@ -29,17 +91,57 @@ func NewWorkerPool(workerCount int, bufferSize int, processor contracts.OrderPro
 func (wp *WorkerPool) Start(ctx context.Context) {
 	for i := 0; i < wp.workerCount; i++ {
 		wp.wg.Add(1)
-		go wp.worker(ctx, i)
+		go wp.worker(ctx, i, wp.lanes[i%len(wp.lanes)])
+	}
+}
+
+// Submit sends an order to the worker pool.
+// ack is called once the order has been durably handled.
+func (wp *WorkerPool) Submit(order *models.Order, ack contracts.Ack) {
+	wp.inFlight.Add(1)
+	wp.lanes[wp.laneFor(order)] <- job{order: order, ack: ack}
+}
+
+// SubmitCancel sends a cancellation request for the order to the lane the
+// order itself is processed on
+func (wp *WorkerPool) SubmitCancel(order *models.Order, reason string, ack contracts.Ack) {
+	wp.inFlight.Add(1)
+	wp.lanes[wp.laneFor(order)] <- job{order: order, ack: ack, cancel: true, reason: reason}
+}
+
+// laneFor hashes the order's dispatch key to a lane index
+func (wp *WorkerPool) laneFor(order *models.Order) int {
+	if len(wp.lanes) == 1 || order == nil {
+		return 0
+	}
+
+	key := order.OrderID
+	if wp.mode == DispatchByUser {
+		key = order.UserID
 	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(wp.lanes)))
 }
 
-// Submit sends an order to the worker pool
-func (wp *WorkerPool) Submit(order *models.Order) {
-	wp.jobs <- order
+// WaitIdle blocks until every submitted order has been processed or ctx is done
+func (wp *WorkerPool) WaitIdle(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for wp.inFlight.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
 }
 
-// worker processes jobs from the channel
-func (wp *WorkerPool) worker(ctx context.Context, id int) {
+// worker processes jobs from its lane
+func (wp *WorkerPool) worker(ctx context.Context, id int, jobs <-chan job) {
 	defer wp.wg.Done()
 
 	for {
@@ -48,20 +150,34 @@ func (wp *WorkerPool) worker(ctx context.Context, id int) {
 			log.Printf("worker %d shutting down", id)
 			return
 
-		case order := <-wp.jobs:
-			if order == nil {
+		case j, ok := <-jobs:
+			if !ok {
+				// Stop was called and the queue is drained
+				return
+			}
+			if j.order == nil {
+				wp.inFlight.Add(-1)
 				continue
 			}
 
-			if err := wp.processor.Process(ctx, order); err != nil {
-				log.Printf("worker %d failed to process order %s: %v", id, order.OrderID, err)
+			if j.cancel {
+				if err := wp.processor.Cancel(ctx, j.order, j.reason, j.ack); err != nil {
+					log.Printf("worker %d failed to cancel order %s: %v", id, j.order.OrderID, err)
+				}
+			} else if err := wp.processor.Process(ctx, j.order, j.ack); err != nil {
+				log.Printf("worker %d failed to process order %s: %v", id, j.order.OrderID, err)
 			}
+			wp.inFlight.Add(-1)
 		}
 	}
 }
 
-// Stop gracefully shuts down the worker pool
+// Stop gracefully shuts down the worker pool.
+// Queued orders are processed before the workers exit; no Submit may
+// happen after Stop.
 func (wp *WorkerPool) Stop() {
-	close(wp.jobs)
+	for _, lane := range wp.lanes {
+		close(lane)
+	}
 	wp.wg.Wait()
 }
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"OrderSystemHighConcurrency/shared/contracts"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAPIKeyVerify(t *testing.T) {
+	path := writeFile(t, "api-keys.json", []apiKeyRecord{
+		{ID: "key-1", UserID: "merchant-42", Hash: HashAPIKey("secret-one"), Scopes: []string{ScopeOrdersRead}},
+		{ID: "key-2", UserID: "merchant-7", Hash: "sha256:" + strings.ToUpper(strings.TrimPrefix(HashAPIKey("secret-two"), "sha256:")), Scopes: []string{ScopeOrdersWrite}},
+	})
+	verifier, err := NewAPIKeyVerifier(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key     string
+		keyID   string
+		subject string
+		scopes  []string
+	}{
+		{"secret-one", "key-1", "merchant-42", []string{ScopeOrdersRead}},
+		{"secret-two", "key-2", "merchant-7", []string{ScopeOrdersWrite}},
+	}
+	for _, tt := range tests {
+		p, err := verifier.Verify(tt.key)
+		if err != nil {
+			t.Fatalf("Verify(%s): %v", tt.key, err)
+		}
+		if p.Subject != tt.subject || p.KeyID != tt.keyID || p.Method != "api_key" || !slices.Equal(p.Scopes, tt.scopes) {
+			t.Errorf("Verify(%s) = %+v", tt.key, p)
+		}
+	}
+
+	for _, key := range []string{"", "secret-three", "SECRET-ONE", HashAPIKey("secret-one")} {
+		if _, err := verifier.Verify(key); !errors.Is(err, contracts.ErrInvalidCredentials) {
+			t.Errorf("Verify(%q): err = %v, want %v", key, err, contracts.ErrInvalidCredentials)
+		}
+	}
+}
+
+func TestAPIKeyFileValidation(t *testing.T) {
+	for name, records := range map[string][]apiKeyRecord{
+		"missing user":   {{ID: "key-1", Hash: HashAPIKey("secret")}},
+		"plaintext key":  {{ID: "key-1", UserID: "merchant-42", Hash: "secret"}},
+		"unknown scheme": {{ID: "key-1", UserID: "merchant-42", Hash: "md5:5ebe2294ecd0e0f08eab7690d2a6ee69"}},
+	} {
+		if _, err := NewAPIKeyVerifier(writeFile(t, "api-keys.json", records)); err == nil {
+			t.Errorf("%s: file was accepted", name)
+		}
+	}
+
+	if _, err := NewAPIKeyVerifier("/nonexistent/api-keys.json"); err == nil {
+		t.Error("missing file was accepted")
+	}
+}
+
+func TestHashAPIKey(t *testing.T) {
+	const want = "sha256:2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"
+	if got := HashAPIKey("secret"); got != want {
+		t.Errorf("HashAPIKey = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"OrderSystemHighConcurrency/shared/contracts"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// jwtLeeway tolerates clock skew between the issuer and order-api
+const jwtLeeway = 30 * time.Second
+
+// verificationKey is a JWKS entry; exactly one of secret and public is set
+type verificationKey struct {
+	secret []byte         // "oct" keys, for HS256
+	public *rsa.PublicKey // "RSA" keys, for RS256
+}
+
+// jwtVerifier implements contracts.CredentialVerifier for HS256 and RS256
+// tokens verified against a local JWKS file
+type jwtVerifier struct {
+	keys     map[string]verificationKey // by kid
+	issuer   string                     // required "iss" when set
+	audience string                     // required "aud" when set
+}
+
+// NewJWTVerifier loads the verification keys from a JWKS file.
+// Tokens must carry "sub" and "exp"; scopes come from a space-separated
+// "scope" claim or a "scp" array.
+func NewJWTVerifier(jwksPath string, issuer string, audience string) (contracts.CredentialVerifier, error) {
+	keys, err := loadJWKS(jwksPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jwtVerifier{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+	}, nil
+}
+
+// loadJWKS reads "oct" and "RSA" keys; other key types are skipped
+func loadJWKS(path string) (map[string]verificationKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			K   string `json:"k"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("jwks file %s: %w", path, err)
+	}
+
+	keys := make(map[string]verificationKey, len(set.Keys))
+	for _, k := range set.Keys {
+		switch k.Kty {
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil || len(secret) < 32 {
+				return nil, fmt.Errorf("jwks file %s: key %q needs at least 256 bits", path, k.Kid)
+			}
+			keys[k.Kid] = verificationKey{secret: secret}
+
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+				return nil, fmt.Errorf("jwks file %s: invalid RSA key %q", path, k.Kid)
+			}
+			keys[k.Kid] = verificationKey{public: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}}
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("jwks file %s: no usable keys", path)
+	}
+	return keys, nil
+}
+
+// jwtClaims are the registered claims order-api checks
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"` // a string or an array
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+	Scope     string          `json:"scope"`
+	Scopes    []string        `json:"scp"`
+}
+
+// Verify checks the token's signature and claims
+func (a *jwtVerifier) Verify(token string) (*contracts.Principal, error) {
+	kid, claims, err := a.verify(token, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", contracts.ErrInvalidCredentials, err)
+	}
+
+	scopes := claims.Scopes
+	if claims.Scope != "" {
+		scopes = strings.Fields(claims.Scope)
+	}
+
+	return &contracts.Principal{
+		Subject: claims.Subject,
+		Method:  "jwt",
+		KeyID:   kid,
+		Scopes:  scopes,
+	}, nil
+}
+
+// verify checks the signature and claims and returns the key ID used
+func (a *jwtVerifier) verify(token string, now time.Time) (string, *jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", nil, fmt.Errorf("header: %w", err)
+	}
+
+	key, err := a.key(header.Kid)
+	if err != nil {
+		return "", nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, errors.New("malformed signature")
+	}
+
+	// The algorithm has to fit the key, so an RSA public key can never be
+	// used as an HMAC secret
+	signed := []byte(parts[0] + "." + parts[1])
+	switch {
+	case header.Alg == "HS256" && key.secret != nil:
+		mac := hmac.New(sha256.New, key.secret)
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return "", nil, errors.New("bad signature")
+		}
+	case header.Alg == "RS256" && key.public != nil:
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(key.public, crypto.SHA256, digest[:], signature); err != nil {
+			return "", nil, errors.New("bad signature")
+		}
+	default:
+		return "", nil, fmt.Errorf("algorithm %q not allowed for key %q", header.Alg, header.Kid)
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", nil, fmt.Errorf("claims: %w", err)
+	}
+	if err := a.checkClaims(&claims, now); err != nil {
+		return "", nil, err
+	}
+	return header.Kid, &claims, nil
+}
+
+// key returns the key named by kid; tokens without a kid are only accepted
+// when the JWKS holds a single key
+func (a *jwtVerifier) key(kid string) (verificationKey, error) {
+	if kid == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key, nil
+		}
+	}
+
+	key, ok := a.keys[kid]
+	if !ok {
+		return verificationKey{}, fmt.Errorf("unknown key %q", kid)
+	}
+	return key, nil
+}
+
+func (a *jwtVerifier) checkClaims(claims *jwtClaims, now time.Time) error {
+	if claims.Subject == "" {
+		return errors.New("missing sub")
+	}
+	if claims.ExpiresAt == nil {
+		return errors.New("missing exp")
+	}
+	if now.After(time.Unix(*claims.ExpiresAt, 0).Add(jwtLeeway)) {
+		return errors.New("token expired")
+	}
+	if claims.NotBefore != nil && now.Add(jwtLeeway).Before(time.Unix(*claims.NotBefore, 0)) {
+		return errors.New("token not valid yet")
+	}
+	if a.issuer != "" && claims.Issuer != a.issuer {
+		return fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if a.audience != "" && !hasAudience(claims.Audience, a.audience) {
+		return errors.New("token not meant for this audience")
+	}
+	return nil
+}
+
+// hasAudience reports whether the "aud" claim, a string or an array,
+// contains audience
+func hasAudience(raw json.RawMessage, audience string) bool {
+	var single string
+	if json.Unmarshal(raw, &single) == nil {
+		return single == audience
+	}
+
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		return slices.Contains(list, audience)
+	}
+	return false
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
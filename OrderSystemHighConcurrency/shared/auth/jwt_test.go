@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"OrderSystemHighConcurrency/shared/contracts"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testSecret = []byte("0123456789abcdef0123456789abcdef")
+	testRSAKey *rsa.PrivateKey
+)
+
+func rsaKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	if testRSAKey == nil {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testRSAKey = key
+	}
+	return testRSAKey
+}
+
+// writeFile stores v as JSON in a temporary file and returns its path
+func writeFile(t *testing.T, name string, v any) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// writeJWKS stores the given keys of the test key pair as a JWKS file
+func writeJWKS(t *testing.T, kids ...string) string {
+	t.Helper()
+
+	var keys []map[string]string
+	for _, kid := range kids {
+		switch kid {
+		case "hs":
+			keys = append(keys, map[string]string{"kty": "oct", "kid": kid, "k": b64(testSecret)})
+		case "rs":
+			pub := rsaKey(t).PublicKey
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"n":   b64(pub.N.Bytes()),
+				"e":   b64(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		}
+	}
+	return writeFile(t, "jwks.json", map[string]any{"keys": keys})
+}
+
+// signToken builds a token with the given header and claims. alg picks the
+// signing method: "HS256" with testSecret, "RS256" with the RSA key, and
+// "HS256-pub" HMACs with the RSA public key, as in the key confusion attack.
+func signToken(t *testing.T, header, claims map[string]any, alg string) string {
+	t.Helper()
+
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	signed := b64(h) + "." + b64(c)
+
+	var sig []byte
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, testSecret)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case "HS256-pub":
+		mac := hmac.New(sha256.New, rsaKey(t).PublicKey.N.Bytes())
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case "RS256":
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, rsaKey(t), crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signed + "." + b64(sig)
+}
+
+func TestJWTVerify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	verifier, err := NewJWTVerifier(writeJWKS(t, "hs", "rs"), "https://issuer.example", "order-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := func(edit func(map[string]any)) map[string]any {
+		c := map[string]any{
+			"sub": "merchant-42",
+			"iss": "https://issuer.example",
+			"aud": "order-api",
+			"exp": now.Add(time.Hour).Unix(),
+		}
+		if edit != nil {
+			edit(c)
+		}
+		return c
+	}
+	hs := map[string]any{"alg": "HS256", "kid": "hs"}
+	rs := map[string]any{"alg": "RS256", "kid": "rs"}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string // substring; "" means valid
+	}{
+		{"HS256", signToken(t, hs, claims(nil), "HS256"), ""},
+		{"RS256", signToken(t, rs, claims(nil), "RS256"), ""},
+		{"audience list", signToken(t, hs, claims(func(c map[string]any) { c["aud"] = []string{"other", "order-api"} }), "HS256"), ""},
+		{"expired within leeway", signToken(t, hs, claims(func(c map[string]any) { c["exp"] = now.Add(-10 * time.Second).Unix() }), "HS256"), ""},
+
+		{"RSA key used as HMAC secret", signToken(t, map[string]any{"alg": "HS256", "kid": "rs"}, claims(nil), "HS256-pub"), "not allowed"},
+		{"HMAC key with RS256", signToken(t, map[string]any{"alg": "RS256", "kid": "hs"}, claims(nil), "RS256"), "not allowed"},
+		{"alg none", signToken(t, map[string]any{"alg": "none", "kid": "hs"}, claims(nil), ""), "not allowed"},
+		{"bad signature", signToken(t, hs, claims(nil), "RS256"), "bad signature"},
+		{"expired", signToken(t, hs, claims(func(c map[string]any) { c["exp"] = now.Add(-time.Minute).Unix() }), "HS256"), "expired"},
+		{"not before", signToken(t, hs, claims(func(c map[string]any) { c["nbf"] = now.Add(time.Minute).Unix() }), "HS256"), "not valid yet"},
+		{"wrong issuer", signToken(t, hs, claims(func(c map[string]any) { c["iss"] = "https://evil.example" }), "HS256"), "issuer"},
+		{"wrong audience", signToken(t, hs, claims(func(c map[string]any) { c["aud"] = "grpc-stream" }), "HS256"), "audience"},
+		{"missing audience", signToken(t, hs, claims(func(c map[string]any) { delete(c, "aud") }), "HS256"), "audience"},
+		{"missing sub", signToken(t, hs, claims(func(c map[string]any) { delete(c, "sub") }), "HS256"), "missing sub"},
+		{"missing exp", signToken(t, hs, claims(func(c map[string]any) { delete(c, "exp") }), "HS256"), "missing exp"},
+		{"missing kid with several keys", signToken(t, map[string]any{"alg": "HS256"}, claims(nil), "HS256"), "unknown key"},
+		{"unknown kid", signToken(t, map[string]any{"alg": "HS256", "kid": "other"}, claims(nil), "HS256"), "unknown key"},
+		{"malformed", "not-a-token", "malformed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got, err := verifier.(*jwtVerifier).verify(tt.token, now)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("verify: %v", err)
+			case tt.wantErr == "" && got.Subject != "merchant-42":
+				t.Fatalf("sub = %q, want merchant-42", got.Subject)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("verify: err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWTVerifyWithoutKidUsesSingleKey(t *testing.T) {
+	verifier, err := NewJWTVerifier(writeJWKS(t, "hs"), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := signToken(t, map[string]any{"alg": "HS256"}, map[string]any{
+		"sub": "merchant-42",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}, "HS256")
+	if _, err := verifier.Verify(token); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+}
+
+func TestJWTVerifyPrincipal(t *testing.T) {
+	verifier, err := NewJWTVerifier(writeJWKS(t, "hs", "rs"), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	p, err := verifier.Verify(signToken(t, map[string]any{"alg": "RS256", "kid": "rs"},
+		map[string]any{"sub": "merchant-42", "exp": exp, "scope": "orders:read orders:write"}, "RS256"))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if p.Subject != "merchant-42" || p.Method != "jwt" || p.KeyID != "rs" ||
+		!slices.Equal(p.Scopes, []string{ScopeOrdersRead, ScopeOrdersWrite}) {
+		t.Errorf("principal = %+v", p)
+	}
+
+	p, err = verifier.Verify(signToken(t, map[string]any{"alg": "HS256", "kid": "hs"},
+		map[string]any{"sub": "merchant-42", "exp": exp, "scp": []string{"webhooks:manage"}}, "HS256"))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !slices.Equal(p.Scopes, []string{ScopeWebhooks}) {
+		t.Errorf("scopes = %v, want [%s]", p.Scopes, ScopeWebhooks)
+	}
+
+	_, err = verifier.Verify("not-a-token")
+	if !errors.Is(err, contracts.ErrInvalidCredentials) {
+		t.Errorf("err = %v, want %v", err, contracts.ErrInvalidCredentials)
+	}
+}
+
+func TestLoadJWKSRejectsWeakSecrets(t *testing.T) {
+	path := writeFile(t, "jwks.json", map[string]any{"keys": []map[string]string{
+		{"kty": "oct", "kid": "short", "k": b64([]byte("too-short"))},
+	}})
+	if _, err := NewJWTVerifier(path, "", ""); err == nil {
+		t.Fatal("a 72-bit HMAC key was accepted")
+	}
+
+	path = writeFile(t, "jwks.json", map[string]any{"keys": []map[string]string{
+		{"kty": "EC", "kid": "ec"},
+	}})
+	if _, err := NewJWTVerifier(path, "", ""); err == nil {
+		t.Fatal("a JWKS without usable keys was accepted")
+	}
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"OrderSystemHighConcurrency/shared/contracts"
+	"context"
+)
+
+// Scopes granted to API keys and tokens
+const (
+	ScopeOrdersRead  = "orders:read"
+	ScopeOrdersWrite = "orders:write"
+	ScopeWebhooks    = "webhooks:manage"
+)
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the authenticated caller
+func WithPrincipal(ctx context.Context, p *contracts.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the authenticated caller, or nil when
+// authentication is disabled
+func PrincipalFrom(ctx context.Context) *contracts.Principal {
+	p, _ := ctx.Value(principalKey{}).(*contracts.Principal)
+	return p
+}
+
+// BindUser returns the user a request acts for. An authenticated caller
+// always acts for itself: an empty userID becomes its subject and any other
+// user is contracts.ErrForbidden. Without authentication userID is trusted.
+func BindUser(ctx context.Context, userID string) (string, error) {
+	p := PrincipalFrom(ctx)
+	if p == nil {
+		return userID, nil
+	}
+
+	if userID != "" && userID != p.Subject {
+		return "", contracts.ErrForbidden
+	}
+	return p.Subject, nil
+}
+
+// CanAccess reports whether the caller may see a resource owned by userID
+func CanAccess(ctx context.Context, userID string) bool {
+	p := PrincipalFrom(ctx)
+	return p == nil || p.Subject == userID
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"OrderSystemHighConcurrency/shared/contracts"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBindUser(t *testing.T) {
+	merchant := WithPrincipal(context.Background(), &contracts.Principal{Subject: "merchant-42"})
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		userID  string
+		want    string
+		wantErr error
+	}{
+		{"anonymous keeps user", context.Background(), "merchant-7", "merchant-7", nil},
+		{"anonymous without user", context.Background(), "", "", nil},
+		{"caller fills in user", merchant, "", "merchant-42", nil},
+		{"caller acts for itself", merchant, "merchant-42", "merchant-42", nil},
+		{"caller acts for another user", merchant, "merchant-7", "", contracts.ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BindUser(tt.ctx, tt.userID)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("BindUser(%q) = %q, %v; want %q, %v", tt.userID, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCanAccess(t *testing.T) {
+	merchant := WithPrincipal(context.Background(), &contracts.Principal{Subject: "merchant-42"})
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		userID string
+		want   bool
+	}{
+		{"anonymous", context.Background(), "merchant-7", true},
+		{"own resource", merchant, "merchant-42", true},
+		{"other user's resource", merchant, "merchant-7", false},
+		{"unowned resource", merchant, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanAccess(tt.ctx, tt.userID); got != tt.want {
+				t.Errorf("CanAccess(%q) = %v, want %v", tt.userID, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package contracts
+
+import (
+	"errors"
+	"slices"
+)
+
+var (
+	// ErrNoCredentials is returned when a request carries no credentials of
+	// the kind being checked, so the next kind can be tried
+	ErrNoCredentials = errors.New("no credentials")
+
+	// ErrInvalidCredentials is returned for credentials that were presented
+	// but can't be verified
+	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	// ErrForbidden is returned when a caller acts for another user
+	ErrForbidden = errors.New("forbidden")
+)
+
+// CredentialVerifier checks one kind of credential, such as an API key or
+// a bearer token, whatever transport it arrived on.
+type CredentialVerifier interface {
+	// Verify returns the caller the credential belongs to, or an error
+	// wrapping ErrInvalidCredentials.
+	Verify(credential string) (*Principal, error)
+}
+
+// Principal is an authenticated caller. Orders and webhooks belong to the
+// user named by Subject.
+type Principal struct {
+	Subject string   `json:"subject"`
+	Method  string   `json:"method"` // "api_key" or "jwt"
+	KeyID   string   `json:"key_id,omitempty"`
+	Scopes  []string `json:"scopes"`
+}
+
+// HasScope reports whether the caller was granted scope
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
@@ -6,6 +6,12 @@ import (
 )
 
 type Producer interface {
+	// Publish sends an ORDER_CREATED event for the order
 	Publish(ctx context.Context, order *models.Order) error
+
+	// PublishEvent sends an event of the given type about the order, keyed
+	// like Publish so it stays in order with the events before it
+	PublishEvent(ctx context.Context, eventType models.EventType, order *models.Order) error
+
 	Close() error
 }
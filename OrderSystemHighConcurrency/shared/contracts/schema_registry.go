@@ -0,0 +1,19 @@
+package contracts
+
+import "context"
+
+// SchemaRegistry stores versioned payload schemas per subject and hands out
+// IDs that producers put in a message header and consumers resolve.
+type SchemaRegistry interface {
+	// Register adds schema as the newest version of subject and returns its
+	// ID. Registering a schema identical to an existing version returns that
+	// version's ID. Schemas that break the subject's compatibility rule are
+	// rejected with an error wrapping schema.ErrIncompatible.
+	Register(ctx context.Context, subject string, schema string) (int, error)
+
+	// Lookup returns the schema registered under id.
+	Lookup(ctx context.Context, id int) (string, error)
+
+	// Compatible reports whether schema could be registered under subject.
+	Compatible(ctx context.Context, subject string, schema string) (bool, error)
+}
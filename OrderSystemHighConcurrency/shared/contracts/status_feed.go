@@ -0,0 +1,55 @@
+package contracts
+
+import (
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+	"errors"
+	"slices"
+)
+
+var (
+	// ErrInvalidCursor is returned for cursors this feed did not hand out
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	// ErrWatcherTooSlow ends a watch that could not keep up with the feed;
+	// the client can resume from its last cursor.
+	ErrWatcherTooSlow = errors.New("watcher too slow, resume from the last cursor")
+)
+
+// StatusFeed streams the order status changes published by order-processor.
+type StatusFeed interface {
+	// Watch calls fn for every status change matching filter until ctx is
+	// done or fn returns an error. It starts after cursor, a value taken
+	// from an earlier StatusUpdate, or with new changes when cursor is "".
+	Watch(ctx context.Context, filter StatusFilter, cursor string, fn func(StatusUpdate) error) error
+
+	// Cursor returns a cursor at the feed's current position. Taken before
+	// reading a snapshot, a watch from it misses no change made after.
+	Cursor() (string, error)
+}
+
+// StatusFilter selects the orders to watch, by ID or by user. All selects
+// every order, for internal followers such as read projections.
+type StatusFilter struct {
+	OrderIDs []string
+	UserID   string
+	All      bool
+}
+
+// Match reports whether the order is watched
+func (f StatusFilter) Match(order *models.Order) bool {
+	if f.All {
+		return true
+	}
+	if f.UserID != "" && order.UserID == f.UserID {
+		return true
+	}
+	return slices.Contains(f.OrderIDs, order.OrderID)
+}
+
+// StatusUpdate is an order as persisted after a status change, plus the
+// cursor to resume watching right after it.
+type StatusUpdate struct {
+	Order  *models.Order
+	Cursor string
+}
@@ -0,0 +1,106 @@
+package kafka
+
+import (
+	"OrderSystemHighConcurrency/shared/models"
+	"OrderSystemHighConcurrency/shared/pb"
+	"OrderSystemHighConcurrency/shared/utils"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/IBM/sarama"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Encoding is the wire format of event payloads
+type Encoding string
+
+const (
+	EncodingJSON     Encoding = "json"
+	EncodingProtobuf Encoding = "protobuf"
+)
+
+// Content types sent in HeaderContentType
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// ParseEncoding validates an encoding name; "" means JSON
+func ParseEncoding(s string) (Encoding, error) {
+	switch e := Encoding(s); e {
+	case "", EncodingJSON:
+		return EncodingJSON, nil
+	case EncodingProtobuf:
+		return e, nil
+	default:
+		return "", fmt.Errorf("unknown encoding %q", s)
+	}
+}
+
+// ContentType returns the HeaderContentType value for the encoding
+func (e Encoding) ContentType() string {
+	if e == EncodingProtobuf {
+		return ContentTypeProtobuf
+	}
+	return ContentTypeJSON
+}
+
+// EncodeOrderEvent wraps order in an event envelope and serialises it
+func EncodeOrderEvent(encoding Encoding, eventType models.EventType, producer string, order *models.Order) ([]byte, error) {
+	if encoding != EncodingProtobuf {
+		event, err := models.NewOrderEvent(eventType, producer, order.OrderID, order)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(event)
+	}
+
+	return proto.Marshal(&pb.OrderEvent{
+		EventId:       utils.GenerateID(),
+		Type:          string(eventType),
+		SchemaVersion: models.EventSchemaVersion,
+		ProducedAt:    timestamppb.New(time.Now().UTC()),
+		Producer:      producer,
+		OrderId:       order.OrderID,
+		Order:         pb.FromModel(order),
+	})
+}
+
+// DecodeEvent decodes a message in the format named by its content-type
+// header. Messages without the header predate it and are JSON.
+func DecodeEvent(msg *sarama.ConsumerMessage) (*models.OrderEvent, error) {
+	if Header(msg, HeaderContentType) != ContentTypeProtobuf {
+		return models.DecodeOrderEvent(msg.Value)
+	}
+
+	var p pb.OrderEvent
+	if err := proto.Unmarshal(msg.Value, &p); err != nil {
+		return nil, err
+	}
+
+	event := &models.OrderEvent{
+		EventID:       p.EventId,
+		Type:          models.EventType(p.Type),
+		SchemaVersion: int(p.SchemaVersion),
+		Producer:      p.Producer,
+		OrderID:       p.OrderId,
+	}
+	if p.ProducedAt != nil {
+		event.ProducedAt = p.ProducedAt.AsTime()
+	}
+	if p.Order != nil {
+		order, err := p.Order.ToModel()
+		if err != nil {
+			return nil, err
+		}
+		event.SetOrder(order)
+	}
+	return event, nil
+}
+
+// ContentTypeHeader builds the HeaderContentType record header for the encoding
+func ContentTypeHeader(encoding Encoding) sarama.RecordHeader {
+	return sarama.RecordHeader{Key: []byte(HeaderContentType), Value: []byte(encoding.ContentType())}
+}
@@ -0,0 +1,92 @@
+package kafka
+
+import (
+	"OrderSystemHighConcurrency/shared/models"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// benchOrder is a typical order with metadata and a few line items
+func benchOrder() *models.Order {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &models.Order{
+		OrderID:  "0f8fad5b-d9cb-469f-a165-70867728950e",
+		UserID:   "merchant-42",
+		Amount:   models.MustParseDecimal("149.97"),
+		Currency: "USD",
+		Status:   models.OrderStatusQueued,
+		Source:   "web",
+		Metadata: map[string]string{
+			models.MetadataTenantID: "tenant-7",
+			"channel":               "checkout",
+		},
+		Items: []models.LineItem{
+			{SKU: "SKU-1001", Quantity: 1, UnitPrice: models.MustParseDecimal("99.99")},
+			{SKU: "SKU-2002", Quantity: 2, UnitPrice: models.MustParseDecimal("24.99")},
+		},
+		Transitions: []models.StatusTransition{
+			{From: models.OrderStatusCreated, To: models.OrderStatusQueued, At: now, Reason: "accepted by order-api"},
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func BenchmarkEncodeJSON(b *testing.B) {
+	benchmarkEncode(b, EncodingJSON)
+}
+
+func BenchmarkEncodeProtobuf(b *testing.B) {
+	benchmarkEncode(b, EncodingProtobuf)
+}
+
+func BenchmarkDecodeJSON(b *testing.B) {
+	benchmarkDecode(b, EncodingJSON)
+}
+
+func BenchmarkDecodeProtobuf(b *testing.B) {
+	benchmarkDecode(b, EncodingProtobuf)
+}
+
+func benchmarkEncode(b *testing.B, encoding Encoding) {
+	order := benchOrder()
+
+	var size int
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		payload, err := EncodeOrderEvent(encoding, models.EventOrderCreated, "bench", order)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = len(payload)
+	}
+
+	b.ReportMetric(float64(size), "bytes/msg")
+}
+
+func benchmarkDecode(b *testing.B, encoding Encoding) {
+	payload, err := EncodeOrderEvent(encoding, models.EventOrderCreated, "bench", benchOrder())
+	if err != nil {
+		b.Fatal(err)
+	}
+	msg := &sarama.ConsumerMessage{
+		Value:   payload,
+		Headers: []*sarama.RecordHeader{{Key: []byte(HeaderContentType), Value: []byte(encoding.ContentType())}},
+	}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(payload)))
+	for i := 0; i < b.N; i++ {
+		event, err := DecodeEvent(msg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := event.Order(); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportMetric(float64(len(payload)), "bytes/msg")
+}
@@ -0,0 +1,34 @@
+package kafka
+
+import "context"
+
+type contextKey int
+
+const (
+	traceIDKey contextKey = iota
+	partitionKey
+)
+
+// WithTraceID attaches a trace ID that the producer forwards as HeaderTraceID
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	if traceID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, traceIDKey, traceID)
+}
+
+// TraceIDFromContext returns the trace ID set by WithTraceID, or ""
+func TraceIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey).(string)
+	return id
+}
+
+// WithPartition selects the partition used by the manual partitioner
+func WithPartition(ctx context.Context, partition int32) context.Context {
+	return context.WithValue(ctx, partitionKey, partition)
+}
+
+func partitionFromContext(ctx context.Context) (int32, bool) {
+	p, ok := ctx.Value(partitionKey).(int32)
+	return p, ok
+}
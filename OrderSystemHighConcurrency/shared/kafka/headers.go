@@ -0,0 +1,68 @@
+package kafka
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// Kafka record headers used across services
+const (
+	// HeaderNotBefore holds the earliest time (unix milliseconds) at which a
+	// retried message may be processed
+	HeaderNotBefore = "not-before"
+
+	// HeaderRetryAttempt holds the attempt number of a retried message
+	HeaderRetryAttempt = "retry-attempt"
+
+	// HeaderRetryReason holds the error that caused the retry
+	HeaderRetryReason = "retry-reason"
+
+	// HeaderReplayAudit holds a JSON record of where a replayed DLQ message came from
+	HeaderReplayAudit = "replay-audit"
+
+	// HeaderTraceID carries the trace ID of the request that produced the message
+	HeaderTraceID = "trace-id"
+
+	// HeaderSchemaVersion holds the version of the payload schema
+	HeaderSchemaVersion = "schema-version"
+
+	// HeaderSource names the service that produced the message
+	HeaderSource = "source"
+
+	// HeaderContentType names the payload encoding (see ContentTypeJSON)
+	HeaderContentType = "content-type"
+
+	// HeaderSchemaID holds the schema registry ID of the payload schema
+	HeaderSchemaID = "schema-id"
+)
+
+// Header returns the value of the named header, or "" when absent
+func Header(msg *sarama.ConsumerMessage, key string) string {
+	for _, h := range msg.Headers {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// NotBefore decodes HeaderNotBefore; ok is false when the header is missing or invalid
+func NotBefore(msg *sarama.ConsumerMessage) (time.Time, bool) {
+	v := Header(msg, HeaderNotBefore)
+	if v == "" {
+		return time.Time{}, false
+	}
+
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+// EncodeNotBefore formats t for HeaderNotBefore
+func EncodeNotBefore(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
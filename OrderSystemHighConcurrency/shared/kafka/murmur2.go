@@ -0,0 +1,67 @@
+package kafka
+
+import "hash"
+
+// murmur2 is the 32-bit MurmurHash2 used by the Java Kafka client's default
+// partitioner, so keys land on the same partitions as Java producers.
+type murmur2 struct {
+	data []byte
+}
+
+// NewMurmur2 returns a hash.Hash32 matching org.apache.kafka.common.utils.Utils.murmur2
+func NewMurmur2() hash.Hash32 {
+	return &murmur2{}
+}
+
+func (m *murmur2) Write(p []byte) (int, error) {
+	m.data = append(m.data, p...)
+	return len(p), nil
+}
+
+func (m *murmur2) Sum(b []byte) []byte {
+	h := m.Sum32()
+	return append(b, byte(h>>24), byte(h>>16), byte(h>>8), byte(h))
+}
+
+func (m *murmur2) Reset()         { m.data = m.data[:0] }
+func (m *murmur2) Size() int      { return 4 }
+func (m *murmur2) BlockSize() int { return 4 }
+
+func (m *murmur2) Sum32() uint32 {
+	const (
+		seed uint32 = 0x9747b28c
+		mul  uint32 = 0x5bd1e995
+		r           = 24
+	)
+
+	data := m.data
+	length := len(data)
+	h := seed ^ uint32(length)
+
+	for i := 0; i+4 <= length; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= mul
+		k ^= k >> r
+		k *= mul
+		h *= mul
+		h ^= k
+	}
+
+	tail := length &^ 3
+	switch length % 4 {
+	case 3:
+		h ^= uint32(data[tail+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[tail+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[tail])
+		h *= mul
+	}
+
+	h ^= h >> 13
+	h *= mul
+	h ^= h >> 15
+	return h
+}
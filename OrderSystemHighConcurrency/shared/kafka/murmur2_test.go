@@ -0,0 +1,56 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/IBM/sarama"
+)
+
+// javaMurmur2 holds Utils.murmur2 results of the Java client, taken from
+// its UtilsTest, and Utils.toPositive(hash) % n for a few partition counts
+var javaMurmur2 = []struct {
+	key        string
+	hash       int32
+	partitions map[int32]int32 // partition count -> partition
+}{
+	{"21", -973932308, map[int32]int32{3: 0, 6: 0, 12: 0}},
+	{"foobar", -790332482, map[int32]int32{3: 0, 6: 0, 12: 6}},
+	{"a-little-bit-long-string", -985981536, map[int32]int32{3: 2, 6: 2, 12: 8}},
+	{"a-little-bit-longer-string", -1486304829, map[int32]int32{3: 2, 6: 5, 12: 11}},
+	{"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8", -58897971, map[int32]int32{3: 2, 6: 5, 12: 5}},
+	{"abc", 479470107, map[int32]int32{3: 0, 6: 3, 12: 3}},
+}
+
+func TestMurmur2MatchesJava(t *testing.T) {
+	for _, tt := range javaMurmur2 {
+		h := NewMurmur2()
+		h.Write([]byte(tt.key))
+
+		if got := int32(h.Sum32()); got != tt.hash {
+			t.Errorf("murmur2(%q) = %d, want %d", tt.key, got, tt.hash)
+		}
+	}
+}
+
+// Negative hashes are where toPositive (hash & 0x7fffffff) and abs differ
+func TestMurmur2PartitionerMatchesJava(t *testing.T) {
+	constructor, err := NewPartitioner(PartitionerMurmur2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partitioner := constructor("orders")
+
+	for _, tt := range javaMurmur2 {
+		for n, want := range tt.partitions {
+			msg := &sarama.ProducerMessage{Topic: "orders", Key: sarama.StringEncoder(tt.key)}
+
+			got, err := partitioner.Partition(msg, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("partition of %q over %d partitions = %d, want %d", tt.key, n, got, want)
+			}
+		}
+	}
+}
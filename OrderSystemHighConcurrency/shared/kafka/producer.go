@@ -4,57 +4,131 @@ import (
 	"OrderSystemHighConcurrency/shared/contracts"
 	"OrderSystemHighConcurrency/shared/models"
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/IBM/sarama"
 )
 
+// KeyStrategy decides which order field becomes the message key.
+// Messages with the same key land on the same partition and stay in order.
+type KeyStrategy string
+
+const (
+	KeyByOrder  KeyStrategy = "order"
+	KeyByUser   KeyStrategy = "user"
+	KeyByTenant KeyStrategy = "tenant" // falls back to the user ID when no tenant is set
+)
+
+// Partitioner selects how keys are mapped to partitions
+type Partitioner string
+
+const (
+	// PartitionerHash is sarama's default FNV-1a hash
+	PartitionerHash Partitioner = "hash"
+
+	// PartitionerMurmur2 matches the Java client's default partitioner
+	PartitionerMurmur2 Partitioner = "murmur2"
+
+	// PartitionerManual uses the partition set with WithPartition (0 otherwise)
+	PartitionerManual Partitioner = "manual"
+)
+
+// ProducerConfig configures NewKafkaProducer
+type ProducerConfig struct {
+	Brokers       []string
+	Topic         string
+	KeyStrategy   KeyStrategy
+	Partitioner   Partitioner
+	Source        string // sent as HeaderSource and recorded as the event producer
+	SchemaVersion string // sent as HeaderSchemaVersion
+	Encoding      Encoding
+	SchemaID      int // registry ID sent as HeaderSchemaID; 0 when no registry is used
+}
+
 // kafkaProducer implements contracts.Producer
 type kafkaProducer struct {
-	producer sarama.SyncProducer
-	topic    string
+	producer      sarama.SyncProducer
+	topic         string
+	keyStrategy   KeyStrategy
+	manual        bool
+	source        string
+	schemaVersion string
+	encoding      Encoding
+	schemaID      string
 }
 
 // NewKafkaProducer creates a new Kafka producer
-func NewKafkaProducer(brokers []string, topic string) (contracts.Producer, error) {
-	if len(brokers) == 0 {
+func NewKafkaProducer(cfg ProducerConfig) (contracts.Producer, error) {
+	if len(cfg.Brokers) == 0 {
 		return nil, errors.New("kafka brokers required")
 	}
 
+	encoding, err := ParseEncoding(string(cfg.Encoding))
+	if err != nil {
+		return nil, err
+	}
+
+	keyStrategy, err := ParseKeyStrategy(string(cfg.KeyStrategy))
+	if err != nil {
+		return nil, err
+	}
+	partitioner, err := NewPartitioner(cfg.Partitioner)
+	if err != nil {
+		return nil, err
+	}
+
 	config := sarama.NewConfig()
 	config.Version = sarama.V2_8_0_0
 	config.Producer.RequiredAcks = sarama.WaitForAll
 	config.Producer.Retry.Max = 5
 	config.Producer.Return.Successes = true
 	config.Producer.Timeout = 5 * time.Second
+	config.Producer.Partitioner = partitioner
 
-	producer, err := sarama.NewSyncProducer(brokers, config)
+	producer, err := sarama.NewSyncProducer(cfg.Brokers, config)
 	if err != nil {
 		return nil, err
 	}
 
 	return &kafkaProducer{
-		producer: producer,
-		topic:    topic,
+		producer:      producer,
+		topic:         cfg.Topic,
+		keyStrategy:   keyStrategy,
+		manual:        cfg.Partitioner == PartitionerManual,
+		source:        cfg.Source,
+		schemaVersion: cfg.SchemaVersion,
+		encoding:      encoding,
+		schemaID:      schemaIDHeader(cfg.SchemaID),
 	}, nil
 }
 
 // Publish sends an order to Kafka
 func (k *kafkaProducer) Publish(ctx context.Context, order *models.Order) error {
+	return k.PublishEvent(ctx, models.EventOrderCreated, order)
+}
+
+// PublishEvent sends an event about the order to Kafka
+func (k *kafkaProducer) PublishEvent(ctx context.Context, eventType models.EventType, order *models.Order) error {
 	if order == nil {
 		return errors.New("order is nil")
 	}
 
-	payload, err := json.Marshal(order)
+	payload, err := EncodeOrderEvent(k.encoding, eventType, k.source, order)
 	if err != nil {
 		return err
 	}
 
 	msg := &sarama.ProducerMessage{
-		Topic: k.topic,
-		Value: sarama.ByteEncoder(payload),
+		Topic:   k.topic,
+		Key:     sarama.StringEncoder(k.key(order)),
+		Value:   sarama.ByteEncoder(payload),
+		Headers: k.headers(ctx),
+	}
+	if k.manual {
+		msg.Partition, _ = partitionFromContext(ctx)
 	}
 
 	// Respect context cancellation
@@ -67,6 +141,78 @@ func (k *kafkaProducer) Publish(ctx context.Context, order *models.Order) error
 	}
 }
 
+func (k *kafkaProducer) key(order *models.Order) string {
+	return MessageKey(k.keyStrategy, order)
+}
+
+// ParseKeyStrategy validates a key strategy name; "" is KeyByUser
+func ParseKeyStrategy(s string) (KeyStrategy, error) {
+	switch strategy := KeyStrategy(s); strategy {
+	case "":
+		return KeyByUser, nil
+	case KeyByOrder, KeyByUser, KeyByTenant:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown key strategy %q", s)
+	}
+}
+
+// MessageKey returns the key the strategy gives the order's messages.
+// Every producer of order events uses it, so retried and replayed events
+// stay on the partition of the order's other events.
+func MessageKey(strategy KeyStrategy, order *models.Order) string {
+	switch strategy {
+	case KeyByOrder:
+		return order.OrderID
+	case KeyByTenant:
+		if tenant := order.TenantID(); tenant != "" {
+			return tenant
+		}
+	}
+	return order.UserID
+}
+
+// NewPartitioner returns the sarama partitioner for p; "" is PartitionerHash
+func NewPartitioner(p Partitioner) (sarama.PartitionerConstructor, error) {
+	switch p {
+	case "", PartitionerHash:
+		return sarama.NewHashPartitioner, nil
+	case PartitionerMurmur2:
+		return sarama.NewCustomPartitioner(
+			sarama.WithAbsFirst(),
+			sarama.WithCustomHashFunction(NewMurmur2),
+		), nil
+	case PartitionerManual:
+		return sarama.NewManualPartitioner, nil
+	default:
+		return nil, fmt.Errorf("unknown partitioner %q", p)
+	}
+}
+
+func (k *kafkaProducer) headers(ctx context.Context) []sarama.RecordHeader {
+	headers := []sarama.RecordHeader{ContentTypeHeader(k.encoding)}
+
+	add := func(key, value string) {
+		if value != "" {
+			headers = append(headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+		}
+	}
+	add(HeaderTraceID, TraceIDFromContext(ctx))
+	add(HeaderSchemaVersion, k.schemaVersion)
+	add(HeaderSource, k.source)
+	add(HeaderSchemaID, k.schemaID)
+
+	return headers
+}
+
+// schemaIDHeader formats a registry ID for HeaderSchemaID; "" when unset
+func schemaIDHeader(id int) string {
+	if id <= 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
+
 func (p *kafkaProducer) Close() error {
 	if p.producer != nil {
 		return p.producer.Close()
@@ -0,0 +1,58 @@
+package kafka
+
+import (
+	"OrderSystemHighConcurrency/shared/contracts"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// position holds the last offset seen on each partition of the status topic
+type position map[int32]int64
+
+// encodeCursor renders the position as an opaque cursor
+func encodeCursor(pos position) string {
+	partitions := make([]int32, 0, len(pos))
+	for p := range pos {
+		partitions = append(partitions, p)
+	}
+	slices.Sort(partitions)
+
+	parts := make([]string, len(partitions))
+	for i, p := range partitions {
+		parts[i] = fmt.Sprintf("%d:%d", p, pos[p])
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(parts, ",")))
+}
+
+// decodeCursor parses a cursor from encodeCursor; "" yields nil
+func decodeCursor(cursor string) (position, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, contracts.ErrInvalidCursor
+	}
+
+	pos := make(position)
+	for _, part := range strings.Split(string(raw), ",") {
+		p, o, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, contracts.ErrInvalidCursor
+		}
+		partition, err := strconv.ParseInt(p, 10, 32)
+		if err != nil {
+			return nil, contracts.ErrInvalidCursor
+		}
+		offset, err := strconv.ParseInt(o, 10, 64)
+		if err != nil {
+			return nil, contracts.ErrInvalidCursor
+		}
+		pos[int32(partition)] = offset
+	}
+	return pos, nil
+}
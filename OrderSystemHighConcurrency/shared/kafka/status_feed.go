@@ -0,0 +1,357 @@
+package kafka
+
+import (
+	"OrderSystemHighConcurrency/shared/contracts"
+	"OrderSystemHighConcurrency/shared/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+// watcherBuffer is how many matching updates may queue up for a watcher
+// before it is dropped as too slow
+const watcherBuffer = 256
+
+// delivery is a status event read from the topic
+type delivery struct {
+	partition int32
+	offset    int64
+	order     *models.Order
+}
+
+// watcher is a registered Watch call
+type watcher struct {
+	filter  contracts.StatusFilter
+	updates chan delivery
+	dropped chan struct{} // closed when the feed gives up on the watcher
+}
+
+// StatusFeed implements contracts.StatusFeed. One reader tails every
+// partition of the status topic and fans matching events out to watchers;
+// a watcher resuming from a cursor first replays what it missed with a
+// consumer of its own.
+type StatusFeed struct {
+	client   sarama.Client
+	consumer sarama.Consumer
+	topic    string
+
+	mu       sync.Mutex
+	next     position // next offset the tail reads, per partition
+	watchers map[*watcher]struct{}
+}
+
+// NewStatusFeed connects to the status topic. Watchers see changes
+// published from the moment the feed was created.
+func NewStatusFeed(brokers []string, topic string) (*StatusFeed, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("kafka brokers required")
+	}
+
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.V2_8_0_0
+
+	client, err := sarama.NewClient(brokers, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("status topic %s: %w", topic, err)
+	}
+
+	next := make(position, len(partitions))
+	for _, p := range partitions {
+		offset, err := client.GetOffset(topic, p, sarama.OffsetNewest)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+		next[p] = offset
+	}
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &StatusFeed{
+		client:   client,
+		consumer: consumer,
+		topic:    topic,
+		next:     next,
+		watchers: make(map[*watcher]struct{}),
+	}, nil
+}
+
+// Start tails every partition until ctx is done
+func (f *StatusFeed) Start(ctx context.Context) error {
+	f.mu.Lock()
+	start := make(position, len(f.next))
+	for p, offset := range f.next {
+		start[p] = offset
+	}
+	f.mu.Unlock()
+
+	for p, offset := range start {
+		pc, err := f.consumer.ConsumePartition(f.topic, p, offset)
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			defer pc.Close()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case msg, ok := <-pc.Messages():
+					if !ok {
+						return
+					}
+					f.dispatch(msg)
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+// dispatch advances the tail and hands the event to matching watchers.
+// A watcher whose buffer is full is dropped rather than slowing down the
+// others.
+func (f *StatusFeed) dispatch(msg *sarama.ConsumerMessage) {
+	order := DecodeStatus(msg)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.next[msg.Partition] = msg.Offset + 1
+	if order == nil {
+		return
+	}
+
+	for w := range f.watchers {
+		if !w.filter.Match(order) {
+			continue
+		}
+		select {
+		case w.updates <- delivery{partition: msg.Partition, offset: msg.Offset, order: order}:
+		default:
+			delete(f.watchers, w)
+			close(w.dropped)
+		}
+	}
+}
+
+// Watch replays the changes after cursor, then follows the tail. The
+// replay is delivered straight to fn; the watcher is only attached to the
+// tail once it is close enough for its buffer to hold what arrives while
+// the rest is replayed.
+func (f *StatusFeed) Watch(ctx context.Context, filter contracts.StatusFilter, cursor string, fn func(contracts.StatusUpdate) error) error {
+	from, err := decodeCursor(cursor)
+	if err != nil {
+		return err
+	}
+
+	// Without a cursor only changes from now on are delivered
+	seen := f.position()
+	for p, next := range seen {
+		seen[p] = next - 1
+		if offset, ok := from[p]; ok && offset < seen[p] {
+			seen[p] = offset
+		}
+	}
+
+	for {
+		target := f.position()
+		if lag(seen, target) <= watcherBuffer/2 {
+			break
+		}
+		if err := f.catchUp(ctx, filter, seen, target, fn); err != nil {
+			return err
+		}
+	}
+
+	w := &watcher{
+		filter:  filter,
+		updates: make(chan delivery, watcherBuffer),
+		dropped: make(chan struct{}),
+	}
+	start := f.register(w)
+	defer f.unregister(w)
+
+	// Changes from the tail's position at registration on arrive through
+	// the watcher; the few before it are replayed
+	if err := f.catchUp(ctx, filter, seen, start, fn); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.dropped:
+			return contracts.ErrWatcherTooSlow
+		case d := <-w.updates:
+			if d.offset <= seen[d.partition] {
+				continue
+			}
+			seen[d.partition] = d.offset
+			if err := fn(contracts.StatusUpdate{Order: d.order, Cursor: encodeCursor(seen)}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Cursor returns a cursor at the tail's current position
+func (f *StatusFeed) Cursor() (string, error) {
+	seen := f.position()
+	for p, next := range seen {
+		seen[p] = next - 1
+	}
+	return encodeCursor(seen), nil
+}
+
+// catchUp replays every partition from just after seen up to target
+func (f *StatusFeed) catchUp(
+	ctx context.Context,
+	filter contracts.StatusFilter,
+	seen, target position,
+	fn func(contracts.StatusUpdate) error,
+) error {
+	partitions := make([]int32, 0, len(seen))
+	for p := range seen {
+		partitions = append(partitions, p)
+	}
+	slices.Sort(partitions)
+
+	for _, p := range partitions {
+		if seen[p] < target[p]-1 {
+			if err := f.replay(ctx, filter, p, target[p], seen, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lag counts the changes between seen and target
+func lag(seen, target position) int64 {
+	var n int64
+	for p, next := range target {
+		n += max(next-1-seen[p], 0)
+	}
+	return n
+}
+
+// replay reads partition p from just after seen[p] up to end (exclusive)
+// and delivers the matching changes
+func (f *StatusFeed) replay(
+	ctx context.Context,
+	filter contracts.StatusFilter,
+	p int32,
+	end int64,
+	seen position,
+	fn func(contracts.StatusUpdate) error,
+) error {
+	// Offsets removed by retention can't be replayed
+	oldest, err := f.client.GetOffset(f.topic, p, sarama.OffsetOldest)
+	if err != nil {
+		return err
+	}
+	from := max(seen[p]+1, oldest)
+	if from >= end {
+		seen[p] = end - 1
+		return nil
+	}
+
+	consumer, err := sarama.NewConsumerFromClient(f.client)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	pc, err := consumer.ConsumePartition(f.topic, p, from)
+	if err != nil {
+		return err
+	}
+	defer pc.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-pc.Messages():
+			if !ok {
+				return fmt.Errorf("replay of partition %d stopped", p)
+			}
+
+			seen[p] = msg.Offset
+			if order := DecodeStatus(msg); order != nil && filter.Match(order) {
+				if err := fn(contracts.StatusUpdate{Order: order, Cursor: encodeCursor(seen)}); err != nil {
+					return err
+				}
+			}
+			if msg.Offset >= end-1 {
+				return nil
+			}
+		}
+	}
+}
+
+// register adds the watcher and returns the tail's position at that moment
+func (f *StatusFeed) register(w *watcher) position {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.watchers[w] = struct{}{}
+	return maps.Clone(f.next)
+}
+
+// position returns the next offset the tail reads, per partition
+func (f *StatusFeed) position() position {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return maps.Clone(f.next)
+}
+
+func (f *StatusFeed) unregister(w *watcher) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.watchers, w)
+}
+
+// Close stops the tail and disconnects
+func (f *StatusFeed) Close() error {
+	if err := f.consumer.Close(); err != nil {
+		log.Printf("status feed consumer close failed: %v", err)
+	}
+	return f.client.Close()
+}
+
+// DecodeStatus returns the order of an ORDER_STATUS_CHANGED event, or nil
+// for other or unreadable messages
+func DecodeStatus(msg *sarama.ConsumerMessage) *models.Order {
+	event, err := DecodeEvent(msg)
+	if err != nil || event.Type != models.EventOrderStatusChanged {
+		return nil
+	}
+
+	order, err := event.Order()
+	if err != nil {
+		return nil
+	}
+	return order
+}
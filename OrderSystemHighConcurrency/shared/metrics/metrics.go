@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -9,12 +10,17 @@ import (
 
 var (
 	// define a map to hold counters dynamically
+	mu         sync.Mutex
 	counters   = map[string]prometheus.Counter{}
 	histograms = map[string]prometheus.Histogram{}
+	gauges     = map[string]prometheus.Gauge{}
 )
 
 // IncrementCounter increments a Prometheus counter by 1
 func IncrementCounter(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+
 	counter, ok := counters[name]
 	if !ok {
 		counter = promauto.NewCounter(prometheus.CounterOpts{
@@ -28,6 +34,9 @@ func IncrementCounter(name string) {
 
 // ObserveDuration records a duration for a metric
 func ObserveDuration(name string, duration float64) {
+	mu.Lock()
+	defer mu.Unlock()
+
 	hist, ok := histograms[name]
 	if !ok {
 		hist = promauto.NewHistogram(prometheus.HistogramOpts{
@@ -40,6 +49,22 @@ func ObserveDuration(name string, duration float64) {
 	hist.Observe(duration)
 }
 
+// SetGauge sets a Prometheus gauge to the given value
+func SetGauge(name string, value float64) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	gauge, ok := gauges[name]
+	if !ok {
+		gauge = promauto.NewGauge(prometheus.GaugeOpts{
+			Name: name,
+			Help: name + " gauge",
+		})
+		gauges[name] = gauge
+	}
+	gauge.Set(value)
+}
+
 // Timer helper to measure duration easily
 func Timer(name string) func() {
 	start := time.Now()
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultCurrency is assumed for orders that don't name a currency
+const DefaultCurrency = "USD"
+
+var (
+	ErrUnknownCurrency = errors.New("unknown currency")
+	ErrAmountPrecision = errors.New("amount has more decimal places than the currency allows")
+)
+
+// currencyExponents holds the ISO 4217 minor unit exponent of the
+// currencies we accept
+var currencyExponents = map[string]int{
+	// Zero decimal places
+	"CLP": 0, "ISK": 0, "JPY": 0, "KRW": 0, "PYG": 0, "UGX": 0, "VND": 0, "XAF": 0, "XOF": 0,
+
+	// Two decimal places
+	"AED": 2, "AUD": 2, "BRL": 2, "CAD": 2, "CHF": 2, "CNY": 2, "CZK": 2, "DKK": 2,
+	"EUR": 2, "GBP": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "MXN": 2,
+	"MYR": 2, "NOK": 2, "NZD": 2, "PHP": 2, "PLN": 2, "SAR": 2, "SEK": 2, "SGD": 2,
+	"THB": 2, "TRY": 2, "TWD": 2, "USD": 2, "ZAR": 2,
+
+	// Three decimal places
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// CurrencyExponent returns the number of minor unit digits of an ISO 4217 code
+func CurrencyExponent(code string) (int, error) {
+	exp, ok := currencyExponents[strings.ToUpper(code)]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	return exp, nil
+}
@@ -0,0 +1,9 @@
+package models
+
+import "errors"
+
+// ErrOrderNotFound is returned by read stores when no order matches the lookup.
+var ErrOrderNotFound = errors.New("order not found")
+
+// ErrCancelTooLate is returned when cancelling an order that has already completed.
+var ErrCancelTooLate = errors.New("order already completed, too late to cancel")
@@ -0,0 +1,351 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact base-10 number: coef × 10^-scale.
+// Amounts are parsed from their text form, never through float64, so
+// 0.1 + 0.2 is exactly 0.3.
+type Decimal struct {
+	coef  int64
+	scale int32
+}
+
+// maxScale keeps 10^scale inside int64
+const maxScale = 18
+
+var (
+	ErrInvalidDecimal  = errors.New("invalid decimal")
+	ErrDecimalOverflow = errors.New("decimal overflow")
+)
+
+// NewDecimal returns coef × 10^-scale
+func NewDecimal(coef int64, scale int32) Decimal {
+	return Decimal{coef: coef, scale: scale}.normalize()
+}
+
+// ParseDecimal parses a plain or exponent decimal literal such as "12.34",
+// "-5", "1e-2" or "12.50"
+func ParseDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Decimal{}, ErrInvalidDecimal
+	}
+
+	mantissa, exp := s, int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+		}
+		mantissa, exp = s[:i], e
+	}
+
+	intPart, fracPart, _ := strings.Cut(mantissa, ".")
+	digits := intPart + fracPart
+	if strings.TrimLeft(digits, "+-") == "" || strings.ContainsAny(digits[1:], "+-") {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+	}
+
+	coef, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return Decimal{}, fmt.Errorf("%w: %q", ErrDecimalOverflow, s)
+		}
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+	}
+	if coef == 0 {
+		return Decimal{}, nil
+	}
+
+	// Beyond this bound the value overflows or needs more than maxScale
+	// decimal places whatever the digits are; rejecting it up front keeps
+	// exponents like 1e2000000000 from spinning through mul10
+	if limit := int64(maxScale + len(digits)); exp > limit || -exp > limit {
+		return Decimal{}, fmt.Errorf("%w: %q exponent out of range", ErrDecimalOverflow, s)
+	}
+
+	scale := int64(len(fracPart)) - exp
+	for scale < 0 {
+		if coef, err = mul10(coef); err != nil {
+			return Decimal{}, err
+		}
+		scale++
+	}
+	if scale > maxScale {
+		return Decimal{}, fmt.Errorf("%w: %q has too many decimal places", ErrInvalidDecimal, s)
+	}
+
+	return Decimal{coef: coef, scale: int32(scale)}.normalize(), nil
+}
+
+// MustParseDecimal is ParseDecimal for constants; it panics on error
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// DecimalFromMinor converts an amount in minor units (cents) to a Decimal
+func DecimalFromMinor(minor int64, exponent int) Decimal {
+	return Decimal{coef: minor, scale: int32(exponent)}.normalize()
+}
+
+// DecimalFromFloat converts a float using its shortest exact representation.
+// It exists for clients that still send amounts as binary floats.
+func DecimalFromFloat(f float64) (Decimal, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{}, ErrInvalidDecimal
+	}
+	return ParseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// Scale returns the number of digits after the decimal point
+func (d Decimal) Scale() int {
+	return int(d.scale)
+}
+
+// Sign returns -1, 0 or 1
+func (d Decimal) Sign() int {
+	switch {
+	case d.coef < 0:
+		return -1
+	case d.coef > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// IsZero reports whether d is zero
+func (d Decimal) IsZero() bool {
+	return d.coef == 0
+}
+
+// Cmp compares d and o and returns -1, 0 or 1
+func (d Decimal) Cmp(o Decimal) int {
+	a, b, err := align(d, o)
+	if err != nil {
+		// Only reachable with extreme magnitudes, which still compare exactly
+		return d.big(o.scale).Cmp(o.big(d.scale))
+	}
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Add returns d + o
+func (d Decimal) Add(o Decimal) (Decimal, error) {
+	a, b, err := align(d, o)
+	if err != nil {
+		return Decimal{}, err
+	}
+
+	sum := a + b
+	if (a > 0 && b > 0 && sum < 0) || (a < 0 && b < 0 && sum >= 0) {
+		return Decimal{}, ErrDecimalOverflow
+	}
+	return Decimal{coef: sum, scale: max(d.scale, o.scale)}.normalize(), nil
+}
+
+// MulInt returns d × n
+func (d Decimal) MulInt(n int64) (Decimal, error) {
+	if d.coef == 0 || n == 0 {
+		return Decimal{}, nil
+	}
+
+	product := d.coef * n
+	if product/n != d.coef {
+		return Decimal{}, ErrDecimalOverflow
+	}
+	return Decimal{coef: product, scale: d.scale}.normalize(), nil
+}
+
+// MinorUnits returns d in units of 10^-exponent. It fails if d has more
+// decimal places than the exponent allows.
+func (d Decimal) MinorUnits(exponent int) (int64, error) {
+	if int(d.scale) > exponent {
+		return 0, fmt.Errorf("%w: %s has more than %d decimal places", ErrAmountPrecision, d, exponent)
+	}
+
+	minor := d.coef
+	for i := int(d.scale); i < exponent; i++ {
+		var err error
+		if minor, err = mul10(minor); err != nil {
+			return 0, err
+		}
+	}
+	return minor, nil
+}
+
+// Float64 returns the nearest float, for metrics and legacy consumers only
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// String formats d without exponent, e.g. "12.5" or "-0.05"
+func (d Decimal) String() string {
+	if d.scale == 0 {
+		return strconv.FormatInt(d.coef, 10)
+	}
+
+	sign := ""
+	coef := d.coef
+	if coef < 0 {
+		sign = "-"
+	}
+	digits := strconv.FormatUint(absUint(coef), 10)
+
+	if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	split := len(digits) - int(d.scale)
+	return sign + digits[:split] + "." + digits[split:]
+}
+
+// StringFixed formats d with exactly places decimal places, e.g. "12.50"
+func (d Decimal) StringFixed(places int) string {
+	if int(d.scale) >= places {
+		return d.String()
+	}
+
+	s := d.String()
+	if d.scale == 0 {
+		s += "."
+	}
+	return s + strings.Repeat("0", places-int(d.scale))
+}
+
+// MarshalJSON writes the amount as a JSON number so existing consumers
+// that decode it into float64 keep working
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a string holding a decimal. The
+// number's text is parsed directly, so float payloads stay exact.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		*d = Decimal{}
+		return nil
+	}
+
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value stores the amount as a decimal string; SQL Server converts it to
+// the DECIMAL column without going through float
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan reads DECIMAL (returned as text) and, for old FLOAT columns, float64
+func (d *Decimal) Scan(src any) error {
+	var (
+		parsed Decimal
+		err    error
+	)
+
+	switch v := src.(type) {
+	case nil:
+		parsed = Decimal{}
+	case []byte:
+		parsed, err = ParseDecimal(string(v))
+	case string:
+		parsed, err = ParseDecimal(v)
+	case int64:
+		parsed = Decimal{coef: v}
+	case float64:
+		parsed, err = DecimalFromFloat(v)
+	default:
+		err = fmt.Errorf("cannot scan %T into Decimal", src)
+	}
+
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// normalize drops trailing zeros so equal values have one representation
+func (d Decimal) normalize() Decimal {
+	if d.coef == 0 {
+		return Decimal{}
+	}
+	for d.scale > 0 && d.coef%10 == 0 {
+		d.coef /= 10
+		d.scale--
+	}
+	return d
+}
+
+// align brings a and b to the same scale and returns their coefficients
+func align(a, b Decimal) (int64, int64, error) {
+	x, y := a.coef, b.coef
+	var err error
+
+	for s := a.scale; s < b.scale; s++ {
+		if x, err = mul10(x); err != nil {
+			return 0, 0, err
+		}
+	}
+	for s := b.scale; s < a.scale; s++ {
+		if y, err = mul10(y); err != nil {
+			return 0, 0, err
+		}
+	}
+	return x, y, nil
+}
+
+func mul10(v int64) (int64, error) {
+	if v > math.MaxInt64/10 || v < math.MinInt64/10 {
+		return 0, ErrDecimalOverflow
+	}
+	return v * 10, nil
+}
+
+func absUint(v int64) uint64 {
+	if v < 0 {
+		return uint64(-(v + 1)) + 1
+	}
+	return uint64(v)
+}
+
+// big returns the coefficient of d at the larger of its scale and scale
+func (d Decimal) big(scale int32) *big.Int {
+	v := big.NewInt(d.coef)
+	if scale > d.scale {
+		pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale-d.scale)), nil)
+		v.Mul(v, pow)
+	}
+	return v
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr error
+	}{
+		{in: "12.34", want: "12.34"},
+		{in: "12.50", want: "12.5"},
+		{in: "-5", want: "-5"},
+		{in: "1e-2", want: "0.01"},
+		{in: "1.5E3", want: "1500"},
+		{in: "0e2000000000", want: "0"},
+		{in: "0.000e-2000000000", want: "0"},
+		{in: "1e2000000000", wantErr: ErrDecimalOverflow},
+		{in: "1e-2000000000", wantErr: ErrDecimalOverflow},
+		{in: "9e18", want: "9000000000000000000"},
+		{in: "1e19", wantErr: ErrDecimalOverflow},
+		{in: "1e-19", wantErr: ErrInvalidDecimal},
+		{in: "99999999999999999999", wantErr: ErrDecimalOverflow},
+		{in: "", wantErr: ErrInvalidDecimal},
+		{in: "1-2", wantErr: ErrInvalidDecimal},
+		{in: "1e", wantErr: ErrInvalidDecimal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			start := time.Now()
+			got, err := ParseDecimal(tt.in)
+			if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+				t.Errorf("ParseDecimal(%q) took %s", tt.in, elapsed)
+			}
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseDecimal(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDecimal(%q): %v", tt.in, err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("ParseDecimal(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecimalCmp(t *testing.T) {
+	tests := []struct {
+		a, b Decimal
+		want int
+	}{
+		{MustParseDecimal("0.1"), MustParseDecimal("0.10"), 0},
+		{MustParseDecimal("1.01"), MustParseDecimal("1.1"), -1},
+		{MustParseDecimal("-2"), MustParseDecimal("-3"), 1},
+
+		// Aligning these overflows int64, so they are compared as big integers
+		{NewDecimal(9223372036854775807, 0), NewDecimal(9223372036854775807, 1), 1},
+		{NewDecimal(9223372036854775806, 18), NewDecimal(9223372036854775807, 18), -1},
+		{NewDecimal(922337203685477580, 0), NewDecimal(9223372036854775801, 1), -1},
+		{NewDecimal(-9223372036854775807, 0), NewDecimal(1, 18), -1},
+	}
+
+	for _, tt := range tests {
+		if got := tt.a.Cmp(tt.b); got != tt.want {
+			t.Errorf("%s.Cmp(%s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := tt.b.Cmp(tt.a); got != -tt.want {
+			t.Errorf("%s.Cmp(%s) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
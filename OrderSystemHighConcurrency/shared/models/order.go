@@ -1,6 +1,12 @@
 package models
 
-import "time"
+import (
+	"OrderSystemHighConcurrency/shared/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
 
 type OrderStatus string
 
@@ -10,23 +16,201 @@ const (
 	OrderStatusProcessing OrderStatus = "PROCESSING"
 	OrderStatusCompleted  OrderStatus = "COMPLETED"
 	OrderStatusFailed     OrderStatus = "FAILED"
+	OrderStatusCancelled  OrderStatus = "CANCELLED"
 )
 
 type Order struct {
 	OrderID    string            `json:"order_id"`
 	UserID     string            `json:"user_id"`
-	Amount     float64           `json:"amount"`
-	Currency   string            `json:"currency"`
+	Amount     Decimal           `json:"amount"`
+	Currency   string            `json:"currency"` // ISO 4217, DefaultCurrency when empty
 	Status     OrderStatus       `json:"status"`
 	Source     string            `json:"source"` // web, pos, mobile
 	RetryCount int               `json:"retry_count"`
 	Metadata   map[string]string `json:"metadata"`
+	Items      []LineItem        `json:"items,omitempty"`
+
+	// Transitions is the status history, appended to by TransitionTo
+	Transitions []StatusTransition `json:"transitions,omitempty"`
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// LineItem is a single product line of an order
+type LineItem struct {
+	SKU       string  `json:"sku"`
+	Quantity  int     `json:"quantity"`
+	UnitPrice Decimal `json:"unit_price"`
+}
+
+// MetadataTenantID is the metadata key holding the tenant an order belongs to
+const MetadataTenantID = "tenant_id"
+
+// TenantID returns the order's tenant from its metadata, or ""
+func (o *Order) TenantID() string {
+	return o.Metadata[MetadataTenantID]
+}
+
+// ErrAmountMismatch is returned when Amount differs from the sum of line items
+var ErrAmountMismatch = errors.New("amount does not match sum of line items")
+
+// CurrencyCode returns the order's currency, or DefaultCurrency when unset
+func (o *Order) CurrencyCode() string {
+	if o.Currency == "" {
+		return DefaultCurrency
+	}
+	return o.Currency
+}
+
+// ValidateAmount checks that the currency is known and that the amount and
+// unit prices don't have more decimal places than it allows (0 for JPY,
+// 2 for USD, 3 for BHD)
+func (o *Order) ValidateAmount() error {
+	exponent, err := CurrencyExponent(o.CurrencyCode())
+	if err != nil {
+		return err
+	}
+
+	if o.Amount.Sign() <= 0 {
+		return errors.New("amount must be greater than zero")
+	}
+	if _, err := o.Amount.MinorUnits(exponent); err != nil {
+		return err
+	}
+
+	for _, item := range o.Items {
+		if _, err := item.UnitPrice.MinorUnits(exponent); err != nil {
+			return fmt.Errorf("line item %s: %w", item.SKU, err)
+		}
+	}
+	return nil
+}
+
+// ItemsTotal returns the exact sum of quantity * unit price over all line items
+func (o *Order) ItemsTotal() (Decimal, error) {
+	var total Decimal
+	for _, item := range o.Items {
+		line, err := item.UnitPrice.MulInt(int64(item.Quantity))
+		if err != nil {
+			return Decimal{}, err
+		}
+		if total, err = total.Add(line); err != nil {
+			return Decimal{}, err
+		}
+	}
+	return total, nil
+}
+
+// ValidateItems checks every line item and, when items are present,
+// that Amount equals their total exactly
+func (o *Order) ValidateItems() error {
+	for _, item := range o.Items {
+		if item.SKU == "" {
+			return errors.New("line item sku is required")
+		}
+		if item.Quantity <= 0 {
+			return errors.New("line item quantity must be greater than zero")
+		}
+		if item.UnitPrice.Sign() < 0 {
+			return errors.New("line item unit_price must not be negative")
+		}
+	}
+
+	if len(o.Items) == 0 {
+		return nil
+	}
+
+	total, err := o.ItemsTotal()
+	if err != nil {
+		return err
+	}
+	if total.Cmp(o.Amount) != 0 {
+		return ErrAmountMismatch
+	}
+	return nil
+}
+
+// EventType identifies what happened to an order
+type EventType string
+
+const (
+	EventOrderCreated         EventType = "ORDER_CREATED"
+	EventOrderDeadLettered    EventType = "ORDER_DEAD_LETTERED"
+	EventOrderCancelRequested EventType = "ORDER_CANCEL_REQUESTED" // payload is the order as the requester knows it
+	EventOrderStatusChanged   EventType = "ORDER_STATUS_CHANGED"   // payload is the order after the change
+)
+
+// EventSchemaVersion is the envelope version written by this build
+const EventSchemaVersion = 1
+
+// OrderEvent is the envelope every Kafka payload is wrapped in.
+// Payload stays raw so consumers can skip event types they don't know
+// without having to understand their shape.
 type OrderEvent struct {
-	OrderID string `json:"order_id"`
-	Type    string `json:"type"` // ORDER_CREATED
-	Payload Order  `json:"payload"`
+	EventID       string          `json:"event_id"`
+	Type          EventType       `json:"type"`
+	SchemaVersion int             `json:"schema_version"`
+	ProducedAt    time.Time       `json:"produced_at"`
+	Producer      string          `json:"producer"`
+	OrderID       string          `json:"order_id"`
+	Payload       json.RawMessage `json:"payload"`
+
+	// order is set when the event was decoded from a non-JSON format
+	order *Order
+}
+
+// NewOrderEvent wraps payload in a new envelope
+func NewOrderEvent(eventType EventType, producer string, orderID string, payload any) (*OrderEvent, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OrderEvent{
+		EventID:       utils.GenerateID(),
+		Type:          eventType,
+		SchemaVersion: EventSchemaVersion,
+		ProducedAt:    time.Now().UTC(),
+		Producer:      producer,
+		OrderID:       orderID,
+		Payload:       raw,
+	}, nil
+}
+
+// DecodeOrderEvent decodes an envelope. Messages written before the
+// envelope existed hold a bare order; they are returned as an
+// ORDER_CREATED event with schema version 0.
+func DecodeOrderEvent(data []byte) (*OrderEvent, error) {
+	var event OrderEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, err
+	}
+
+	if event.Type == "" && len(event.Payload) == 0 {
+		return &OrderEvent{
+			Type:    EventOrderCreated,
+			OrderID: event.OrderID,
+			Payload: json.RawMessage(data),
+		}, nil
+	}
+	return &event, nil
+}
+
+// SetOrder attaches an already decoded order, for codecs other than JSON
+func (e *OrderEvent) SetOrder(order *Order) {
+	e.order = order
+}
+
+// Order decodes the payload of an event that carries an order
+func (e *OrderEvent) Order() (*Order, error) {
+	if e.order != nil {
+		return e.order, nil
+	}
+
+	var order Order
+	if err := json.Unmarshal(e.Payload, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
 }
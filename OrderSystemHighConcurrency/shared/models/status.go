@@ -0,0 +1,75 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidTransition is returned for status changes the lifecycle forbids
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// transitions lists the statuses each status may move to.
+// FAILED -> QUEUED is the manual replay from the DLQ and
+// PROCESSING -> QUEUED a scheduled retry. Anything not completed may be
+// cancelled; COMPLETED and CANCELLED are final.
+var transitions = map[OrderStatus][]OrderStatus{
+	OrderStatusCreated:    {OrderStatusQueued, OrderStatusFailed, OrderStatusCancelled},
+	OrderStatusQueued:     {OrderStatusProcessing, OrderStatusFailed, OrderStatusCancelled},
+	OrderStatusProcessing: {OrderStatusCompleted, OrderStatusQueued, OrderStatusFailed, OrderStatusCancelled},
+	OrderStatusFailed:     {OrderStatusQueued, OrderStatusCancelled},
+	OrderStatusCompleted:  {},
+	OrderStatusCancelled:  {},
+}
+
+// StatusTransition records one status change of an order
+type StatusTransition struct {
+	From   OrderStatus `json:"from"`
+	To     OrderStatus `json:"to"`
+	At     time.Time   `json:"at"`
+	Reason string      `json:"reason,omitempty"`
+}
+
+// Valid reports whether s is a known status
+func (s OrderStatus) Valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// CanTransition reports whether an order may move from one status to another
+func CanTransition(from, to OrderStatus) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transitions returns the allowed target statuses of every status
+func Transitions() map[OrderStatus][]OrderStatus {
+	copied := make(map[OrderStatus][]OrderStatus, len(transitions))
+	for from, to := range transitions {
+		copied[from] = append([]OrderStatus(nil), to...)
+	}
+	return copied
+}
+
+// TransitionTo moves the order to status, stamps UpdatedAt and records the
+// change with its reason. Illegal changes leave the order untouched.
+func (o *Order) TransitionTo(status OrderStatus, reason string) error {
+	if !CanTransition(o.Status, status) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, o.Status, status)
+	}
+
+	now := time.Now().UTC()
+	o.Transitions = append(o.Transitions, StatusTransition{
+		From:   o.Status,
+		To:     status,
+		At:     now,
+		Reason: reason,
+	})
+	o.Status = status
+	o.UpdatedAt = now
+	return nil
+}
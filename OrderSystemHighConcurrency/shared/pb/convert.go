@@ -0,0 +1,127 @@
+package pb
+
+import (
+	"OrderSystemHighConcurrency/shared/models"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FromModel converts a domain order to its protobuf form
+func FromModel(o *models.Order) *Order {
+	if o == nil {
+		return nil
+	}
+
+	p := &Order{
+		OrderId:       o.OrderID,
+		Amount:        o.Amount.Float64(),
+		AmountDecimal: o.Amount.String(),
+		UserId:        o.UserID,
+		Currency:      o.Currency,
+		Source:        o.Source,
+		Metadata:      o.Metadata,
+		Status:        string(o.Status),
+		RetryCount:    int32(o.RetryCount),
+		CreatedAt:     timestamp(o.CreatedAt),
+		UpdatedAt:     timestamp(o.UpdatedAt),
+	}
+
+	for _, item := range o.Items {
+		p.Items = append(p.Items, &LineItem{
+			Sku:              item.SKU,
+			Quantity:         int32(item.Quantity),
+			UnitPrice:        item.UnitPrice.Float64(),
+			UnitPriceDecimal: item.UnitPrice.String(),
+		})
+	}
+
+	for _, t := range o.Transitions {
+		p.Transitions = append(p.Transitions, FromTransition(t))
+	}
+	return p
+}
+
+// FromTransition converts a status transition to its protobuf form
+func FromTransition(t models.StatusTransition) *StatusTransition {
+	return &StatusTransition{
+		From:   string(t.From),
+		To:     string(t.To),
+		At:     timestamp(t.At),
+		Reason: t.Reason,
+	}
+}
+
+// ToModel converts the protobuf order to the domain type.
+// Clients that only send the deprecated double fields are converted via
+// the shortest decimal form of the float.
+func (p *Order) ToModel() (*models.Order, error) {
+	if p == nil {
+		return nil, nil
+	}
+
+	amount, err := decimal(p.AmountDecimal, p.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("amount: %w", err)
+	}
+
+	o := &models.Order{
+		OrderID:    p.OrderId,
+		Amount:     amount,
+		UserID:     p.UserId,
+		Currency:   p.Currency,
+		Source:     p.Source,
+		Metadata:   p.Metadata,
+		Status:     models.OrderStatus(p.Status),
+		RetryCount: int(p.RetryCount),
+	}
+	if p.CreatedAt != nil {
+		o.CreatedAt = p.CreatedAt.AsTime()
+	}
+	if p.UpdatedAt != nil {
+		o.UpdatedAt = p.UpdatedAt.AsTime()
+	}
+
+	for _, item := range p.Items {
+		price, err := decimal(item.UnitPriceDecimal, item.UnitPrice)
+		if err != nil {
+			return nil, fmt.Errorf("unit_price of %s: %w", item.Sku, err)
+		}
+
+		o.Items = append(o.Items, models.LineItem{
+			SKU:       item.Sku,
+			Quantity:  int(item.Quantity),
+			UnitPrice: price,
+		})
+	}
+
+	for _, t := range p.Transitions {
+		transition := models.StatusTransition{
+			From:   models.OrderStatus(t.From),
+			To:     models.OrderStatus(t.To),
+			Reason: t.Reason,
+		}
+		if t.At != nil {
+			transition.At = t.At.AsTime()
+		}
+		o.Transitions = append(o.Transitions, transition)
+	}
+	return o, nil
+}
+
+// decimal prefers the exact string form and falls back to the float
+func decimal(exact string, legacy float64) (models.Decimal, error) {
+	if exact != "" {
+		return models.ParseDecimal(exact)
+	}
+	return models.DecimalFromFloat(legacy)
+}
+
+// timestamp leaves zero times unset instead of encoding year 1
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
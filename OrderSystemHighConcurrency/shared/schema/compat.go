@@ -0,0 +1,103 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrIncompatible is returned when a schema breaks the compatibility rule
+var ErrIncompatible = errors.New("schema incompatible")
+
+// Compatibility is the rule a new schema version must satisfy
+type Compatibility string
+
+const (
+	// CompatibilityNone accepts every change
+	CompatibilityNone Compatibility = "NONE"
+
+	// CompatibilityBackward means the new schema can read data written with the previous one
+	CompatibilityBackward Compatibility = "BACKWARD"
+
+	// CompatibilityForward means the previous schema can read data written with the new one
+	CompatibilityForward Compatibility = "FORWARD"
+
+	// CompatibilityFull is both backward and forward
+	CompatibilityFull Compatibility = "FULL"
+)
+
+// ParseCompatibility validates a compatibility name; "" means BACKWARD
+func ParseCompatibility(s string) (Compatibility, error) {
+	switch c := Compatibility(strings.ToUpper(s)); c {
+	case "":
+		return CompatibilityBackward, nil
+	case CompatibilityNone, CompatibilityBackward, CompatibilityForward, CompatibilityFull:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unknown compatibility %q", s)
+	}
+}
+
+// Check verifies that next may follow previous under the given rule
+func Check(mode Compatibility, previous, next *Schema) error {
+	var problems []string
+
+	if mode == CompatibilityBackward || mode == CompatibilityFull {
+		problems = append(problems, Readable(previous, next)...)
+	}
+	if mode == CompatibilityForward || mode == CompatibilityFull {
+		problems = append(problems, Readable(next, previous)...)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w (%s): %s", ErrIncompatible, mode, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Readable lists why data written with writer cannot be read with reader.
+// A reader can't rely on a field the writer may leave out, and a field
+// must keep its type (integers may widen to numbers).
+func Readable(writer, reader *Schema) []string {
+	return readable(writer, reader, "$")
+}
+
+func readable(writer, reader *Schema, path string) []string {
+	if writer == nil || reader == nil || writer.Type == "" || reader.Type == "" {
+		return nil
+	}
+
+	if writer.Type != reader.Type && !(writer.Type == "integer" && reader.Type == "number") {
+		return []string{fmt.Sprintf("%s changed type from %s to %s", path, writer.Type, reader.Type)}
+	}
+
+	var problems []string
+
+	required := make(map[string]bool, len(writer.Required))
+	for _, name := range writer.Required {
+		required[name] = true
+	}
+	for _, name := range reader.Required {
+		if !required[name] {
+			problems = append(problems, fmt.Sprintf("%s.%s is required but may be missing", path, name))
+		}
+	}
+
+	names := make([]string, 0, len(reader.Properties))
+	for name := range reader.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if w, ok := writer.Properties[name]; ok {
+			problems = append(problems, readable(w, reader.Properties[name], path+"."+name)...)
+		}
+	}
+
+	problems = append(problems, readable(writer.Items, reader.Items, path+"[]")...)
+	problems = append(problems, readable(writer.AdditionalProperties, reader.AdditionalProperties, path+"{}")...)
+
+	return problems
+}
@@ -0,0 +1,162 @@
+package schema
+
+import (
+	"OrderSystemHighConcurrency/shared/contracts"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// registryFile is the on-disk layout of the file registry
+type registryFile struct {
+	NextID   int              `json:"next_id"`
+	Schemas  map[int]string   `json:"schemas"`
+	Subjects map[string][]int `json:"subjects"` // versions, oldest first
+}
+
+// fileRegistry implements contracts.SchemaRegistry on a single JSON file.
+// The file is re-read on every call so services sharing it on one machine
+// see each other's registrations.
+type fileRegistry struct {
+	mu            sync.Mutex
+	path          string
+	compatibility Compatibility
+}
+
+// NewFileRegistry opens (or creates on first Register) the registry at path
+func NewFileRegistry(path string, compatibility Compatibility) (contracts.SchemaRegistry, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("schema registry dir error: %w", err)
+	}
+
+	return &fileRegistry{
+		path:          path,
+		compatibility: compatibility,
+	}, nil
+}
+
+func (r *fileRegistry) Register(ctx context.Context, subject string, schema string) (int, error) {
+	next, err := Parse(schema)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema: %w", err)
+	}
+	canonical := next.String()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, err := r.load()
+	if err != nil {
+		return 0, err
+	}
+
+	versions := state.Subjects[subject]
+	for _, id := range versions {
+		if state.Schemas[id] == canonical {
+			return id, nil
+		}
+	}
+
+	if err := r.check(state, versions, next); err != nil {
+		return 0, fmt.Errorf("subject %s: %w", subject, err)
+	}
+
+	id := state.NextID
+	state.NextID++
+	state.Schemas[id] = canonical
+	state.Subjects[subject] = append(versions, id)
+
+	if err := r.save(state); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *fileRegistry) Lookup(ctx context.Context, id int) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, err := r.load()
+	if err != nil {
+		return "", err
+	}
+
+	schema, ok := state.Schemas[id]
+	if !ok {
+		return "", fmt.Errorf("schema %d not found", id)
+	}
+	return schema, nil
+}
+
+func (r *fileRegistry) Compatible(ctx context.Context, subject string, schema string) (bool, error) {
+	next, err := Parse(schema)
+	if err != nil {
+		return false, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, err := r.load()
+	if err != nil {
+		return false, err
+	}
+
+	err = r.check(state, state.Subjects[subject], next)
+	if errors.Is(err, ErrIncompatible) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// check compares next against the latest version of the subject
+func (r *fileRegistry) check(state *registryFile, versions []int, next *Schema) error {
+	if len(versions) == 0 || r.compatibility == CompatibilityNone {
+		return nil
+	}
+
+	latest, err := Parse(state.Schemas[versions[len(versions)-1]])
+	if err != nil {
+		return fmt.Errorf("stored schema corrupt: %w", err)
+	}
+	return Check(r.compatibility, latest, next)
+}
+
+func (r *fileRegistry) load() (*registryFile, error) {
+	state := &registryFile{
+		NextID:   1,
+		Schemas:  make(map[int]string),
+		Subjects: make(map[string][]int),
+	}
+
+	data, err := os.ReadFile(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("schema registry read error: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("schema registry corrupt: %w", err)
+	}
+	return state, nil
+}
+
+// save replaces the registry file atomically
+func (r *fileRegistry) save(state *registryFile) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("schema registry write error: %w", err)
+	}
+	return os.Rename(tmp, r.path)
+}
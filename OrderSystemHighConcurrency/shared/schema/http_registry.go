@@ -0,0 +1,124 @@
+package schema
+
+import (
+	"OrderSystemHighConcurrency/shared/contracts"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+// httpRegistry implements contracts.SchemaRegistry against the Confluent
+// Schema Registry REST API. Compatibility is enforced by the server.
+type httpRegistry struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPRegistry creates a client for the registry at baseURL
+func NewHTTPRegistry(baseURL string) contracts.SchemaRegistry {
+	return &httpRegistry{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// registryError is the error body returned by the registry
+type registryError struct {
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+func (r *httpRegistry) Register(ctx context.Context, subject string, schema string) (int, error) {
+	var resp struct {
+		ID int `json:"id"`
+	}
+
+	status, err := r.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", schemaRequest(schema), &resp)
+	if status == http.StatusConflict {
+		return 0, fmt.Errorf("subject %s: %w: %v", subject, ErrIncompatible, err)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return resp.ID, nil
+}
+
+func (r *httpRegistry) Lookup(ctx context.Context, id int) (string, error) {
+	var resp struct {
+		Schema string `json:"schema"`
+	}
+
+	if _, err := r.do(ctx, http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.Schema, nil
+}
+
+func (r *httpRegistry) Compatible(ctx context.Context, subject string, schema string) (bool, error) {
+	var resp struct {
+		IsCompatible bool `json:"is_compatible"`
+	}
+
+	path := "/compatibility/subjects/" + url.PathEscape(subject) + "/versions/latest"
+	status, err := r.do(ctx, http.MethodPost, path, schemaRequest(schema), &resp)
+	if status == http.StatusNotFound {
+		// No versions yet: anything is compatible
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return resp.IsCompatible, nil
+}
+
+func schemaRequest(schema string) any {
+	return map[string]string{
+		"schema":     schema,
+		"schemaType": "JSON",
+	}
+}
+
+// do sends a request and decodes the JSON response into out.
+// The status code is returned alongside errors so callers can map it.
+func (r *httpRegistry) do(ctx context.Context, method, path string, body any, out any) (int, error) {
+	var reader *bytes.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(payload)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.baseURL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", contentType)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("schema registry request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var regErr registryError
+		_ = json.NewDecoder(resp.Body).Decode(&regErr)
+		return resp.StatusCode, fmt.Errorf("schema registry %s %s: %d %s", method, path, resp.StatusCode, regErr.Message)
+	}
+
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,192 @@
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// stubRegistry is a minimal Confluent Schema Registry. Every schema is
+// compatible unless incompatible is set.
+type stubRegistry struct {
+	t            *testing.T
+	incompatible bool
+
+	mu       sync.Mutex
+	schemas  map[int]string
+	subjects map[string][]int
+}
+
+func newStubRegistry(t *testing.T) (*stubRegistry, *httptest.Server) {
+	stub := &stubRegistry{t: t, schemas: map[int]string{}, subjects: map[string][]int{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /subjects/{subject}/versions", stub.register)
+	mux.HandleFunc("GET /schemas/ids/{id}", stub.lookup)
+	mux.HandleFunc("POST /compatibility/subjects/{subject}/versions/latest", stub.compatible)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return stub, server
+}
+
+// add stores a schema directly, as another producer would have
+func (s *stubRegistry) add(subject, schema string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, existing := range s.schemas {
+		if existing == schema {
+			return id
+		}
+	}
+	id := len(s.schemas) + 1
+	s.schemas[id] = schema
+	s.subjects[subject] = append(s.subjects[subject], id)
+	return id
+}
+
+// decode reads a schema request, checking what every client must send
+func (s *stubRegistry) decode(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if got := r.Header.Get("Content-Type"); got != contentType {
+		s.t.Errorf("%s %s: content type %q, want %q", r.Method, r.URL.Path, got, contentType)
+	}
+
+	var req struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Schema == "" {
+		writeRegistryError(w, http.StatusUnprocessableEntity, 42201, "invalid schema")
+		return "", false
+	}
+	if req.SchemaType != "JSON" {
+		s.t.Errorf("schema type %q, want JSON", req.SchemaType)
+	}
+	return req.Schema, true
+}
+
+func (s *stubRegistry) register(w http.ResponseWriter, r *http.Request) {
+	schema, ok := s.decode(w, r)
+	if !ok {
+		return
+	}
+	if s.incompatible {
+		writeRegistryError(w, http.StatusConflict, 409, "Schema being registered is incompatible with an earlier schema")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]int{"id": s.add(r.PathValue("subject"), schema)})
+}
+
+func (s *stubRegistry) lookup(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.PathValue("id"))
+
+	s.mu.Lock()
+	schema, ok := s.schemas[id]
+	s.mu.Unlock()
+
+	if !ok {
+		writeRegistryError(w, http.StatusNotFound, 40403, "Schema not found")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]string{"schema": schema})
+}
+
+func (s *stubRegistry) compatible(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.decode(w, r); !ok {
+		return
+	}
+
+	s.mu.Lock()
+	versions := len(s.subjects[r.PathValue("subject")])
+	s.mu.Unlock()
+
+	if versions == 0 {
+		writeRegistryError(w, http.StatusNotFound, 40401, "Subject not found")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]bool{"is_compatible": !s.incompatible})
+}
+
+func writeRegistryError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(registryError{ErrorCode: code, Message: message})
+}
+
+func TestHTTPRegistryRegisterAndLookup(t *testing.T) {
+	_, server := newStubRegistry(t)
+	registry := NewHTTPRegistry(server.URL + "/")
+	ctx := context.Background()
+
+	id, err := RegisterOrderEvent(ctx, registry, "orders")
+	if err != nil {
+		t.Fatalf("RegisterOrderEvent: %v", err)
+	}
+
+	// Registering the same schema again, e.g. from another replica, keeps its ID
+	again, err := RegisterOrderEvent(ctx, registry, "orders")
+	if err != nil {
+		t.Fatalf("second RegisterOrderEvent: %v", err)
+	}
+	if again != id {
+		t.Errorf("re-registration returned id %d, want %d", again, id)
+	}
+
+	doc, err := registry.Lookup(ctx, id)
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if doc != OrderEvent().String() {
+		t.Errorf("Lookup returned %s", doc)
+	}
+
+	if _, err := registry.Lookup(ctx, id+100); err == nil {
+		t.Error("Lookup of an unknown id succeeded")
+	}
+}
+
+func TestHTTPRegistryIncompatible(t *testing.T) {
+	stub, server := newStubRegistry(t)
+	registry := NewHTTPRegistry(server.URL)
+	ctx := context.Background()
+
+	stub.add(Subject("orders"), `{"type":"object"}`)
+	stub.incompatible = true
+
+	if _, err := RegisterOrderEvent(ctx, registry, "orders"); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("RegisterOrderEvent error = %v, want ErrIncompatible", err)
+	}
+
+	// A registry that only rejects on registration reports a conflict
+	if _, err := registry.Register(ctx, Subject("orders"), OrderEvent().String()); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Register error = %v, want ErrIncompatible", err)
+	}
+}
+
+func TestResolverAgainstHTTPRegistry(t *testing.T) {
+	stub, server := newStubRegistry(t)
+	resolver := NewResolver(NewHTTPRegistry(server.URL))
+	ctx := context.Background()
+
+	current := stub.add(Subject("orders"), OrderEvent().String())
+	if err := resolver.Readable(ctx, current); err != nil {
+		t.Errorf("current schema unreadable: %v", err)
+	}
+
+	// A writer that may leave out fields this build requires
+	legacy := stub.add(Subject("orders"), `{"type":"object","properties":{"order_id":{"type":"string"}}}`)
+	if err := resolver.Readable(ctx, legacy); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Readable(legacy) = %v, want ErrIncompatible", err)
+	}
+
+	// Lookup failures are not incompatibilities, so messages still decode
+	if err := resolver.Readable(ctx, 999); err == nil || errors.Is(err, ErrIncompatible) {
+		t.Errorf("Readable(unknown) = %v, want a lookup error", err)
+	}
+}
@@ -0,0 +1,111 @@
+package schema
+
+import (
+	"OrderSystemHighConcurrency/shared/contracts"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Open creates the registry selected by kind: "file", "http" or "none".
+// It returns nil for "none" so callers can skip schema handling.
+func Open(kind, path, baseURL, compatibility string) (contracts.SchemaRegistry, error) {
+	switch kind {
+	case "", "none":
+		return nil, nil
+	case "file":
+		mode, err := ParseCompatibility(compatibility)
+		if err != nil {
+			return nil, err
+		}
+		return NewFileRegistry(path, mode)
+	case "http":
+		if baseURL == "" {
+			return nil, fmt.Errorf("schema registry url required")
+		}
+		return NewHTTPRegistry(baseURL), nil
+	default:
+		return nil, fmt.Errorf("unknown schema registry %q", kind)
+	}
+}
+
+// Subject returns the registry subject for a topic's message values
+func Subject(topic string) string {
+	return topic + "-value"
+}
+
+// RegisterOrderEvent registers this build's order event schema under the
+// topic's subject. It fails when the schema is incompatible with what is
+// already registered, which is meant to stop the producer from starting.
+func RegisterOrderEvent(ctx context.Context, registry contracts.SchemaRegistry, topic string) (int, error) {
+	subject := Subject(topic)
+	doc := OrderEvent().String()
+
+	ok, err := registry.Compatible(ctx, subject, doc)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("subject %s: %w with the registered version", subject, ErrIncompatible)
+	}
+
+	return registry.Register(ctx, subject, doc)
+}
+
+// ParseID parses the schema-id header
+func ParseID(s string) (int, error) {
+	return strconv.Atoi(strings.TrimSpace(s))
+}
+
+// Resolver checks that messages written with a registered schema can be
+// read with this build's schema. Results are cached per schema ID.
+type Resolver struct {
+	registry contracts.SchemaRegistry
+	local    *Schema
+
+	mu    sync.Mutex
+	cache map[int]error
+}
+
+// NewResolver creates a resolver for the local order event schema
+func NewResolver(registry contracts.SchemaRegistry) *Resolver {
+	return &Resolver{
+		registry: registry,
+		local:    OrderEvent(),
+		cache:    make(map[int]error),
+	}
+}
+
+// Readable returns nil when data written with schema id can be decoded
+// locally, and an error wrapping ErrIncompatible when it can't. Lookup
+// failures are returned as-is and not cached.
+func (r *Resolver) Readable(ctx context.Context, id int) error {
+	r.mu.Lock()
+	result, ok := r.cache[id]
+	r.mu.Unlock()
+	if ok {
+		return result
+	}
+
+	doc, err := r.registry.Lookup(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	writer, err := Parse(doc)
+	if err != nil {
+		return fmt.Errorf("schema %d: %w", id, err)
+	}
+
+	if problems := Readable(writer, r.local); len(problems) > 0 {
+		result = fmt.Errorf("schema %d: %w: %s", id, ErrIncompatible, strings.Join(problems, "; "))
+	}
+
+	r.mu.Lock()
+	r.cache[id] = result
+	r.mu.Unlock()
+
+	return result
+}